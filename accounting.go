@@ -0,0 +1,336 @@
+package onerng
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"math"
+	"sync"
+
+	"github.com/hairyhenderson/go-onerng/sp80090b"
+)
+
+// maxHeldBytes - the most output an Accountant will hold back while waiting
+// for entropy to be credited
+const maxHeldBytes = 1 << 20
+
+// Accountant - keeps track of how much min-entropy is behind the data read
+// from the device, as it flows through readers, conditioners, and writers.
+//
+// Each byte read in a noise mode is credited with the mode's min-entropy
+// estimate - the claimed min-entropy (see WithMinEntropy), unless a different
+// estimate is set with SetEstimate or measured with Measure. Conditioning
+// can't add entropy, so output (see Output) is credited with at most 8 bits
+// per byte, and no more than was credited to the input behind it. Whatever
+// that input had beyond what its output can hold is written off, rather than
+// credited to later output.
+//
+// The Accountant can also hold back output until enough entropy has been
+// credited - see MinCredited and FullEntropy.
+type Accountant struct {
+	// OnUpdate - called with the updated totals whenever data is credited or
+	// output. It mustn't call the Accountant's methods.
+	OnUpdate func(EntropyStats)
+	// MinCredited - hold back output until at least this many bits of
+	// min-entropy have been credited
+	MinCredited float64
+	// FullEntropy - only emit output that's covered by credited entropy,
+	// 8 bits for every byte. Output beyond that is discarded.
+	FullEntropy bool
+
+	o         *OneRNG
+	estimates map[NoiseMode]float64
+	held      []byte
+	stats     EntropyStats
+	// pending - the number of bytes of credited input that aren't yet behind
+	// any output - the balance is their entropy
+	pending float64
+	mu      sync.Mutex
+}
+
+// EntropyStats - the totals kept by an Accountant
+type EntropyStats struct {
+	// InputBytes - the number of bytes credited
+	InputBytes int64 `json:"input_bytes"`
+	// CreditedBits - the min-entropy credited to the input, in bits
+	CreditedBits float64 `json:"credited_bits"`
+	// OutputBytes - the number of bytes of output emitted
+	OutputBytes int64 `json:"output_bytes"`
+	// OutputCreditedBits - the min-entropy credited to the output, in bits
+	OutputCreditedBits float64 `json:"output_credited_bits"`
+	// HeldBytes - the number of bytes of output currently held back, waiting
+	// for MinCredited to be reached
+	HeldBytes int64 `json:"held_bytes"`
+	// DiscardedBytes - the number of bytes of output discarded, because
+	// there wasn't enough entropy to cover them (see FullEntropy)
+	DiscardedBytes int64 `json:"discarded_bytes"`
+	// WrittenOffBits - the min-entropy credited to input whose output was
+	// thrown away, which can't be credited to later output
+	WrittenOffBits float64 `json:"written_off_bits"`
+}
+
+// Balance returns the credited min-entropy that hasn't yet been credited to
+// output (or written off), in bits
+func (s EntropyStats) Balance() float64 {
+	return s.CreditedBits - s.OutputCreditedBits - s.WrittenOffBits
+}
+
+// OutputEntropyPerByte returns the min-entropy credited to each byte of
+// output, or 0 if there's been no output
+func (s EntropyStats) OutputEntropyPerByte() float64 {
+	if s.OutputBytes == 0 {
+		return 0
+	}
+
+	return s.OutputCreditedBits / float64(s.OutputBytes)
+}
+
+// NewAccountant returns an Accountant using the device's claimed min-entropy
+// for each noise mode
+func (o *OneRNG) NewAccountant() *Accountant {
+	return &Accountant{o: o, estimates: map[NoiseMode]float64{}}
+}
+
+// Estimate returns the min-entropy estimate for the given mode, in bits per
+// byte
+func (a *Accountant) Estimate(mode NoiseMode) float64 {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	return a.estimate(mode)
+}
+
+func (a *Accountant) estimate(mode NoiseMode) float64 {
+	if h, ok := a.estimates[mode]; ok {
+		return h
+	}
+
+	return a.o.getMinEntropy(mode)
+}
+
+// SetEstimate sets the min-entropy estimate for the given mode, in bits per
+// byte (between 0 and 8)
+func (a *Accountant) SetEstimate(mode NoiseMode, bitsPerByte float64) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.estimates[mode] = min(max(bitsPerByte, 0), 8)
+}
+
+// Measure estimates the min-entropy of the given mode from n samples (see
+// MeasureMinEntropy), and uses it from then on
+func (a *Accountant) Measure(ctx context.Context, mode NoiseMode, n int) (float64, error) {
+	h, err := a.o.MeasureMinEntropy(ctx, mode, n)
+	if err != nil {
+		return 0, err
+	}
+
+	a.SetEstimate(mode, h)
+
+	return h, nil
+}
+
+// Credit credits n bytes read in the given mode
+func (a *Accountant) Credit(mode NoiseMode, n int64) {
+	a.mu.Lock()
+	a.stats.InputBytes += n
+	a.stats.CreditedBits += a.estimate(mode) * float64(n)
+	a.pending += float64(n)
+	s := a.stats
+	a.mu.Unlock()
+
+	a.notify(s)
+}
+
+// Stats returns the current totals
+func (a *Accountant) Stats() EntropyStats {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	return a.stats
+}
+
+// writeOff writes off the balance, when the output it was behind has been
+// thrown away
+func (a *Accountant) writeOff() {
+	a.mu.Lock()
+	a.stats.WrittenOffBits += max(a.stats.Balance(), 0)
+	a.pending = 0
+	s := a.stats
+	a.mu.Unlock()
+
+	a.notify(s)
+}
+
+func (a *Accountant) notify(s EntropyStats) {
+	if a.OnUpdate != nil {
+		a.OnUpdate(s)
+	}
+}
+
+// Reader returns a reader which credits the data read from r, which was
+// read from the device in the given mode
+func (a *Accountant) Reader(r io.Reader, mode NoiseMode) io.Reader {
+	return readerFunc(func(p []byte) (int, error) {
+		n, err := r.Read(p)
+		if n > 0 {
+			a.Credit(mode, int64(n))
+		}
+
+		return n, err
+	})
+}
+
+// Writer returns a writer which credits the data written to it, which was
+// read from the device in the given mode, and passes it on to w (usually a
+// conditioner). The data is credited before it's passed on, so that the
+// output it produces is covered.
+func (a *Accountant) Writer(w io.Writer, mode NoiseMode) io.Writer {
+	return writerFunc(func(p []byte) (int, error) {
+		a.Credit(mode, int64(len(p)))
+
+		return w.Write(p)
+	})
+}
+
+// Output returns a writer for the output produced from credited data by a
+// conditioner with the given ratio (see Conditioner.Ratio), which credits it
+// with the entropy behind it and passes it on to w. Each byte of output is
+// taken to come from 1/ratio bytes of the input credited so far, and is
+// credited with at most their entropy - any surplus is written off.
+//
+// Output is held back until MinCredited bits have been credited. If too much
+// output is held back, writes fail with ErrInsufficientEntropy. Closing the
+// writer discards any output still held back, and returns
+// ErrInsufficientEntropy - it doesn't close w.
+func (a *Accountant) Output(w io.Writer, ratio float64) io.WriteCloser {
+	return &accountedWriter{a: a, w: w, ratio: ratio}
+}
+
+type accountedWriter struct {
+	a     *Accountant
+	w     io.Writer
+	ratio float64
+}
+
+func (aw *accountedWriter) Write(p []byte) (int, error) {
+	a := aw.a
+	a.mu.Lock()
+
+	if a.stats.CreditedBits < a.MinCredited {
+		if len(a.held)+len(p) > maxHeldBytes {
+			a.mu.Unlock()
+
+			return 0, fmt.Errorf("%w: holding back %d bytes of output, with %.0f of %.0f bits credited",
+				ErrInsufficientEntropy, len(a.held), a.stats.CreditedBits, a.MinCredited)
+		}
+
+		a.held = append(a.held, p...)
+		a.stats.HeldBytes = int64(len(a.held))
+		s := a.stats
+		a.mu.Unlock()
+		a.notify(s)
+
+		return len(p), nil
+	}
+
+	out := p
+	if len(a.held) > 0 {
+		out = append(a.held, p...)
+		a.held = nil
+		a.stats.HeldBytes = 0
+	}
+
+	// the input behind this output, and its entropy
+	input := a.pending
+	if aw.ratio > 0 {
+		input = min(float64(len(out))/aw.ratio, input)
+	}
+	behind := 0.0
+	if a.pending > 0 {
+		behind = max(a.stats.Balance(), 0) * input / a.pending
+	}
+	a.pending -= input
+
+	n := len(out)
+	if a.FullEntropy {
+		n = min(n, int(math.Floor(behind/8)))
+		a.stats.DiscardedBytes += int64(len(out) - n)
+	}
+
+	// credit the output before writing it, so concurrent writes can't spend
+	// the same balance, and write off the rest of the entropy behind it
+	credited := min(8*float64(n), behind)
+	a.stats.OutputBytes += int64(n)
+	a.stats.OutputCreditedBits += credited
+	a.stats.WrittenOffBits += behind - credited
+	a.mu.Unlock()
+
+	var err error
+	written := n
+	if n > 0 {
+		written, err = aw.w.Write(out[:n])
+	}
+
+	// output that wasn't written is thrown away, along with its credit
+	a.mu.Lock()
+	if written < n {
+		unwritten := credited - min(8*float64(written), credited)
+		a.stats.OutputBytes -= int64(n - written)
+		a.stats.OutputCreditedBits -= unwritten
+		a.stats.WrittenOffBits += unwritten
+	}
+	s := a.stats
+	a.mu.Unlock()
+	a.notify(s)
+
+	if err != nil {
+		return 0, err
+	}
+
+	return len(p), nil
+}
+
+func (aw *accountedWriter) Close() error {
+	a := aw.a
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if len(a.held) == 0 {
+		return nil
+	}
+
+	n := len(a.held)
+	a.held = nil
+	a.stats.HeldBytes = 0
+	a.stats.DiscardedBytes += int64(n)
+
+	return fmt.Errorf("%w: discarded %d bytes of output, with only %.0f of %.0f bits credited",
+		ErrInsufficientEntropy, n, a.stats.CreditedBits, a.MinCredited)
+}
+
+// MeasureMinEntropy estimates the min-entropy of the data produced in the
+// given mode, in bits per byte, by running the SP 800-90B estimators on n
+// bytes from the device (see sp80090b.Assess). 800-90B expects at least
+// 1,000,000 samples. The samples are raw - they aren't health-tested (see
+// Session.Raw), since the estimators need to see the noise as it is.
+func (o *OneRNG) MeasureMinEntropy(ctx context.Context, mode NoiseMode, n int) (float64, error) {
+	s, err := o.Open(ctx, mode)
+	if err != nil {
+		return 0, err
+	}
+	defer s.Close()
+
+	samples := make([]byte, n)
+	_, err = io.ReadFull(s.Raw(), samples)
+	if err != nil {
+		return 0, fmt.Errorf("failed to capture samples: %w", err)
+	}
+
+	r, err := sp80090b.Assess(samples, 8)
+	if err != nil {
+		return 0, err
+	}
+
+	return r.MinEntropy, nil
+}
@@ -0,0 +1,232 @@
+package onerng
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAccountant_Estimate(t *testing.T) {
+	o := New("/dev/null", WithMinEntropy(EnableRF, 6.5))
+	a := o.NewAccountant()
+
+	assert.InDelta(t, 7, a.Estimate(Default), 0)
+	assert.InDelta(t, 2, a.Estimate(DisableWhitener), 0)
+	assert.InDelta(t, 6.5, a.Estimate(EnableRF), 0)
+	assert.Zero(t, a.Estimate(Silent))
+
+	a.SetEstimate(DisableWhitener, 3.25)
+	assert.InDelta(t, 3.25, a.Estimate(DisableWhitener), 0)
+	a.SetEstimate(Default, 9)
+	assert.InDelta(t, 8, a.Estimate(Default), 0)
+	a.SetEstimate(Default, -1)
+	assert.Zero(t, a.Estimate(Default))
+}
+
+func TestAccountant_Measure(t *testing.T) {
+	o := New("/dev/null", WithDialer(randomDialer()))
+	a := o.NewAccountant()
+
+	h, err := a.Measure(context.Background(), DisableWhitener, 4096)
+	require.NoError(t, err)
+	// pseudo-random data, but a small sample gives a conservative estimate
+	assert.Greater(t, h, 5.0)
+	assert.LessOrEqual(t, h, 8.0)
+	assert.InDelta(t, h, a.Estimate(DisableWhitener), 0)
+
+	_, err = a.Measure(context.Background(), DisableWhitener, 10)
+	require.Error(t, err)
+
+	// the samples aren't health-tested, so a poor source is measured rather
+	// than failing
+	o = New("/dev/null", WithDialer(DialerFunc(func(context.Context, string) (Transport, error) {
+		return newFakeDev(strings.Repeat("\x00\x01", 2048)), nil
+	})))
+	h, err = o.MeasureMinEntropy(context.Background(), DisableWhitener, 4096)
+	require.NoError(t, err)
+	assert.Less(t, h, 1.0)
+}
+
+func TestAccountant_ReaderWriter(t *testing.T) {
+	o := New("/dev/null")
+	a := o.NewAccountant()
+
+	updates := []EntropyStats{}
+	a.OnUpdate = func(s EntropyStats) { updates = append(updates, s) }
+
+	_, err := io.ReadAll(a.Reader(bytes.NewReader(make([]byte, 100)), DisableWhitener))
+	require.NoError(t, err)
+
+	out := &bytes.Buffer{}
+	_, err = a.Writer(out, Default).Write(make([]byte, 10))
+	require.NoError(t, err)
+	assert.Equal(t, 10, out.Len())
+
+	s := a.Stats()
+	assert.Equal(t, int64(110), s.InputBytes)
+	assert.InDelta(t, 100*2+10*7, s.CreditedBits, 0)
+	assert.InDelta(t, 270, s.Balance(), 0)
+	require.NotEmpty(t, updates)
+	assert.Equal(t, s, updates[len(updates)-1])
+}
+
+func TestAccountant_Output(t *testing.T) {
+	o := New("/dev/null")
+	a := o.NewAccountant()
+
+	// 2 bits per byte, compressed 4:1 gives full-entropy output
+	out := &bytes.Buffer{}
+	ow := a.Output(out, 0.25)
+	w := a.Writer(writerFunc(func(p []byte) (int, error) {
+		_, err := ow.Write(p[:len(p)/4])
+
+		return len(p), err
+	}), DisableWhitener)
+
+	_, err := w.Write(make([]byte, 400))
+	require.NoError(t, err)
+	require.NoError(t, ow.Close())
+
+	s := a.Stats()
+	assert.Equal(t, int64(100), s.OutputBytes)
+	assert.InDelta(t, 800, s.OutputCreditedBits, 0)
+	assert.InDelta(t, 8, s.OutputEntropyPerByte(), 0)
+	assert.Zero(t, s.Balance())
+
+	// without compression, each output byte only gets 2 bits
+	a = o.NewAccountant()
+	ow = a.Output(io.Discard, 1)
+	_, err = a.Writer(ow, DisableWhitener).Write(make([]byte, 400))
+	require.NoError(t, err)
+	assert.InDelta(t, 2, a.Stats().OutputEntropyPerByte(), 0)
+
+	// no output, no credit
+	assert.Zero(t, EntropyStats{}.OutputEntropyPerByte())
+}
+
+func TestAccountant_OutputShortWrite(t *testing.T) {
+	o := New("/dev/null")
+	a := o.NewAccountant()
+	a.Credit(Default, 100)
+
+	// the output is written without holding the lock, so the writer can use
+	// the Accountant, and the output credited is what was actually written
+	var during EntropyStats
+	ow := a.Output(writerFunc(func(p []byte) (int, error) {
+		during = a.Stats()
+
+		return len(p) / 2, io.ErrShortWrite
+	}), 1)
+
+	_, err := ow.Write(make([]byte, 10))
+	require.ErrorIs(t, err, io.ErrShortWrite)
+	assert.Equal(t, int64(10), during.OutputBytes)
+
+	s := a.Stats()
+	assert.Equal(t, int64(5), s.OutputBytes)
+	assert.InDelta(t, 40, s.OutputCreditedBits, 0)
+
+	// the credit for what wasn't written is written off, along with the rest
+	// of the entropy behind it
+	assert.InDelta(t, 30, s.WrittenOffBits, 0)
+	assert.InDelta(t, 630, s.Balance(), 0)
+}
+
+func TestAccountant_OutputSurplus(t *testing.T) {
+	o := New("/dev/null")
+	a := o.NewAccountant()
+
+	// 64 bytes at 7 bits per byte (448 bits) are compressed into a 32-byte
+	// block, which can only hold 256 bits - the rest isn't carried over to
+	// the next block
+	c, err := o.NewConditioner(context.Background(), "sha256-compress")
+	require.NoError(t, err)
+	ow := a.Output(io.Discard, c.Ratio())
+	w := c.Writer(ow)
+	_, err = a.Writer(w, Default).Write(make([]byte, 96))
+	require.NoError(t, err)
+
+	s := a.Stats()
+	assert.Equal(t, int64(32), s.OutputBytes)
+	assert.InDelta(t, 256, s.OutputCreditedBits, 0)
+	assert.InDelta(t, 192, s.WrittenOffBits, 0)
+	// the 32 bytes still buffered by the conditioner are covered by what's
+	// left
+	assert.InDelta(t, 224, s.Balance(), 0)
+
+	_, err = a.Writer(w, Default).Write(make([]byte, 32))
+	require.NoError(t, err)
+
+	s = a.Stats()
+	assert.Equal(t, int64(64), s.OutputBytes)
+	assert.InDelta(t, 512, s.OutputCreditedBits, 0)
+	assert.InDelta(t, 384, s.WrittenOffBits, 0)
+	assert.Zero(t, s.Balance())
+}
+
+func TestAccountant_MinCredited(t *testing.T) {
+	o := New("/dev/null")
+	a := o.NewAccountant()
+	a.MinCredited = 256
+
+	out := &bytes.Buffer{}
+	ow := a.Output(out, 1)
+	w := a.Writer(ow, Default)
+
+	// 7 bits per byte, so 37 bytes are needed
+	_, err := w.Write(bytes.Repeat([]byte{1}, 36))
+	require.NoError(t, err)
+	assert.Zero(t, out.Len())
+	assert.Equal(t, int64(36), a.Stats().HeldBytes)
+
+	_, err = w.Write([]byte{2})
+	require.NoError(t, err)
+	assert.Equal(t, append(bytes.Repeat([]byte{1}, 36), 2), out.Bytes())
+
+	s := a.Stats()
+	assert.Zero(t, s.HeldBytes)
+	assert.Equal(t, int64(37), s.OutputBytes)
+	require.NoError(t, ow.Close())
+
+	// never enough
+	a = o.NewAccountant()
+	a.MinCredited = 256
+	ow = a.Output(io.Discard, 1)
+	_, err = a.Writer(ow, DisableWhitener).Write(make([]byte, 100))
+	require.NoError(t, err)
+	err = ow.Close()
+	require.ErrorIs(t, err, ErrInsufficientEntropy)
+	assert.Equal(t, int64(100), a.Stats().DiscardedBytes)
+
+	// nothing credited in silent mode, so output is held back until there's
+	// too much
+	a = o.NewAccountant()
+	a.MinCredited = 1
+	_, err = a.Writer(a.Output(io.Discard, 1), Silent).Write(make([]byte, maxHeldBytes+1))
+	require.ErrorIs(t, err, ErrInsufficientEntropy)
+}
+
+func TestAccountant_FullEntropy(t *testing.T) {
+	o := New("/dev/null")
+	a := o.NewAccountant()
+	a.FullEntropy = true
+
+	// 2 bits per byte, so only a quarter of the output is covered
+	out := &bytes.Buffer{}
+	w := a.Writer(a.Output(out, 1), DisableWhitener)
+	for range 10 {
+		_, err := w.Write(make([]byte, 100))
+		require.NoError(t, err)
+	}
+
+	s := a.Stats()
+	assert.Equal(t, 250, out.Len())
+	assert.Equal(t, int64(250), s.OutputBytes)
+	assert.Equal(t, int64(750), s.DiscardedBytes)
+	assert.InDelta(t, 8, s.OutputEntropyPerByte(), 0)
+}
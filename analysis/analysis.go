@@ -0,0 +1,203 @@
+/*
+Package analysis computes the same statistical summary of random data as the
+ent(1) tool (https://www.fourmilab.ch/random/): entropy, chi-square, mean,
+Monte Carlo value for pi, serial correlation, and optimum compression.
+
+An Analyzer is an io.Writer, so it can be used with streams of any length:
+
+	a := analysis.NewAnalyzer(analysis.Bytes)
+	if _, err := io.Copy(a, r); err != nil {
+		return err
+	}
+	res := a.Result()
+*/
+package analysis
+
+import (
+	"io"
+	"math"
+
+	"github.com/hairyhenderson/go-onerng/internal/special"
+)
+
+// Granularity - whether the data is analyzed as bytes or as bits
+type Granularity int
+
+const (
+	// Bytes - each byte is a sample
+	Bytes Granularity = iota
+	// Bits - each bit is a sample, most significant first
+	Bits
+)
+
+func (g Granularity) String() string {
+	if g == Bits {
+		return "bit"
+	}
+
+	return "byte"
+}
+
+// size - the number of bits in each sample
+func (g Granularity) size() int {
+	if g == Bits {
+		return 1
+	}
+
+	return 8
+}
+
+// montePoints - the number of bytes used for each Monte Carlo point (24 bits
+// for each coordinate)
+const montePoints = 6
+
+// Result - the results of the analysis
+type Result struct {
+	// Granularity - whether samples are bytes or bits
+	Granularity Granularity
+	// Samples - the number of samples
+	Samples int64
+	// Entropy - the Shannon entropy, in bits per sample
+	Entropy float64
+	// Compression - the percentage by which optimum compression would
+	// reduce the size of the data
+	Compression float64
+	// ChiSquare - the chi-square statistic for the distribution of samples
+	ChiSquare float64
+	// ChiSquareP - the probability of a chi-square at least as large for
+	// truly random data. Very high or very low values are suspicious.
+	ChiSquareP float64
+	// Mean - the arithmetic mean of the samples (127.5 for random bytes, or
+	// 0.5 for random bits)
+	Mean float64
+	// MonteCarloPi - the value of pi estimated by using each 6 bytes as
+	// coordinates in a square, and counting how many fall within the
+	// inscribed circle
+	MonteCarloPi float64
+	// MonteCarloPiError - the error of MonteCarloPi, in percent
+	MonteCarloPiError float64
+	// SerialCorrelation - how much each sample depends on the previous one
+	// (0 for random data, and 1 or -1 for entirely predictable data)
+	SerialCorrelation float64
+}
+
+// Analyzer accumulates statistics over the data written to it. The zero
+// value analyzes bytes.
+type Analyzer struct {
+	counts [256]int64
+	total  int64
+
+	// Monte Carlo state
+	monte   [montePoints]byte
+	mp      int
+	inCirc  int64
+	mPoints int64
+
+	// serial correlation state
+	first, last float64
+	t1, t2, t3  float64
+	started     bool
+	granularity Granularity
+}
+
+// NewAnalyzer creates an Analyzer for samples of the given granularity
+func NewAnalyzer(g Granularity) *Analyzer {
+	return &Analyzer{granularity: g}
+}
+
+// Analyze reads r until EOF, and returns the result
+func Analyze(r io.Reader, g Granularity) (Result, error) {
+	a := NewAnalyzer(g)
+	_, err := io.Copy(a, r)
+
+	return a.Result(), err
+}
+
+// Write adds data to the analysis. It never returns an error.
+func (a *Analyzer) Write(p []byte) (int, error) {
+	const circle = float64((1<<24 - 1) * (1<<24 - 1))
+
+	for _, b := range p {
+		a.monte[a.mp] = b
+		a.mp++
+		if a.mp == montePoints {
+			a.mp = 0
+			a.mPoints++
+			x := float64(uint32(a.monte[0])<<16 | uint32(a.monte[1])<<8 | uint32(a.monte[2]))
+			y := float64(uint32(a.monte[3])<<16 | uint32(a.monte[4])<<8 | uint32(a.monte[5]))
+			if x*x+y*y <= circle {
+				a.inCirc++
+			}
+		}
+
+		if a.granularity == Bits {
+			for i := 7; i >= 0; i-- {
+				a.add((b >> i) & 1)
+			}
+		} else {
+			a.add(b)
+		}
+	}
+
+	return len(p), nil
+}
+
+func (a *Analyzer) add(c byte) {
+	a.counts[c]++
+	a.total++
+
+	v := float64(c)
+	if a.started {
+		a.t1 += a.last * v
+	} else {
+		a.started = true
+		a.first = v
+	}
+	a.t2 += v
+	a.t3 += v * v
+	a.last = v
+}
+
+// Result returns the results for the data written so far
+func (a *Analyzer) Result() Result {
+	r := Result{Granularity: a.granularity, Samples: a.total}
+	if a.total == 0 {
+		return r
+	}
+
+	symbols := 1 << a.granularity.size()
+	n := float64(a.total)
+	expected := n / float64(symbols)
+
+	sum := 0.0
+	for c, count := range a.counts[:symbols] {
+		p := float64(count) / n
+		if p > 0 {
+			r.Entropy -= p * math.Log2(p)
+		}
+		d := float64(count) - expected
+		r.ChiSquare += d * d / expected
+		sum += float64(c) * float64(count)
+	}
+
+	bits := float64(a.granularity.size())
+	r.Compression = 100 * (bits - r.Entropy) / bits
+	r.ChiSquareP = special.ChiSquareP(r.ChiSquare, symbols-1)
+	r.Mean = sum / n
+
+	if a.mPoints > 0 {
+		r.MonteCarloPi = 4 * float64(a.inCirc) / float64(a.mPoints)
+		r.MonteCarloPiError = 100 * math.Abs(math.Pi-r.MonteCarloPi) / math.Pi
+	}
+
+	// the last sample wraps around to correlate with the first
+	t1 := a.t1 + a.last*a.first
+	t2 := a.t2 * a.t2
+	if d := n*a.t3 - t2; d == 0 {
+		r.SerialCorrelation = math.NaN()
+	} else {
+		r.SerialCorrelation = (n*t1 - t2) / d
+	}
+
+	return r
+}
@@ -0,0 +1,98 @@
+package analysis
+
+import (
+	"bytes"
+	"math"
+	"math/rand/v2"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAnalyze_Random(t *testing.T) {
+	data := make([]byte, 1<<20)
+	_, _ = rand.NewChaCha8([32]byte{1}).Read(data)
+
+	r, err := Analyze(bytes.NewReader(data), Bytes)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1<<20), r.Samples)
+	assert.InDelta(t, 8, r.Entropy, 0.001)
+	assert.InDelta(t, 0, r.Compression, 0.1)
+	assert.Greater(t, r.ChiSquareP, 0.001)
+	assert.Less(t, r.ChiSquareP, 0.999)
+	assert.InDelta(t, 127.5, r.Mean, 0.5)
+	assert.InDelta(t, math.Pi, r.MonteCarloPi, 0.02)
+	assert.Less(t, r.MonteCarloPiError, 1.0)
+	assert.InDelta(t, 0, r.SerialCorrelation, 0.01)
+
+	r, err = Analyze(bytes.NewReader(data), Bits)
+	require.NoError(t, err)
+	assert.Equal(t, int64(8<<20), r.Samples)
+	assert.InDelta(t, 1, r.Entropy, 0.001)
+	assert.InDelta(t, 0.5, r.Mean, 0.001)
+	assert.InDelta(t, 0, r.SerialCorrelation, 0.01)
+	// Monte Carlo always works on bytes
+	assert.InDelta(t, math.Pi, r.MonteCarloPi, 0.02)
+}
+
+func TestAnalyze_Known(t *testing.T) {
+	// every byte value once - perfectly uniform
+	data := make([]byte, 256)
+	for i := range data {
+		data[i] = byte(i)
+	}
+	r, err := Analyze(bytes.NewReader(data), Bytes)
+	require.NoError(t, err)
+	assert.InDelta(t, 8, r.Entropy, 1e-9)
+	assert.InDelta(t, 0, r.ChiSquare, 1e-9)
+	assert.InDelta(t, 1, r.ChiSquareP, 1e-9)
+	assert.InDelta(t, 127.5, r.Mean, 1e-9)
+
+	// alternating values are perfectly anti-correlated
+	r, err = Analyze(bytes.NewReader(bytes.Repeat([]byte{0, 255}, 500)), Bytes)
+	require.NoError(t, err)
+	assert.InDelta(t, 1, r.Entropy, 1e-9)
+	assert.InDelta(t, 87.5, r.Compression, 1e-9)
+	assert.InDelta(t, 127.5, r.Mean, 1e-9)
+	assert.InDelta(t, -1, r.SerialCorrelation, 1e-9)
+	assert.InDelta(t, 0, r.ChiSquareP, 1e-9)
+
+	r, err = Analyze(bytes.NewReader(bytes.Repeat([]byte{0x55}, 100)), Bits)
+	require.NoError(t, err)
+	assert.InDelta(t, 1, r.Entropy, 1e-9)
+	assert.InDelta(t, 0, r.Compression, 1e-9)
+	assert.InDelta(t, 0.5, r.Mean, 1e-9)
+	assert.InDelta(t, -1, r.SerialCorrelation, 1e-9)
+	assert.InDelta(t, 0, r.ChiSquare, 1e-9)
+
+	// all zero coordinates are inside the circle
+	r, err = Analyze(bytes.NewReader(make([]byte, 60)), Bytes)
+	require.NoError(t, err)
+	assert.InDelta(t, 4, r.MonteCarloPi, 1e-9)
+	assert.InDelta(t, 0, r.Entropy, 1e-9)
+	assert.True(t, math.IsNaN(r.SerialCorrelation))
+}
+
+func TestAnalyzer_Streaming(t *testing.T) {
+	data := make([]byte, 10007)
+	_, _ = rand.NewChaCha8([32]byte{2}).Read(data)
+
+	whole, err := Analyze(bytes.NewReader(data), Bytes)
+	require.NoError(t, err)
+
+	// writing in odd-sized chunks gives the same result
+	a := NewAnalyzer(Bytes)
+	for i := 0; i < len(data); i += 7 {
+		_, _ = a.Write(data[i:min(i+7, len(data))])
+	}
+	assert.Equal(t, whole, a.Result())
+}
+
+func TestAnalyzer_Empty(t *testing.T) {
+	r := (&Analyzer{}).Result()
+	assert.Equal(t, Bytes, r.Granularity)
+	assert.Zero(t, r.Samples)
+	assert.Equal(t, "bit", Bits.String())
+	assert.Equal(t, "byte", Bytes.String())
+}
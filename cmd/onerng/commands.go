@@ -2,22 +2,158 @@ package main
 
 import (
 	"bytes"
+	"context"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"log/slog"
 	"math"
+	"net"
 	"os"
+	"runtime"
+	"strings"
+	"sync"
+	"syscall"
+	"text/tabwriter"
 	"time"
 
 	"github.com/hairyhenderson/go-onerng"
+	"github.com/hairyhenderson/go-onerng/analysis"
+	"github.com/hairyhenderson/go-onerng/drbg"
+	"github.com/hairyhenderson/go-onerng/egd"
+	"github.com/hairyhenderson/go-onerng/expand"
+	"github.com/hairyhenderson/go-onerng/fips1402"
+	"github.com/hairyhenderson/go-onerng/sp80090b"
+	"github.com/hairyhenderson/go-onerng/sts"
 	"github.com/spf13/cobra"
 )
 
-func createORNG(cmd *cobra.Command) *onerng.OneRNG {
-	return &onerng.OneRNG{Path: cmd.Flag("device").Value.String()}
+func createORNG(cmd *cobra.Command) (*onerng.OneRNG, error) {
+	path := cmd.Flag("device").Value.String()
+	if path == "auto" {
+		devs, err := onerng.Discover()
+		if err != nil {
+			return nil, fmt.Errorf("failed to discover OneRNG devices: %w", err)
+		}
+		if len(devs) == 0 {
+			return nil, fmt.Errorf("%w: no OneRNG devices found", onerng.ErrDeviceNotFound)
+		}
+		if len(devs) > 1 {
+			fmt.Fprintf(os.Stderr, "warning: %d OneRNG devices found, using %s\n", len(devs), devs[0].Path)
+		}
+		path = devs[0].Path
+	}
+
+	opts, err := orngOptions(cmd)
+	if err != nil {
+		return nil, err
+	}
+
+	return onerng.New(path, opts...), nil
+}
+
+// orngOptions converts the global flags into options
+func orngOptions(cmd *cobra.Command) ([]onerng.Option, error) {
+	f := cmd.Flags()
+
+	lockTimeout, err := f.GetDuration("lock-timeout")
+	if err != nil {
+		return nil, err
+	}
+	readTimeout, err := f.GetDuration("read-timeout")
+	if err != nil {
+		return nil, err
+	}
+	allowedTimeouts, err := f.GetInt("allowed-timeouts")
+	if err != nil {
+		return nil, err
+	}
+	initAttempts, err := f.GetInt("init-attempts")
+	if err != nil {
+		return nil, err
+	}
+	initTimeout, err := f.GetDuration("init-timeout")
+	if err != nil {
+		return nil, err
+	}
+	imageEndZeros, err := f.GetInt("image-end-zeros")
+	if err != nil {
+		return nil, err
+	}
+	warmup, err := f.GetInt64("warmup")
+	if err != nil {
+		return nil, err
+	}
+	healthTests, err := f.GetBool("health-tests")
+	if err != nil {
+		return nil, err
+	}
+	minEntropy, err := f.GetFloat64("min-entropy")
+	if err != nil {
+		return nil, err
+	}
+	verbose, err := f.GetBool("verbose")
+	if err != nil {
+		return nil, err
+	}
+
+	level := slog.LevelWarn
+	if verbose {
+		level = slog.LevelDebug
+	}
+	logger := slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: level}))
+
+	opts := []onerng.Option{
+		onerng.WithDialer(onerng.FileDialer{LockTimeout: lockTimeout}),
+		onerng.WithReadTimeout(readTimeout),
+		onerng.WithAllowedTimeouts(allowedTimeouts),
+		onerng.WithInitAttempts(initAttempts),
+		onerng.WithInitTimeout(initTimeout),
+		onerng.WithImageEndZeros(imageEndZeros),
+		onerng.WithWarmup(warmup),
+		onerng.WithHealthTests(healthTests),
+		onerng.WithLogger(logger),
+	}
+
+	// the claim applies to whichever mode is used - invalid claims are
+	// rejected by the option
+	if minEntropy != 0 {
+		for mode := onerng.NoiseMode(0); mode <= onerng.DisableWhitener|onerng.EnableRF|onerng.DisableAvalanche; mode++ {
+			opts = append(opts, onerng.WithMinEntropy(mode, minEntropy))
+		}
+	}
+
+	return opts, nil
+}
+
+func listCmd(_ *cobra.Command, _ []string) error {
+	devs, err := onerng.Discover()
+	if err != nil {
+		return err
+	}
+	if len(devs) == 0 {
+		fmt.Fprintln(os.Stderr, "No OneRNG devices found")
+
+		return nil
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "DEVICE\tSERIAL\tBUS")
+	for _, d := range devs {
+		fmt.Fprintf(w, "%s\t%s\t%s\n", d.Path, d.Serial, d.BusLocation)
+	}
+
+	return w.Flush()
 }
 
 func idCmd(cmd *cobra.Command, _ []string) error {
-	o := createORNG(cmd)
+	o, err := createORNG(cmd)
+	if err != nil {
+		return err
+	}
 	id, err := o.Identify(cmd.Context())
 	if err != nil {
 		return err
@@ -28,7 +164,10 @@ func idCmd(cmd *cobra.Command, _ []string) error {
 }
 
 func versionCmd(cmd *cobra.Command, _ []string) error {
-	o := createORNG(cmd)
+	o, err := createORNG(cmd)
+	if err != nil {
+		return err
+	}
 	version, err := o.Version(cmd.Context())
 	if err != nil {
 		return err
@@ -39,21 +178,57 @@ func versionCmd(cmd *cobra.Command, _ []string) error {
 }
 
 func flushCmd(cmd *cobra.Command, _ []string) error {
-	o := createORNG(cmd)
+	o, err := createORNG(cmd)
+	if err != nil {
+		return err
+	}
 
 	return o.Flush(cmd.Context())
 }
 
 func initCmd(cmd *cobra.Command, _ []string) error {
-	o := createORNG(cmd)
+	o, err := createORNG(cmd)
+	if err != nil {
+		return err
+	}
 
-	return o.Init(cmd.Context())
+	flags, err := readFlags(cmd)
+	if err != nil {
+		return err
+	}
+
+	r, err := o.Init(cmd.Context(), flags)
+	if r != nil {
+		printInitReport(r)
+	}
+
+	return err
+}
+
+func printInitReport(r *onerng.InitReport) {
+	verdict := "rejected"
+	if r.Accepted {
+		verdict = "accepted"
+	}
+
+	fmt.Printf("mode: %d (%s)\n", r.Mode, r.Mode)
+	fmt.Printf("attempts before data: %d\n", r.Attempts)
+	fmt.Printf("warm-up discarded: %d bytes\n", r.Discarded)
+	fmt.Printf("samples tested: %d\n", r.Tested)
+	if r.RCTCutoff > 0 {
+		fmt.Printf("health test cutoffs: RCT %d, APT %d\n", r.RCTCutoff, r.APTCutoff)
+	}
+	fmt.Printf("duration: %s\n", r.Duration.Round(time.Millisecond))
+	fmt.Printf("%s: %s\n", verdict, r.Reason)
 }
 
 func verifyCmd(cmd *cobra.Command, _ []string) error {
 	ctx := cmd.Context()
-	o := createORNG(cmd)
-	err := o.Init(ctx)
+	o, err := createORNG(cmd)
+	if err != nil {
+		return err
+	}
+	_, err = o.Init(ctx, onerng.Default)
 	if err != nil {
 		return fmt.Errorf("init failed before image verification: %w", err)
 	}
@@ -68,9 +243,11 @@ func verifyCmd(cmd *cobra.Command, _ []string) error {
 
 func imageCmd(cmd *cobra.Command, _ []string) error {
 	ctx := cmd.Context()
-	o := createORNG(cmd)
-
-	err := o.Init(ctx)
+	o, err := createORNG(cmd)
+	if err != nil {
+		return err
+	}
+	_, err = o.Init(ctx, onerng.Default)
 	if err != nil {
 		return fmt.Errorf("init failed before image extraction: %w", err)
 	}
@@ -127,34 +304,34 @@ func readFlags(cmd *cobra.Command) (onerng.NoiseMode, error) {
 //nolint:gocyclo
 func readCmd(cmd *cobra.Command, _ []string) error {
 	ctx := cmd.Context()
-	o := createORNG(cmd)
-	err := o.Init(ctx)
+	o, err := createORNG(cmd)
 	if err != nil {
-		return fmt.Errorf("init failed before read: %w", err)
+		return err
 	}
-
-	enableAESWhiten, err := cmd.Flags().GetBool("aes-whitener")
+	flags, err := readFlags(cmd)
 	if err != nil {
 		return err
 	}
-	count, err := cmd.Flags().GetInt64("count")
+	_, err = o.Init(ctx, flags)
 	if err != nil {
-		return err
+		return fmt.Errorf("init failed before read: %w", err)
 	}
-	flags, err := readFlags(cmd)
+
+	reconnect, err := cmd.Flags().GetBool("reconnect")
 	if err != nil {
 		return err
 	}
+	if reconnect {
+		o.Reconnect = &onerng.ReconnectPolicy{OnGap: reportGap}
+	}
 
-	// waste some entropy...
-	devNull, err := os.OpenFile("/dev/null", os.O_WRONLY, 0o200)
+	conditioner, err := conditionerName(cmd)
 	if err != nil {
 		return err
 	}
-	wasteAmount := 10240
-	_, err = o.Read(ctx, devNull, int64(wasteAmount), flags)
+	count, err := cmd.Flags().GetInt64("count")
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "warning: entropy wasteage failed or incomplete, continuing anyway\n")
+		return err
 	}
 
 	out := io.WriteCloser(os.Stdout)
@@ -166,15 +343,65 @@ func readCmd(cmd *cobra.Command, _ []string) error {
 		}
 	}
 
-	if enableAESWhiten {
-		out, err = o.AESWhitener(ctx, out)
+	drbgName, err := cmd.Flags().GetString("drbg")
+	if err != nil {
+		return err
+	}
+
+	expander, err := cmd.Flags().GetBool("expand")
+	if err != nil {
+		return err
+	}
+
+	var (
+		written int64
+		start   time.Time
+	)
+	switch {
+	case drbgName != "" && expander:
+		return fmt.Errorf("--drbg can't be used with --expand")
+	case (drbgName != "" || expander) && changed(cmd, "conditioner", "min-credited", "full-entropy", "measure-entropy"):
+		return fmt.Errorf("--conditioner, --min-credited, --full-entropy, and --measure-entropy can't be used with --drbg or --expand")
+	case drbgName != "":
+		var r *drbg.Reader
+		r, err = newDRBG(cmd, o, drbgName, flags)
+		if err != nil {
+			return err
+		}
+		defer r.Close()
+
+		start = time.Now()
+		written, err = copyN(ctx, out, r, count)
+	case expander:
+		var r *expand.Reader
+		r, err = newExpander(cmd, o, flags)
+		if err != nil {
+			return err
+		}
+		defer r.Close()
+
+		start = time.Now()
+		written, err = copyN(ctx, out, r, count)
+	default:
+		var c onerng.Conditioner
+		c, err = o.NewConditioner(ctx, conditioner)
 		if err != nil {
 			return err
 		}
-	}
 
-	start := time.Now()
-	written, err := o.Read(ctx, out, count, flags)
+		var a *onerng.Accountant
+		a, err = newAccountant(cmd, o, flags)
+		if err != nil {
+			return err
+		}
+
+		start = time.Now()
+		written, err = readConditioned(ctx, o, c, a, out, count, flags)
+
+		s := a.Stats()
+		fmt.Fprintf(os.Stderr, "%.0f bits of min-entropy credited to the output (%.3f bits/byte)\n",
+			s.OutputCreditedBits, s.OutputEntropyPerByte())
+	}
 	delta := time.Since(start)
 	rate := float64(written) / delta.Seconds()
 	fmt.Fprintf(os.Stderr, "%s written in %s (%s/s)\n", humanizeBytes(float64(written)), delta, humanizeBytes(rate))
@@ -182,6 +409,813 @@ func readCmd(cmd *cobra.Command, _ []string) error {
 	return err
 }
 
+// readConditioned reads from the device through the conditioner, until count
+// bytes of output have been written (or forever, if count is negative). The
+// accountant credits the data read, and holds back output as it's configured
+// to.
+func readConditioned(ctx context.Context, o *onerng.OneRNG, c onerng.Conditioner, a *onerng.Accountant,
+	out io.Writer, count int64, flags onerng.NoiseMode,
+) (int64, error) {
+	lw := &limitWriter{w: out, n: count}
+	ow := a.Output(lw, c.Ratio())
+	w := c.Writer(ow)
+
+	_, err := o.Read(ctx, a.Writer(w, flags), -1, flags)
+	if errors.Is(err, errLimitReached) {
+		err = nil
+	}
+	if cerr := w.Close(); err == nil {
+		err = cerr
+	}
+	if cerr := ow.Close(); err == nil {
+		err = cerr
+	}
+
+	return lw.written, err
+}
+
+// newAccountant creates an entropy accountant with the settings from the
+// command's flags, measuring the mode's min-entropy first if asked to
+func newAccountant(cmd *cobra.Command, o *onerng.OneRNG, mode onerng.NoiseMode) (*onerng.Accountant, error) {
+	f := cmd.Flags()
+	minCredited, err := f.GetFloat64("min-credited")
+	if err != nil {
+		return nil, err
+	}
+	fullEntropy, err := f.GetBool("full-entropy")
+	if err != nil {
+		return nil, err
+	}
+	measure, err := f.GetInt("measure-entropy")
+	if err != nil {
+		return nil, err
+	}
+
+	a := o.NewAccountant()
+	a.MinCredited = minCredited
+	a.FullEntropy = fullEntropy
+
+	if measure > 0 {
+		h, err := a.Measure(cmd.Context(), mode, measure)
+		if err != nil {
+			return nil, fmt.Errorf("failed to measure min-entropy: %w", err)
+		}
+		fmt.Fprintf(os.Stderr, "measured min-entropy: %.6f bits/byte\n", h)
+	}
+
+	return a, nil
+}
+
+// errLimitReached - returned by limitWriter when it's written enough
+var errLimitReached = errors.New("limit reached")
+
+// limitWriter writes at most n bytes to w (or everything, if n is negative),
+// and then fails with errLimitReached
+type limitWriter struct {
+	w          io.Writer
+	n, written int64
+}
+
+func (l *limitWriter) Write(p []byte) (int, error) {
+	if l.n >= 0 && int64(len(p)) > l.n-l.written {
+		p = p[:l.n-l.written]
+	}
+
+	n, err := l.w.Write(p)
+	l.written += int64(n)
+	if err == nil && l.written == l.n {
+		err = errLimitReached
+	}
+
+	return n, err
+}
+
+// conditionerName returns the conditioner chosen with --conditioner, or with
+// the deprecated --aes-whitener flag
+func conditionerName(cmd *cobra.Command) (string, error) {
+	f := cmd.Flags()
+	name, err := f.GetString("conditioner")
+	if err != nil {
+		return "", err
+	}
+
+	if f.Changed("aes-whitener") && !f.Changed("conditioner") {
+		aes, err := f.GetBool("aes-whitener")
+		if err != nil {
+			return "", err
+		}
+		if !aes {
+			name = "none"
+		}
+	}
+
+	return name, nil
+}
+
+// drbgNames - the DRBGs available with --drbg
+var drbgNames = []string{"ctr", "hmac-sha256", "hmac-sha512", "hash-sha256", "hash-sha512"}
+
+// newDRBG instantiates the named DRBG, seeded from the device, with the
+// reseed settings from the command's flags
+func newDRBG(cmd *cobra.Command, o *onerng.OneRNG, name string, mode onerng.NoiseMode) (*drbg.Reader, error) {
+	f := cmd.Flags()
+	reseedBytes, err := f.GetInt64("reseed-bytes")
+	if err != nil {
+		return nil, err
+	}
+	reseedInterval, err := f.GetDuration("reseed-interval")
+	if err != nil {
+		return nil, err
+	}
+	pr, err := f.GetBool("prediction-resistance")
+	if err != nil {
+		return nil, err
+	}
+
+	ctx := cmd.Context()
+	var r *drbg.Reader
+	switch name {
+	case "ctr":
+		r, err = o.CTRDRBG(ctx, mode, nil)
+	case "hmac-sha256":
+		r, err = o.HMACDRBG(ctx, mode, sha256.New, nil)
+	case "hmac-sha512":
+		r, err = o.HMACDRBG(ctx, mode, sha512.New, nil)
+	case "hash-sha256":
+		r, err = o.HashDRBG(ctx, mode, sha256.New, nil)
+	case "hash-sha512":
+		r, err = o.HashDRBG(ctx, mode, sha512.New, nil)
+	default:
+		return nil, fmt.Errorf("unknown DRBG %q (must be one of %s)", name, strings.Join(drbgNames, ", "))
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to instantiate the %s DRBG: %w", name, err)
+	}
+
+	r.ReseedBytes = reseedBytes
+	r.ReseedInterval = reseedInterval
+	r.PredictionResistance = pr
+
+	return r, nil
+}
+
+// changed returns true if any of the named flags were set
+func changed(cmd *cobra.Command, names ...string) bool {
+	for _, name := range names {
+		if cmd.Flags().Changed(name) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// newExpander creates a ChaCha20 expander, seeded from the device, with the
+// reseed settings from the command's flags
+func newExpander(cmd *cobra.Command, o *onerng.OneRNG, mode onerng.NoiseMode) (*expand.Reader, error) {
+	f := cmd.Flags()
+	reseedBytes, err := f.GetInt64("reseed-bytes")
+	if err != nil {
+		return nil, err
+	}
+	reseedInterval, err := f.GetDuration("reseed-interval")
+	if err != nil {
+		return nil, err
+	}
+
+	r, err := o.Expander(cmd.Context(), mode)
+	if err != nil {
+		return nil, fmt.Errorf("failed to seed the expander: %w", err)
+	}
+
+	r.ReseedBytes = reseedBytes
+	r.ReseedInterval = reseedInterval
+
+	return r, nil
+}
+
+// copyN copies n bytes (or until an error, if n is negative) from r to out,
+// stopping early if the context is cancelled
+func copyN(ctx context.Context, out io.Writer, r io.Reader, n int64) (int64, error) {
+	cr := contextReader{ctx: ctx, r: r}
+	if n < 0 {
+		return io.Copy(out, cr)
+	}
+
+	return io.CopyN(out, cr, n)
+}
+
+type contextReader struct {
+	ctx context.Context
+	r   io.Reader
+}
+
+func (c contextReader) Read(p []byte) (int, error) {
+	if err := c.ctx.Err(); err != nil {
+		return 0, err
+	}
+
+	return c.r.Read(p)
+}
+
+func feedKernelCmd(cmd *cobra.Command, _ []string) error {
+	ctx := cmd.Context()
+	o, err := createORNG(cmd)
+	if err != nil {
+		return err
+	}
+	mode, err := readFlags(cmd)
+	if err != nil {
+		return err
+	}
+
+	f := cmd.Flags()
+	conditioner, err := f.GetString("conditioner")
+	if err != nil {
+		return err
+	}
+	n, err := f.GetInt("bytes")
+	if err != nil {
+		return err
+	}
+	creditBits, err := f.GetInt("credit-bits")
+	if err != nil {
+		return err
+	}
+	pollInterval, err := f.GetDuration("poll-interval")
+	if err != nil {
+		return err
+	}
+	procDir, err := f.GetString("proc-dir")
+	if err != nil {
+		return err
+	}
+	device, err := f.GetString("random-device")
+	if err != nil {
+		return err
+	}
+
+	_, err = o.Init(ctx, mode)
+	if err != nil {
+		return fmt.Errorf("init failed before feeding the kernel: %w", err)
+	}
+
+	c, err := o.NewConditioner(ctx, conditioner)
+	if err != nil {
+		return err
+	}
+
+	err = o.FeedKernel(ctx, mode, &onerng.FeedPolicy{
+		Pool:         &onerng.KernelPool{ProcDir: procDir, Device: device},
+		Conditioner:  c,
+		Bytes:        n,
+		CreditBits:   creditBits,
+		PollInterval: pollInterval,
+	})
+	if errors.Is(err, context.Canceled) {
+		// interrupted
+		return nil
+	}
+
+	return err
+}
+
+func egdCmd(cmd *cobra.Command, _ []string) error {
+	ctx, cancel := context.WithCancel(cmd.Context())
+	defer cancel()
+
+	o, err := createORNG(cmd)
+	if err != nil {
+		return err
+	}
+	mode, err := readFlags(cmd)
+	if err != nil {
+		return err
+	}
+
+	f := cmd.Flags()
+	socket, err := f.GetString("socket")
+	if err != nil {
+		return err
+	}
+	conditioner, err := f.GetString("conditioner")
+	if err != nil {
+		return err
+	}
+	size, err := f.GetInt("buffer-size")
+	if err != nil {
+		return err
+	}
+	if size <= 0 {
+		return fmt.Errorf("--buffer-size must be positive")
+	}
+	maxConns, err := f.GetInt("max-conns")
+	if err != nil {
+		return err
+	}
+	maxBytes, err := f.GetInt64("max-bytes-per-conn")
+	if err != nil {
+		return err
+	}
+	idleTimeout, err := f.GetDuration("idle-timeout")
+	if err != nil {
+		return err
+	}
+
+	_, err = o.Init(ctx, mode)
+	if err != nil {
+		return fmt.Errorf("init failed before serving: %w", err)
+	}
+
+	c, err := o.NewConditioner(ctx, conditioner)
+	if err != nil {
+		return err
+	}
+
+	// remove a socket left behind by a previous run, unless it's still being
+	// served
+	if fi, err := os.Lstat(socket); err == nil && fi.Mode()&os.ModeSocket != 0 {
+		if !staleSocket(ctx, socket) {
+			return fmt.Errorf("%s is in use - is another EGD server running?", socket)
+		}
+		_ = os.Remove(socket)
+	}
+
+	l, err := net.Listen("unix", socket)
+	if err != nil {
+		return err
+	}
+
+	// fill the buffer in the background, and stop serving if that fails
+	b, err := egd.NewBuffer(size)
+	if err != nil {
+		return err
+	}
+	readErr := make(chan error, 1)
+	go func() {
+		w := c.Writer(b)
+		_, err := o.Read(ctx, w, -1, mode)
+		_ = w.Close()
+		b.CloseWithError(err)
+		readErr <- err
+		cancel()
+	}()
+
+	s := &egd.Server{
+		Buffer:          b,
+		EntropyPerByte:  min(o.NewAccountant().Estimate(mode)/c.Ratio(), 8),
+		MaxConns:        maxConns,
+		MaxBytesPerConn: maxBytes,
+		IdleTimeout:     idleTimeout,
+		OnError: func(err error) {
+			fmt.Fprintf(os.Stderr, "warning: closed connection: %v\n", err)
+		},
+	}
+	err = s.Serve(ctx, l)
+
+	// stop filling the buffer, which may be waiting for space
+	cancel()
+	b.CloseWithError(context.Canceled)
+	if rerr := <-readErr; rerr != nil && !errors.Is(rerr, context.Canceled) {
+		return fmt.Errorf("failed to read from the OneRNG: %w", rerr)
+	}
+	if errors.Is(err, context.Canceled) {
+		// interrupted
+		return nil
+	}
+
+	return err
+}
+
+// staleSocket returns true if nothing is listening on the Unix socket at path
+func staleSocket(ctx context.Context, path string) bool {
+	var d net.Dialer
+	c, err := d.DialContext(ctx, "unix", path)
+	if err != nil {
+		return errors.Is(err, syscall.ECONNREFUSED)
+	}
+	_ = c.Close()
+
+	return false
+}
+
+func testCmd(cmd *cobra.Command, _ []string) error {
+	ctx := cmd.Context()
+	o, err := createORNG(cmd)
+	if err != nil {
+		return err
+	}
+	blocks, err := cmd.Flags().GetInt("blocks")
+	if err != nil {
+		return err
+	}
+	flags, err := readFlags(cmd)
+	if err != nil {
+		return err
+	}
+
+	// the tests aren't meant to catch a source that's still warming up, which
+	// Init takes care of
+	_, err = o.Init(ctx, flags)
+	if err != nil {
+		return fmt.Errorf("init failed before testing: %w", err)
+	}
+
+	s, err := o.SelfTest(ctx, flags, blocks, func(i int, res fips1402.Result) {
+		if !res.Passed() {
+			fmt.Fprintf(os.Stderr, "block %d: %s\n", i+1, res)
+		}
+	})
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("bits tested: %d (%d blocks)\n", s.Blocks*fips1402.BlockBits, s.Blocks)
+	fmt.Printf("successes: %d\n", s.Successes())
+	fmt.Printf("failures: %d\n", s.Failures)
+	for _, t := range fips1402.Tests {
+		fmt.Printf("  %s: %d\n", t, s.TestFailures[t])
+	}
+
+	verdict := "PASS"
+	if !s.Passed() {
+		verdict = "FAIL"
+	}
+	fmt.Printf("verdict: %s (at most %d failures expected)\n", verdict, fips1402.MaxFailures(s.Blocks))
+
+	return s.Err()
+}
+
+//nolint:gocyclo
+func assessCmd(cmd *cobra.Command, _ []string) error {
+	ctx := cmd.Context()
+	o, err := createORNG(cmd)
+	if err != nil {
+		return err
+	}
+	f := cmd.Flags()
+	n, err := f.GetInt("samples")
+	if err != nil {
+		return err
+	}
+	asJSON, err := f.GetBool("json")
+	if err != nil {
+		return err
+	}
+	disableAvalanche, err := f.GetBool("disable-avalanche")
+	if err != nil {
+		return err
+	}
+	enableRF, err := f.GetBool("enable-rf")
+	if err != nil {
+		return err
+	}
+
+	// the noise sources must be assessed before whitening
+	mode := onerng.DisableWhitener
+	if disableAvalanche {
+		mode |= onerng.DisableAvalanche
+	}
+	if enableRF {
+		mode |= onerng.EnableRF
+	}
+
+	_, err = o.Init(ctx, mode)
+	if err != nil {
+		return fmt.Errorf("init failed before assessment: %w", err)
+	}
+
+	samples, err := capture(ctx, o, mode, n)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(os.Stderr, "captured %d samples in mode %d, assessing...\n", len(samples), mode)
+	r, err := sp80090b.Assess(samples, 8)
+	if err != nil {
+		return err
+	}
+
+	if asJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+
+		return enc.Encode(struct {
+			*sp80090b.Report
+			Mode onerng.NoiseMode `json:"mode"`
+		}{r, mode})
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "ESTIMATOR\tSAMPLES (bits/bit)\tBITSTRING (bits/bit)")
+	for _, b := range r.Bitstring {
+		orig := "-"
+		for _, e := range r.Original {
+			if e.Name == b.Name {
+				orig = fmt.Sprintf("%.6f", e.MinEntropyPerBit)
+			}
+		}
+		fmt.Fprintf(w, "%s\t%s\t%.6f\n", b.Name, orig, b.MinEntropyPerBit)
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+
+	fmt.Printf("\nH_original: %.6f bits/sample\n", r.HOriginal)
+	fmt.Printf("H_bitstring: %.6f bits/bit\n", r.HBitstring)
+	fmt.Printf("min-entropy: %.6f bits/sample (%.6f bits/bit)\n", r.MinEntropy, r.MinEntropyPerBit)
+
+	return nil
+}
+
+func diagnoseCmd(cmd *cobra.Command, _ []string) error {
+	ctx := cmd.Context()
+	o, err := createORNG(cmd)
+	if err != nil {
+		return err
+	}
+	_, err = o.Init(ctx, onerng.Default)
+	if err != nil {
+		return fmt.Errorf("init failed before diagnosis: %w", err)
+	}
+
+	f := cmd.Flags()
+	n, err := f.GetInt("samples")
+	if err != nil {
+		return err
+	}
+	timeout, err := f.GetDuration("timeout")
+	if err != nil {
+		return err
+	}
+	asJSON, err := f.GetBool("json")
+	if err != nil {
+		return err
+	}
+
+	ds, err := o.Diagnose(ctx, n, timeout, func(d *onerng.Diagnosis) {
+		fmt.Fprintf(os.Stderr, "mode %d (%s): %d bytes, %s\n", d.Mode, d.Mode, d.Bytes, d.Faults)
+	})
+	if err != nil {
+		return err
+	}
+
+	if asJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		err = enc.Encode(ds)
+	} else {
+		err = printDiagnoses(ds)
+	}
+	if err != nil {
+		return err
+	}
+
+	faulty := 0
+	for _, d := range ds {
+		if d.Faults != 0 {
+			faulty++
+		}
+	}
+	if faulty > 0 {
+		return fmt.Errorf("%w: %d of %d noise modes look faulty", onerng.ErrDiagnosisFailed, faulty, len(ds))
+	}
+
+	return nil
+}
+
+func printDiagnoses(ds []*onerng.Diagnosis) error {
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "MODE\tNAME\tBYTES\tRATE\tBIAS\tENTROPY\tMIN-ENTROPY (EXPECTED)\tLONGEST REPEAT\tCORRELATION (LAG)\tSTATUS")
+	for _, d := range ds {
+		if d.Bytes == 0 {
+			fmt.Fprintf(w, "%d\t%s\t0\t-\t-\t-\t- (%.1f)\t-\t-\t%s\n", d.Mode, d.Mode, d.Expected, d.Faults)
+
+			continue
+		}
+
+		fmt.Fprintf(w, "%d\t%s\t%d\t%s/s\t%+.4f\t%.4f\t%.4f (%.1f)\t%d\t%+.4f (%d)\t%s\n",
+			d.Mode, d.Mode, d.Bytes, humanizeBytes(d.Throughput), d.Bias, d.Entropy,
+			d.MinEntropy, d.Expected, d.LongestRepeat, d.Correlation, d.CorrelationLag, d.Faults)
+	}
+
+	return w.Flush()
+}
+
+func statsCmd(cmd *cobra.Command, _ []string) error {
+	f := cmd.Flags()
+	input, err := f.GetString("input")
+	if err != nil {
+		return err
+	}
+	bits, err := f.GetBool("bits")
+	if err != nil {
+		return err
+	}
+
+	g := analysis.Bytes
+	if bits {
+		g = analysis.Bits
+	}
+	a := analysis.NewAnalyzer(g)
+
+	if input != "" {
+		in := io.Reader(os.Stdin)
+		if input != "-" {
+			file, err := os.Open(input)
+			if err != nil {
+				return err
+			}
+			defer file.Close()
+			in = file
+		}
+
+		_, err = io.Copy(a, in)
+		if err != nil {
+			return err
+		}
+	} else {
+		count, err := f.GetInt64("count")
+		if err != nil {
+			return err
+		}
+
+		err = deviceRead(cmd, a, count)
+		if err != nil {
+			return err
+		}
+	}
+
+	printStats(a.Result())
+
+	return nil
+}
+
+// deviceRead initializes the device and reads count bytes into out, with the
+// noise mode from the command's flags
+func deviceRead(cmd *cobra.Command, out io.Writer, count int64) error {
+	ctx := cmd.Context()
+	o, err := createORNG(cmd)
+	if err != nil {
+		return err
+	}
+	flags, err := readFlags(cmd)
+	if err != nil {
+		return err
+	}
+	_, err = o.Init(ctx, flags)
+	if err != nil {
+		return fmt.Errorf("init failed before read: %w", err)
+	}
+
+	_, err = o.Read(ctx, out, count, flags)
+
+	return err
+}
+
+// printStats prints the results in the same format as ent
+func printStats(r analysis.Result) {
+	unit := r.Granularity.String()
+	randomMean := 127.5
+	if r.Granularity == analysis.Bits {
+		randomMean = 0.5
+	}
+
+	fmt.Printf("Entropy = %f bits per %s.\n\n", r.Entropy, unit)
+	fmt.Printf("Optimum compression would reduce the size\nof this %d %s file by %d percent.\n\n",
+		r.Samples, unit, int(r.Compression))
+	fmt.Printf("Chi square distribution for %d samples is %1.2f, and randomly\n", r.Samples, r.ChiSquare)
+	switch p := r.ChiSquareP * 100; {
+	case p < 0.01:
+		fmt.Printf("would exceed this value less than 0.01 percent of the times.\n\n")
+	case p > 99.99:
+		fmt.Printf("would exceed this value more than 99.99 percent of the times.\n\n")
+	default:
+		fmt.Printf("would exceed this value %1.2f percent of the times.\n\n", p)
+	}
+	fmt.Printf("Arithmetic mean value of data %ss is %1.4f (%.1f = random).\n",
+		unit, r.Mean, randomMean)
+	fmt.Printf("Monte Carlo value for Pi is %1.9f (error %1.2f percent).\n", r.MonteCarloPi, r.MonteCarloPiError)
+	if math.IsNaN(r.SerialCorrelation) {
+		fmt.Printf("Serial correlation coefficient is undefined (all values equal!).\n")
+	} else {
+		fmt.Printf("Serial correlation coefficient is %f (totally uncorrelated = 0.0).\n", r.SerialCorrelation)
+	}
+}
+
+func stsCmd(cmd *cobra.Command, _ []string) error {
+	f := cmd.Flags()
+	input, err := f.GetString("input")
+	if err != nil {
+		return err
+	}
+	streams, err := f.GetInt("streams")
+	if err != nil {
+		return err
+	}
+	length, err := f.GetInt("length")
+	if err != nil {
+		return err
+	}
+	alpha, err := f.GetFloat64("alpha")
+	if err != nil {
+		return err
+	}
+	if streams < 1 || length < 1 {
+		return fmt.Errorf("--streams and --length must be positive")
+	}
+
+	streamBytes := (length + 7) / 8
+	buf := &bytes.Buffer{}
+	source := "OneRNG " + cmd.Flag("device").Value.String()
+
+	if input != "" {
+		source = input
+		in := io.Reader(os.Stdin)
+		if input != "-" {
+			file, err := os.Open(input)
+			if err != nil {
+				return err
+			}
+			defer file.Close()
+			in = file
+		}
+
+		_, err = io.CopyN(buf, in, int64(streams*streamBytes))
+		if err != nil && !errors.Is(err, io.EOF) {
+			return err
+		}
+
+		if n := buf.Len() / streamBytes; n < streams {
+			if n == 0 {
+				return fmt.Errorf("%w: %s is shorter than one %d-bit stream", onerng.ErrShortRead, input, length)
+			}
+
+			fmt.Fprintf(os.Stderr, "warning: only enough data for %d streams\n", n)
+			streams = n
+		}
+	} else {
+		err = deviceRead(cmd, buf, int64(streams*streamBytes))
+		if err != nil {
+			return err
+		}
+	}
+
+	rep := sts.NewReport(alpha)
+	rep.Source = source
+	data := buf.Bytes()
+
+	// the tests are CPU-bound, so run one stream per CPU at a time
+	results := make([][]sts.Result, streams)
+	sem := make(chan struct{}, runtime.GOMAXPROCS(0))
+	wg := sync.WaitGroup{}
+	for i := range results {
+		sem <- struct{}{}
+		wg.Add(1)
+		go func() {
+			defer func() { <-sem; wg.Done() }()
+
+			seq := sts.Bits(data[i*streamBytes : (i+1)*streamBytes])
+			results[i] = sts.Run(seq[:length])
+		}()
+	}
+	wg.Wait()
+
+	for _, r := range results {
+		rep.Add(r)
+	}
+
+	_, err = rep.WriteTo(os.Stdout)
+	if err != nil {
+		return err
+	}
+
+	return rep.Err()
+}
+
+// capture reads n raw bytes from the device, without health tests
+func capture(ctx context.Context, o *onerng.OneRNG, mode onerng.NoiseMode, n int) ([]byte, error) {
+	s, err := o.Open(ctx, mode)
+	if err != nil {
+		return nil, err
+	}
+	defer s.Close()
+
+	buf := make([]byte, n)
+	_, err = io.ReadFull(s.Raw(), buf)
+	if err != nil {
+		return nil, fmt.Errorf("failed to capture samples: %w", err)
+	}
+
+	return buf, nil
+}
+
+// reportGap warns about interruptions in the stream during reads
+func reportGap(g onerng.Gap) {
+	fmt.Fprintf(os.Stderr, "warning: device disconnected after %s (%v), reconnected at %s after %s\n",
+		humanizeBytes(float64(g.Written)), g.Err, g.Path, g.End.Sub(g.Start).Round(time.Millisecond))
+}
+
 // humanizeBytes produces a human readable representation of an IEC size.
 // Taken from github.com/dustin/go-humanize
 func humanizeBytes(s float64) string {
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"net"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/hairyhenderson/go-onerng/emulator"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReadCmd_EmulatedStall(t *testing.T) {
+	testdata := []struct {
+		name string
+		args []string
+	}{
+		{"conditioner", nil},
+		{"drbg", []string{"--drbg", "ctr", "--reseed-bytes", "4096"}},
+		{"expand", []string{"--expand", "--reseed-bytes", "4096"}},
+	}
+
+	for _, d := range testdata {
+		t.Run(d.name, func(t *testing.T) {
+			// enough data to initialize and seed, then nothing more
+			data := make([]byte, 16<<10)
+			_, err := rand.Read(data)
+			require.NoError(t, err)
+
+			dev := &emulator.Device{Source: bytes.NewReader(data)}
+			require.NoError(t, dev.Start())
+			t.Cleanup(func() { _ = dev.Close() })
+
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+			defer cancel()
+
+			cmd := commands()
+			cmd.SetArgs(append([]string{
+				"read", "-d", dev.Path(), "-o", filepath.Join(t.TempDir(), "out"),
+				"--warmup", "0", "--read-timeout", "50ms", "--allowed-timeouts", "0",
+			}, d.args...))
+
+			err = cmd.ExecuteContext(ctx)
+			require.Error(t, err)
+			require.NoError(t, ctx.Err())
+			assert.Equal(t, exitTimeout, exitCode(err))
+		})
+	}
+}
+
+func TestStaleSocket(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "egd.sock")
+	l, err := net.Listen("unix", path)
+	require.NoError(t, err)
+	l.(*net.UnixListener).SetUnlinkOnClose(false)
+
+	ctx := context.Background()
+	assert.False(t, staleSocket(ctx, path))
+
+	// the socket file is left behind, with nothing listening
+	require.NoError(t, l.Close())
+	assert.True(t, staleSocket(ctx, path))
+
+	assert.False(t, staleSocket(ctx, filepath.Join(t.TempDir(), "missing.sock")))
+}
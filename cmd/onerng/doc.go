@@ -4,5 +4,26 @@ onerng is a OneRNG hardware random number generation utility.
 # Usage
 
 TODO...
+
+# Exit Codes
+
+	0  success
+	1  unclassified error
+	2  device not found
+	3  device busy (locked by another process)
+	4  timed out waiting for data from the device
+	5  device is not a OneRNG
+	6  short read
+	7  bad firmware image
+	8  firmware signature invalid
+	9  noise source health test failed
+	10 statistical self-test failed (see the test command)
+	11 SP 800-22 statistical tests failed (see the sts command)
+	12 device failed its start-up tests, for a reason not covered above
+	13 not enough entropy credited for the output (see --min-credited and
+	   --full-entropy)
+	14 no entropy would be credited to the kernel's pool (see the feed-kernel
+	   command)
+	15 one or more noise modes look faulty (see the diagnose command)
 */
 package main
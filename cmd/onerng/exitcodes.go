@@ -0,0 +1,67 @@
+package main
+
+import (
+	"errors"
+
+	"github.com/hairyhenderson/go-onerng"
+	"github.com/hairyhenderson/go-onerng/fips1402"
+	"github.com/hairyhenderson/go-onerng/sts"
+)
+
+// exit codes - see doc.go
+const (
+	exitOK = iota
+	exitError
+	exitDeviceNotFound
+	exitDeviceBusy
+	exitTimeout
+	exitNotOneRNG
+	exitShortRead
+	exitBadImage
+	exitSignatureInvalid
+	exitHealthTest
+	exitSelfTest
+	exitStatisticalTests
+	exitNotReady
+	exitInsufficientEntropy
+	exitNoEntropyCredited
+	exitDiagnosisFailed
+)
+
+// exitCode maps an error to a distinct exit code, so that scripts can tell
+// different failures apart
+func exitCode(err error) int {
+	codes := []struct {
+		err  error
+		code int
+	}{
+		// more specific errors first - a bad image may also be a short read
+		{onerng.ErrHealthTest, exitHealthTest},
+		{fips1402.ErrFailed, exitSelfTest},
+		{sts.ErrFailed, exitStatisticalTests},
+		{onerng.ErrSignatureInvalid, exitSignatureInvalid},
+		{onerng.ErrBadImage, exitBadImage},
+		{onerng.ErrDeviceNotFound, exitDeviceNotFound},
+		{onerng.ErrDeviceBusy, exitDeviceBusy},
+		{onerng.ErrNotOneRNG, exitNotOneRNG},
+		{onerng.ErrTimeout, exitTimeout},
+		{onerng.ErrShortRead, exitShortRead},
+		{onerng.ErrInsufficientEntropy, exitInsufficientEntropy},
+		{onerng.ErrNoEntropyCredited, exitNoEntropyCredited},
+		{onerng.ErrDiagnosisFailed, exitDiagnosisFailed},
+		// Init's errors wrap the cause, which is more specific
+		{onerng.ErrNotReady, exitNotReady},
+	}
+
+	if err == nil {
+		return exitOK
+	}
+
+	for _, c := range codes {
+		if errors.Is(err, c.err) {
+			return c.code
+		}
+	}
+
+	return exitError
+}
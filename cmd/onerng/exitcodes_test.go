@@ -0,0 +1,31 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/hairyhenderson/go-onerng"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExitCode(t *testing.T) {
+	testdata := []struct {
+		err  error
+		code int
+	}{
+		{nil, exitOK},
+		{errors.New("bad flag"), exitError},
+		{fmt.Errorf("wrapped: %w", onerng.ErrDeviceBusy), exitDeviceBusy},
+		{fmt.Errorf("wrapped: %w", onerng.ErrInsufficientEntropy), exitInsufficientEntropy},
+		{fmt.Errorf("wrapped: %w", onerng.ErrNoEntropyCredited), exitNoEntropyCredited},
+		{fmt.Errorf("wrapped: %w", onerng.ErrDiagnosisFailed), exitDiagnosisFailed},
+		// the cause of a start-up failure takes precedence
+		{fmt.Errorf("%w: %w", onerng.ErrNotReady, onerng.ErrTimeout), exitTimeout},
+		{fmt.Errorf("%w: %w", onerng.ErrNotReady, errors.New("EOF")), exitNotReady},
+	}
+
+	for _, d := range testdata {
+		assert.Equal(t, d.code, exitCode(d.err), "%v", d.err)
+	}
+}
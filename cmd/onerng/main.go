@@ -7,7 +7,10 @@ import (
 	"fmt"
 	"os"
 	"os/signal"
+	"strings"
+	"time"
 
+	"github.com/hairyhenderson/go-onerng"
 	"github.com/hairyhenderson/go-onerng/version"
 	"github.com/spf13/cobra"
 )
@@ -28,7 +31,17 @@ correctly, and that the firmware has not been tampered with.`,
 			return nil
 		},
 	}
-	cmd.PersistentFlags().StringP("device", "d", "/dev/ttyACM0", "the OneRNG device")
+	cmd.PersistentFlags().StringP("device", "d", "/dev/ttyACM0", "the OneRNG device (use auto to find it automatically)")
+	cmd.PersistentFlags().Duration("lock-timeout", 0, "how long to wait for another process to release the device")
+	cmd.PersistentFlags().Duration("read-timeout", onerng.DefaultReadTimeout, "how long to wait for data on each read before counting a timeout")
+	cmd.PersistentFlags().Int("allowed-timeouts", onerng.DefaultAllowedTimeouts, "number of read timeouts to tolerate before giving up")
+	cmd.PersistentFlags().Int("init-attempts", onerng.DefaultInitAttempts, "number of times to try getting data from the device during init")
+	cmd.PersistentFlags().Duration("init-timeout", onerng.DefaultInitTimeout, "how long to wait for data on each init attempt")
+	cmd.PersistentFlags().Int("image-end-zeros", onerng.DefaultImageEndZeros, "number of consecutive zero bytes marking the end of the firmware image")
+	cmd.PersistentFlags().Int64("warmup", onerng.DefaultWarmup, "number of bytes to discard during init, while the noise sources settle")
+	cmd.PersistentFlags().Bool("health-tests", true, "run continuous health tests on the data read, and stop if they fail")
+	cmd.PersistentFlags().Float64("min-entropy", 0, "claimed min-entropy in bits per byte, which sets the health test cutoffs (0 for the noise mode's default)")
+	cmd.PersistentFlags().BoolP("verbose", "v", false, "log diagnostic messages to stderr")
 
 	flush := &cobra.Command{
 		Use:   "flush",
@@ -42,14 +55,24 @@ correctly, and that the firmware has not been tampered with.`,
 	}
 	init := &cobra.Command{
 		Use:   "init",
-		Short: "Initialize the RNG",
-		RunE:  initCmd,
+		Short: "Initialize the RNG, and run the start-up self-tests",
+		Long: `Wait for the OneRNG to start sending data, discard a warm-up (see
+--warmup), then run the continuous health tests on 1024 consecutive samples,
+as NIST SP 800-90B requires at start-up. Every command that reads data does
+this first.`,
+		RunE: initCmd,
 	}
+	addNoiseFlags(init)
 	verify := &cobra.Command{
 		Use:   "verify",
 		Short: "Verify that OneRNG's firmware has not been tampered with.",
 		RunE:  verifyCmd,
 	}
+	list := &cobra.Command{
+		Use:   "list",
+		Short: "List the OneRNG devices attached to this system",
+		RunE:  listCmd,
+	}
 	version := &cobra.Command{
 		Use:   "version",
 		Short: "Display the OneRNG's hardware version",
@@ -68,17 +91,140 @@ correctly, and that the firmware has not been tampered with.`,
 		RunE:  readCmd,
 	}
 	read.Flags().StringP("out", "o", "-", "output file for data (use - for stdout)")
-	read.Flags().Bool("disable-avalanche", false, "Disable noise generation from the Avalanche Diode")
-	read.Flags().Bool("enable-rf", false, "Enable noise generation from RF")
-	read.Flags().Bool("disable-whitener", false, "Disable the on-board CRC16 generator")
+	addNoiseFlags(read)
 	read.Flags().Int64P("count", "n", -1, "Read only N bytes (use -1 for unlimited)")
+	read.Flags().Bool("reconnect", false, "wait for the device to reconnect if it disappears (i.e. is unplugged), and continue reading")
+	read.Flags().String("conditioner", "aes-cfb", "post-process the data with this conditioner ("+strings.Join(onerng.Conditioners(), ", ")+")")
 	read.Flags().Bool("aes-whitener", true, "encrypt with AES-128 to 'whiten' the input stream with a random key obtained from the OneRNG")
+	_ = read.Flags().MarkDeprecated("aes-whitener", "use --conditioner aes-cfb (the default) or --conditioner none instead")
+	read.Flags().Float64("min-credited", 0, "hold back the conditioned output until this many bits of min-entropy have been credited to the data read")
+	read.Flags().Bool("full-entropy", false, "only output conditioned data that's covered by credited min-entropy (8 bits per byte), discarding the rest")
+	read.Flags().Int("measure-entropy", 0, "measure the noise mode's min-entropy from this many samples before reading, instead of using the claimed min-entropy")
+	read.Flags().String("drbg", "", "generate the output with an SP 800-90A DRBG seeded from the OneRNG, instead of whitening (ctr, hmac-sha256, hmac-sha512, hash-sha256, or hash-sha512)")
+	read.Flags().Bool("expand", false, "expand a seed from the OneRNG with a fast ChaCha20 keystream generator, reseeding in the background, instead of whitening")
+	read.Flags().Int64("reseed-bytes", 1<<20, "with --drbg or --expand, reseed from the OneRNG after this many bytes (0 to only reseed when required)")
+	read.Flags().Duration("reseed-interval", time.Minute, "with --drbg or --expand, reseed from the OneRNG after this long (0 to never reseed based on time)")
+	read.Flags().Bool("prediction-resistance", false, "with --drbg, reseed from the OneRNG before generating each chunk of output")
+
+	feedKernel := &cobra.Command{
+		Use:   "feed-kernel",
+		Short: "Keep the kernel's entropy pool topped up from the OneRNG, like rngd",
+		Long: `Watch the kernel's entropy pool, and whenever it holds less entropy than
+its wakeup threshold (write_wakeup_threshold), read data from the OneRNG,
+health-test and condition it, and add it to the pool with the RNDADDENTROPY
+ioctl.
+
+Each addition is credited with the min-entropy accounted to the conditioned
+data (see --min-entropy), or --credit-bits if that's lower. This needs
+CAP_SYS_ADMIN (i.e. root).`,
+		RunE: feedKernelCmd,
+	}
+	addNoiseFlags(feedKernel)
+	feedKernel.Flags().String("conditioner", "sha256-compress", "condition the data with this conditioner before adding it ("+strings.Join(onerng.Conditioners(), ", ")+")")
+	feedKernel.Flags().Int("bytes", onerng.DefaultFeedBytes, "number of bytes of conditioned data to add at a time")
+	feedKernel.Flags().Int("credit-bits", 0, "number of bits of entropy to credit for each addition, at most (0 to credit what's accounted)")
+	feedKernel.Flags().Duration("poll-interval", onerng.DefaultPollInterval, "how often to check the kernel's entropy pool")
+	feedKernel.Flags().String("proc-dir", onerng.DefaultProcDir, "where the kernel's random sysctls are")
+	feedKernel.Flags().String("random-device", onerng.DefaultRandomDevice, "the device to add entropy with")
+
+	egd := &cobra.Command{
+		Use:   "egd",
+		Short: "Serve random data from the OneRNG over the EGD protocol",
+		Long: `Serve random data from the OneRNG on a Unix socket, using the Entropy
+Gathering Daemon (EGD) protocol, for clients such as OpenSSL and GnuPG.
+
+The data is conditioned and buffered in the background. Each connection can
+read at most --max-bytes-per-conn bytes.`,
+		RunE: egdCmd,
+	}
+	addNoiseFlags(egd)
+	egd.Flags().String("socket", "", "the Unix socket to listen on (required)")
+	_ = egd.MarkFlagRequired("socket")
+	egd.Flags().String("conditioner", "aes-cfb", "post-process the data with this conditioner ("+strings.Join(onerng.Conditioners(), ", ")+")")
+	egd.Flags().Int("buffer-size", 4096, "number of bytes of data to keep buffered")
+	egd.Flags().Int("max-conns", 16, "number of connections to serve at once (0 for no limit)")
+	egd.Flags().Int64("max-bytes-per-conn", 1<<20, "number of bytes to serve on each connection before closing it (0 for no limit)")
+	egd.Flags().Duration("idle-timeout", time.Minute, "close connections that are idle for this long (0 for no timeout)")
+
+	test := &cobra.Command{
+		Use:   "test",
+		Short: "Run the FIPS 140-2 statistical tests on data from the OneRNG",
+		Long: `Run the FIPS 140-2 statistical tests (as rngtest does) on 20,000-bit
+blocks of data from the OneRNG.
+
+A good source fails occasionally, so the tests only fail overall when more
+blocks fail than can reasonably be expected by chance.`,
+		RunE: testCmd,
+	}
+	addNoiseFlags(test)
+	test.Flags().IntP("blocks", "n", 100, "number of 20,000-bit blocks to test")
+
+	assess := &cobra.Command{
+		Use:   "assess",
+		Short: "Estimate the min-entropy of the raw noise sources (NIST SP 800-90B)",
+		Long: `Capture raw (unwhitened) samples from the OneRNG, and estimate their
+min-entropy with the non-IID estimators from NIST SP 800-90B.
 
-	cmd.AddCommand(flush, id, init, image, read, verify, version)
+800-90B expects at least 1,000,000 samples. This takes a while.`,
+		RunE: assessCmd,
+	}
+	assess.Flags().Bool("disable-avalanche", false, "Disable noise generation from the Avalanche Diode")
+	assess.Flags().Bool("enable-rf", false, "Enable noise generation from RF")
+	assess.Flags().IntP("samples", "n", 1000000, "number of 8-bit samples to capture")
+	assess.Flags().Bool("json", false, "output the results as JSON")
+
+	diagnose := &cobra.Command{
+		Use:   "diagnose",
+		Short: "Measure each noise mode, and flag any that look dead, stuck, or dominated by interference",
+		Long: `Cycle through all eight combinations of the noise sources and the
+whitener, measuring throughput, bias, entropy, and repetition in each.
+
+The silent modes (with the avalanche diode and RF both disabled) should
+produce nothing - any entropy there suggests interference.`,
+		RunE: diagnoseCmd,
+	}
+	diagnose.Flags().IntP("samples", "n", 65536, "number of bytes to measure in each mode")
+	diagnose.Flags().Duration("timeout", 5*time.Second, "how long to wait for the samples in each mode")
+	diagnose.Flags().Bool("json", false, "output the results as JSON")
+
+	stats := &cobra.Command{
+		Use:   "stats",
+		Short: "Summarize the randomness of data from the OneRNG or a file, like ent",
+		RunE:  statsCmd,
+	}
+	addNoiseFlags(stats)
+	stats.Flags().StringP("input", "i", "", "analyze this file instead of reading from the device (use - for stdin)")
+	stats.Flags().Int64P("count", "n", 1<<20, "number of bytes to read from the device")
+	stats.Flags().BoolP("bits", "b", false, "treat the data as a stream of bits, rather than bytes")
+
+	sts := &cobra.Command{
+		Use:   "sts",
+		Short: "Run the NIST SP 800-22 statistical test suite on data from the OneRNG or a file",
+		Long: `Run the 15 statistical tests from NIST SP 800-22 on a number of bit
+streams, and report the uniformity of the p-values and the proportion of
+streams passing each test, as the NIST reference implementation (sts) does.
+
+The defaults (100 streams of 1,000,000 bits) need 12.5MB of data.`,
+		RunE: stsCmd,
+	}
+	addNoiseFlags(sts)
+	sts.Flags().StringP("input", "i", "", "test this file instead of reading from the device (use - for stdin)")
+	sts.Flags().IntP("streams", "n", 100, "number of bit streams to test")
+	sts.Flags().Int("length", 1000000, "length of each bit stream, in bits")
+	sts.Flags().Float64("alpha", 0.01, "significance level")
+
+	cmd.AddCommand(assess, diagnose, egd, feedKernel, flush, id, init, image, list, read, stats, sts, test, verify, version)
 
 	return cmd
 }
 
+// addNoiseFlags adds the flags for choosing the noise mode (see readFlags)
+func addNoiseFlags(cmd *cobra.Command) {
+	cmd.Flags().Bool("disable-avalanche", false, "Disable noise generation from the Avalanche Diode")
+	cmd.Flags().Bool("enable-rf", false, "Enable noise generation from RF")
+	cmd.Flags().Bool("disable-whitener", false, "Disable the on-board CRC16 generator")
+}
+
 func main() {
 	returncode := 0
 	defer func() { os.Exit(returncode) }()
@@ -101,6 +247,6 @@ func main() {
 	cmd := commands()
 	if err := cmd.ExecuteContext(ctx); err != nil {
 		fmt.Fprintln(os.Stderr, err)
-		returncode = 1
+		returncode = exitCode(err)
 	}
 }
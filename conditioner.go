@@ -0,0 +1,229 @@
+package onerng
+
+import (
+	"context"
+	"crypto/cipher"
+	"fmt"
+	"io"
+	"slices"
+	"sync"
+
+	"github.com/hairyhenderson/go-onerng/extract"
+	"golang.org/x/crypto/chacha20"
+)
+
+// Conditioner - post-processes data from the device, for example to remove
+// bias or to mix it with a keyed cipher. Conditioners can be registered by
+// name with RegisterConditioner.
+type Conditioner interface {
+	// Writer returns a writer which conditions the data written to it, and
+	// writes the output to w. Close flushes any buffered output (but doesn't
+	// close w), and must be called when done.
+	Writer(w io.Writer) io.WriteCloser
+	// Reader returns a reader which conditions the data read from r
+	Reader(r io.Reader) io.Reader
+	// Ratio - the amount of output produced for each byte of input. A
+	// conditioner which compresses its input (with a ratio below 1) packs
+	// the input's entropy into less output, so the output has more entropy
+	// per byte.
+	Ratio() float64
+}
+
+// ConditionerFunc - creates a Conditioner. Conditioners which need key
+// material can read it from the device.
+type ConditionerFunc func(ctx context.Context, o *OneRNG) (Conditioner, error)
+
+var (
+	conditioners   = map[string]ConditionerFunc{}
+	conditionersMu sync.RWMutex
+)
+
+// the built-in conditioners
+func init() {
+	RegisterConditioner("none", func(context.Context, *OneRNG) (Conditioner, error) {
+		return passthrough{}, nil
+	})
+	RegisterConditioner("aes-cfb", func(ctx context.Context, o *OneRNG) (Conditioner, error) {
+		return o.aesCFB(ctx)
+	})
+	RegisterConditioner("chacha20", func(ctx context.Context, o *OneRNG) (Conditioner, error) {
+		return o.chacha20(ctx)
+	})
+	RegisterConditioner("sha256-compress", func(context.Context, *OneRNG) (Conditioner, error) {
+		return newSHA256Compressor()
+	})
+	RegisterConditioner("von-neumann", func(context.Context, *OneRNG) (Conditioner, error) {
+		return vonNeumann{}, nil
+	})
+	RegisterConditioner("iterated-von-neumann", func(context.Context, *OneRNG) (Conditioner, error) {
+		return vonNeumann{levels: 4}, nil
+	})
+}
+
+// RegisterConditioner makes a Conditioner available by name (see
+// NewConditioner). Registering a name that's already registered replaces it.
+func RegisterConditioner(name string, f ConditionerFunc) {
+	conditionersMu.Lock()
+	defer conditionersMu.Unlock()
+
+	conditioners[name] = f
+}
+
+// Conditioners returns the names of the registered conditioners, sorted
+func Conditioners() []string {
+	conditionersMu.RLock()
+	defer conditionersMu.RUnlock()
+
+	names := make([]string, 0, len(conditioners))
+	for name := range conditioners {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+
+	return names
+}
+
+// NewConditioner creates the named Conditioner (see RegisterConditioner). The
+// built-in conditioners are:
+//
+//   - none: passes data through unchanged
+//   - aes-cfb: the legacy AES-128-CFB whitener (see AESWhitener)
+//   - chacha20: encrypts with ChaCha20, keyed from the device
+//   - sha256-compress: replaces every 64 bytes with their SHA-256 hash
+//   - von-neumann: removes bias from independent bits, discarding at least
+//     three quarters of them
+//   - iterated-von-neumann: removes bias like von-neumann, but recovers
+//     much of what it discards
+func (o *OneRNG) NewConditioner(ctx context.Context, name string) (Conditioner, error) {
+	conditionersMu.RLock()
+	f, ok := conditioners[name]
+	conditionersMu.RUnlock()
+
+	if !ok {
+		return nil, fmt.Errorf("unknown conditioner %q (must be one of %v)", name, Conditioners())
+	}
+
+	return f(ctx, o)
+}
+
+// passthrough - the "none" conditioner
+type passthrough struct{}
+
+func (passthrough) Writer(w io.Writer) io.WriteCloser { return nopWriteCloser{w} }
+func (passthrough) Reader(r io.Reader) io.Reader      { return r }
+func (passthrough) Ratio() float64                    { return 1 }
+
+// streamConditioner - XORs the data with a keystream
+type streamConditioner struct {
+	s cipher.Stream
+}
+
+func (c *streamConditioner) Writer(w io.Writer) io.WriteCloser {
+	return nopWriteCloser{&cipher.StreamWriter{S: c.s, W: w}}
+}
+
+func (c *streamConditioner) Reader(r io.Reader) io.Reader {
+	return &cipher.StreamReader{S: c.s, R: r}
+}
+
+func (c *streamConditioner) Ratio() float64 { return 1 }
+
+func (o *OneRNG) aesCFB(ctx context.Context) (*streamConditioner, error) {
+	s, err := o.aesCFBStream(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return &streamConditioner{s: s}, nil
+}
+
+func (o *OneRNG) chacha20(ctx context.Context) (*streamConditioner, error) {
+	k, err := o.key(ctx, chacha20.KeySize+chacha20.NonceSize)
+	if err != nil {
+		return nil, err
+	}
+
+	s, err := chacha20.NewUnauthenticatedCipher(k[:chacha20.KeySize], k[chacha20.KeySize:])
+	if err != nil {
+		return nil, err
+	}
+
+	return &streamConditioner{s: s}, nil
+}
+
+// sha256Compressor - replaces each 64-byte block with its 32-byte SHA-256
+// hash (see extract.SHA256). A partial block at the end is discarded.
+type sha256Compressor struct {
+	ratio      int
+	minEntropy float64
+}
+
+func newSHA256Compressor() (sha256Compressor, error) {
+	// the input's min-entropy only affects the extractor's stats, which
+	// aren't used here
+	c := sha256Compressor{ratio: 2, minEntropy: 8}
+	if _, err := extract.NewSHA256(io.Discard, c.ratio, c.minEntropy); err != nil {
+		return sha256Compressor{}, err
+	}
+
+	return c, nil
+}
+
+func (c sha256Compressor) Writer(w io.Writer) io.WriteCloser {
+	return c.extractor(w)
+}
+
+func (c sha256Compressor) Reader(r io.Reader) io.Reader {
+	return extract.NewReader(r, c.extractor)
+}
+
+func (c sha256Compressor) extractor(w io.Writer) extract.Extractor {
+	e, err := extract.NewSHA256(w, c.ratio, c.minEntropy)
+	if err != nil {
+		// the parameters were checked by newSHA256Compressor
+		panic(err)
+	}
+
+	return e
+}
+
+func (sha256Compressor) Ratio() float64 { return 0.5 }
+
+// vonNeumann - the von Neumann debiaser, iterated to the given number of
+// levels (see extract.VonNeumann). This removes bias entirely, as long as the
+// bits are independent.
+type vonNeumann struct {
+	levels int
+}
+
+func (c vonNeumann) Writer(w io.Writer) io.WriteCloser {
+	return c.extractor(w)
+}
+
+func (c vonNeumann) Reader(r io.Reader) io.Reader {
+	return extract.NewReader(r, c.extractor)
+}
+
+func (c vonNeumann) extractor(w io.Writer) extract.Extractor {
+	return extract.NewVonNeumann(w, max(c.levels, 1))
+}
+
+// Ratio - for unbiased input, a quarter for the plain debiaser, and about
+// two thirds with 4 levels - biased input produces less
+func (c vonNeumann) Ratio() float64 {
+	if c.levels > 1 {
+		return vonNeumannIteratedRatio
+	}
+
+	return 0.25
+}
+
+// vonNeumannIteratedRatio - the measured output ratio of the 4-level iterated
+// von Neumann debiaser, for unbiased input
+const vonNeumannIteratedRatio = 0.68
+
+type nopWriteCloser struct {
+	io.Writer
+}
+
+func (nopWriteCloser) Close() error { return nil }
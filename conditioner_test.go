@@ -0,0 +1,181 @@
+package onerng
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"io"
+	"math/rand/v2"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConditioners(t *testing.T) {
+	assert.Subset(t, Conditioners(), []string{"aes-cfb", "chacha20", "iterated-von-neumann", "none", "sha256-compress", "von-neumann"})
+
+	o := New("/dev/null", WithDialer(randomDialer()))
+	_, err := o.NewConditioner(context.Background(), "bogus")
+	require.ErrorContains(t, err, `unknown conditioner "bogus"`)
+}
+
+// customConditioner - a third-party conditioner
+type customConditioner struct{ passthrough }
+
+func (customConditioner) Ratio() float64 { return 2 }
+
+func TestRegisterConditioner(t *testing.T) {
+	RegisterConditioner("test-custom", func(context.Context, *OneRNG) (Conditioner, error) {
+		return customConditioner{}, nil
+	})
+	defer func() {
+		conditionersMu.Lock()
+		delete(conditioners, "test-custom")
+		conditionersMu.Unlock()
+	}()
+
+	assert.Contains(t, Conditioners(), "test-custom")
+
+	o := New("/dev/null", WithDialer(randomDialer()))
+	c, err := o.NewConditioner(context.Background(), "test-custom")
+	require.NoError(t, err)
+	assert.InDelta(t, 2, c.Ratio(), 0)
+}
+
+// condition passes the input through the conditioner's Writer
+func condition(t *testing.T, c Conditioner, in []byte) []byte {
+	t.Helper()
+
+	out := &bytes.Buffer{}
+	w := c.Writer(out)
+	_, err := w.Write(in)
+	require.NoError(t, err)
+	require.NoError(t, w.Close())
+
+	return out.Bytes()
+}
+
+func TestConditioner_Builtins(t *testing.T) {
+	in := make([]byte, 10000)
+	_, _ = rand.NewChaCha8([32]byte{1}).Read(in)
+
+	o := New("/dev/null", WithDialer(randomDialer()))
+	for _, name := range []string{"none", "aes-cfb", "chacha20", "sha256-compress", "von-neumann", "iterated-von-neumann"} {
+		t.Run(name, func(t *testing.T) {
+			c, err := o.NewConditioner(context.Background(), name)
+			require.NoError(t, err)
+
+			out := condition(t, c, in)
+			// pseudo-random input, so the ratio is close to nominal
+			assert.InDelta(t, c.Ratio(), float64(len(out))/float64(len(in)), 0.01)
+
+			if name == "none" {
+				assert.Equal(t, in, out)
+			} else {
+				assert.NotEqual(t, in[:len(out)], out)
+			}
+
+			// reading gives the same result as writing, for the
+			// conditioners that aren't keyed
+			if name == "none" || name == "sha256-compress" || name == "von-neumann" || name == "iterated-von-neumann" {
+				read, err := io.ReadAll(c.Reader(bytes.NewReader(in)))
+				require.NoError(t, err)
+				assert.Equal(t, out, read)
+			}
+		})
+	}
+}
+
+func TestConditioner_ChaCha20IsKeyed(t *testing.T) {
+	// the same key material gives the same keystream
+	o := New("/dev/null", WithDialer(randomDialer()))
+	in := bytes.Repeat([]byte{0}, 64)
+
+	c1, err := o.NewConditioner(context.Background(), "chacha20")
+	require.NoError(t, err)
+	c2, err := o.NewConditioner(context.Background(), "chacha20")
+	require.NoError(t, err)
+
+	out, err := io.ReadAll(c2.Reader(bytes.NewReader(in)))
+	require.NoError(t, err)
+	assert.Equal(t, condition(t, c1, in), out)
+
+	// not enough data for a key
+	o = New("/dev/null", WithDialer(DialerFunc(func(context.Context, string) (Transport, error) {
+		return newFakeDev("short"), nil
+	})))
+	_, err = o.NewConditioner(context.Background(), "chacha20")
+	require.ErrorIs(t, err, ErrShortRead)
+	_, err = o.NewConditioner(context.Background(), "aes-cfb")
+	require.ErrorIs(t, err, ErrShortRead)
+}
+
+func TestSHA256Compressor(t *testing.T) {
+	in := bytes.Repeat([]byte("a"), 150)
+	c, err := newSHA256Compressor()
+	require.NoError(t, err)
+	out := condition(t, c, in)
+
+	// two whole blocks, and the remainder is discarded
+	sum := sha256.Sum256(in[:64])
+	assert.Equal(t, append(sum[:], sum[:]...), out)
+
+	// each Writer has its own extractor, so partial blocks don't mix
+	b1, b2 := &bytes.Buffer{}, &bytes.Buffer{}
+	w1, w2 := c.Writer(b1), c.Writer(b2)
+	_, err = w1.Write(in[:40])
+	require.NoError(t, err)
+	_, err = w2.Write(in[:40])
+	require.NoError(t, err)
+	_, err = w1.Write(in[40:64])
+	require.NoError(t, err)
+	assert.Equal(t, sum[:], b1.Bytes())
+	assert.Zero(t, b2.Len())
+	require.NoError(t, w1.Close())
+	require.NoError(t, w2.Close())
+}
+
+func TestVonNeumann(t *testing.T) {
+	testdata := []struct {
+		in, out []byte
+	}{
+		// 01 10 01 10 -> 0101, twice
+		{[]byte{0b01100110, 0b01100110}, []byte{0b01010101}},
+		// 00 and 11 are discarded
+		{[]byte{0b00110011, 0b11001100}, nil},
+		{[]byte{0b10101010, 0b00000000, 0b10101010}, []byte{0xff}},
+		// leftover bits are dropped
+		{[]byte{0b10101010}, nil},
+	}
+	for _, d := range testdata {
+		assert.Equal(t, d.out, condition(t, vonNeumann{}, d.in), "%08b", d.in)
+	}
+
+	// constant input produces nothing
+	out, err := io.ReadAll(vonNeumann{}.Reader(bytes.NewReader(make([]byte, 1000))))
+	require.NoError(t, err)
+	assert.Empty(t, out)
+}
+
+func TestConditionedReader_SmallReads(t *testing.T) {
+	in := make([]byte, 1000)
+	_, _ = rand.NewChaCha8([32]byte{2}).Read(in)
+
+	c, err := newSHA256Compressor()
+	require.NoError(t, err)
+	expected := condition(t, c, in)
+
+	r := c.Reader(bytes.NewReader(in))
+	got := []byte{}
+	buf := make([]byte, 7)
+	for {
+		n, err := r.Read(buf)
+		got = append(got, buf[:n]...)
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+	}
+	assert.Equal(t, expected, got)
+}
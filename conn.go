@@ -0,0 +1,270 @@
+package onerng
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// readBufSize - the size of each read from the device
+const readBufSize = 4096
+
+// conn is an open connection to the device. A single reader goroutine owns
+// the read side of the Transport, and hands whatever it reads to the current
+// subscriber - either as lines of text (for command responses like "Version
+// 3" or the "___" ID), or as raw binary data (for the random stream and the
+// firmware image). Data that arrives while nobody is subscribed is discarded.
+//
+// Callers are responsible for making sure there's at most one subscriber at
+// a time.
+type conn struct {
+	t Transport
+	// pollInterval - how often the reader wakes up to check whether it's been
+	// closed, for Transports that support read deadlines
+	pollInterval time.Duration
+
+	mu  sync.Mutex
+	sub *subscription
+
+	// done is closed when the reader goroutine exits, after which err holds
+	// the error that stopped it
+	done    chan struct{}
+	err     error
+	closing atomic.Bool
+}
+
+// newConn wraps the transport and starts the reader goroutine
+func newConn(t Transport, pollInterval time.Duration) *conn {
+	c := &conn{t: t, pollInterval: pollInterval, done: make(chan struct{})}
+	go c.readLoop()
+
+	return c
+}
+
+// close the transport and wait for the reader goroutine to exit
+func (c *conn) close() error {
+	c.closing.Store(true)
+	err := c.t.Close()
+	<-c.done
+
+	return err
+}
+
+// cmd sends one or more commands to the OneRNG. If the context is cancelled,
+// any remaining commands are skipped.
+func (c *conn) cmd(ctx context.Context, cmds ...string) error {
+	for _, v := range cmds {
+		_, err := c.t.Write([]byte(v))
+		if err != nil {
+			return fmt.Errorf("errored on command %q: %w", v, err)
+		}
+		select {
+		case <-ctx.Done():
+			return nil
+		default:
+		}
+	}
+
+	return nil
+}
+
+func (c *conn) readLoop() {
+	defer close(c.done)
+
+	d, canDeadline := c.t.(ReadDeadliner)
+	for {
+		if canDeadline {
+			// wake up periodically, so we notice being closed even if the
+			// transport's Close doesn't interrupt reads
+			if err := d.SetReadDeadline(time.Now().Add(c.pollInterval)); err != nil {
+				c.err = err
+
+				return
+			}
+		}
+
+		b := make([]byte, readBufSize)
+		n, err := c.t.Read(b)
+		if n > 0 {
+			c.dispatch(b[:n])
+		}
+
+		switch {
+		case c.closing.Load():
+			c.err = os.ErrClosed
+
+			return
+		case err != nil && os.IsTimeout(err):
+			continue
+		case err != nil:
+			c.err = err
+
+			return
+		}
+	}
+}
+
+// dispatch hands the data to the current subscriber, if there is one
+func (c *conn) dispatch(b []byte) {
+	c.mu.Lock()
+	s := c.sub
+	c.mu.Unlock()
+
+	if s == nil {
+		return
+	}
+
+	s.deliver(b)
+}
+
+// subscribe to lines of text read from the device. The lines have any
+// trailing CR/LF removed. Call cancel once done.
+func (c *conn) subscribeLines(ctx context.Context) (lines <-chan string, cancel func()) {
+	s := c.subscribe(ctx, true)
+
+	return s.lines, s.cancel
+}
+
+// subscribe to binary data read from the device. Call Close on the returned
+// reader once done.
+func (c *conn) subscribeData(ctx context.Context) *dataReader {
+	return &dataReader{s: c.subscribe(ctx, false)}
+}
+
+func (c *conn) subscribe(ctx context.Context, lines bool) *subscription {
+	s := &subscription{
+		c:    c,
+		ctx:  ctx,
+		stop: make(chan struct{}),
+	}
+	if lines {
+		s.lines = make(chan string)
+	} else {
+		s.data = make(chan []byte)
+	}
+
+	c.mu.Lock()
+	c.sub = s
+	c.mu.Unlock()
+
+	return s
+}
+
+// subscription - a consumer of the data read from the device. Exactly one of
+// lines or data is set.
+type subscription struct {
+	c     *conn
+	ctx   context.Context
+	lines chan string
+	data  chan []byte
+	stop  chan struct{}
+	once  sync.Once
+
+	// partial holds the unterminated end of the last chunk, in line mode
+	partial []byte
+}
+
+func (s *subscription) cancel() {
+	s.once.Do(func() {
+		s.c.mu.Lock()
+		if s.c.sub == s {
+			s.c.sub = nil
+		}
+		s.c.mu.Unlock()
+		close(s.stop)
+	})
+}
+
+// deliver the chunk to the subscriber, blocking until it's accepted or the
+// subscription is cancelled
+func (s *subscription) deliver(b []byte) {
+	if s.data != nil {
+		select {
+		case s.data <- b:
+		case <-s.stop:
+		case <-s.ctx.Done():
+		}
+
+		return
+	}
+
+	s.partial = append(s.partial, b...)
+	for {
+		i := bytes.IndexByte(s.partial, '\n')
+		if i < 0 {
+			return
+		}
+		line := string(bytes.TrimRight(s.partial[:i], "\r"))
+		s.partial = s.partial[i+1:]
+
+		select {
+		case s.lines <- line:
+		case <-s.stop:
+			return
+		case <-s.ctx.Done():
+			return
+		}
+	}
+}
+
+// dataReader - an io.Reader over a binary data subscription. Read deadlines
+// are supported, so that a silent device can be detected.
+type dataReader struct {
+	s        *subscription
+	buf      []byte
+	deadline time.Time
+}
+
+var _ ReadDeadliner = (*dataReader)(nil)
+
+// SetReadDeadline sets the deadline for future Reads. A zero value means
+// Reads will wait until data arrives or the context is cancelled.
+func (r *dataReader) SetReadDeadline(t time.Time) error {
+	r.deadline = t
+
+	return nil
+}
+
+func (r *dataReader) Read(p []byte) (int, error) {
+	if err := r.s.ctx.Err(); err != nil {
+		return 0, err
+	}
+
+	if len(r.buf) == 0 {
+		var timeout <-chan time.Time
+		if !r.deadline.IsZero() {
+			t := time.NewTimer(time.Until(r.deadline))
+			defer t.Stop()
+			timeout = t.C
+		}
+
+		select {
+		case <-r.s.ctx.Done():
+			return 0, r.s.ctx.Err()
+		case <-timeout:
+			return 0, os.ErrDeadlineExceeded
+		case <-r.s.c.done:
+			return 0, r.s.c.err
+		case <-r.s.stop:
+			// interrupted by Close
+			return 0, os.ErrClosed
+		case r.buf = <-r.s.data:
+		}
+	}
+
+	n := copy(p, r.buf)
+	r.buf = r.buf[n:]
+
+	return n, nil
+}
+
+// Close cancels the subscription
+func (r *dataReader) Close() error {
+	r.s.cancel()
+
+	return nil
+}
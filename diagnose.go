@@ -0,0 +1,321 @@
+package onerng
+
+import (
+	"context"
+	"errors"
+	"io"
+	"math"
+	"math/bits"
+	"strings"
+	"time"
+
+	"github.com/hairyhenderson/go-onerng/sp80090b"
+)
+
+// NoiseModes - all the noise modes, in order
+var NoiseModes = []NoiseMode{
+	Default,
+	DisableWhitener,
+	EnableRF,
+	EnableRF | DisableWhitener,
+	DisableAvalanche,
+	DisableAvalanche | DisableWhitener,
+	DisableAvalanche | EnableRF,
+	DisableAvalanche | EnableRF | DisableWhitener,
+}
+
+// String describes the mode by its enabled noise sources, e.g. "avalanche+rf",
+// with "/raw" appended when the whitener is disabled
+func (m NoiseMode) String() string {
+	sources := []string{}
+	if m&DisableAvalanche == 0 {
+		sources = append(sources, "avalanche")
+	}
+	if m&EnableRF != 0 {
+		sources = append(sources, "rf")
+	}
+	if len(sources) == 0 {
+		sources = append(sources, "silent")
+	}
+
+	s := strings.Join(sources, "+")
+	if m&DisableWhitener != 0 {
+		s += "/raw"
+	}
+
+	return s
+}
+
+// Fault - a problem with a noise mode, found by Diagnose
+type Fault uint8
+
+const (
+	// FaultDead - the mode produced no data
+	FaultDead Fault = 1 << iota
+	// FaultStuck - the output is (nearly) constant, or repeats a value for
+	// implausibly long
+	FaultStuck
+	// FaultInterference - the output is strongly correlated with itself, or
+	// has far less entropy than expected, which suggests that it's dominated
+	// by interference rather than noise. For the silent modes, which should
+	// produce nothing, it means that there's signal where there shouldn't be.
+	FaultInterference
+)
+
+// String returns "ok", or the faults separated by commas
+func (f Fault) String() string {
+	if f == 0 {
+		return "ok"
+	}
+
+	names := []string{}
+	for _, n := range []struct {
+		name string
+		f    Fault
+	}{{"dead", FaultDead}, {"stuck", FaultStuck}, {"interference", FaultInterference}} {
+		if f&n.f != 0 {
+			names = append(names, n.name)
+		}
+	}
+
+	return strings.Join(names, ", ")
+}
+
+// MarshalText - faults are marshalled as their String
+func (f Fault) MarshalText() ([]byte, error) {
+	return []byte(f.String()), nil
+}
+
+// thresholds for the diagnosis
+const (
+	// diagnoseSettle - how long to discard data for after switching modes,
+	// so that none of the previous mode's data (which may still be buffered)
+	// is measured
+	diagnoseSettle = 100 * time.Millisecond
+	// maxCorrelationLag - autocorrelation is measured at lags up to this
+	maxCorrelationLag = 64
+	// maxCorrelation - the largest autocorrelation that isn't considered to
+	// be interference - for a good source it's within a few multiples of
+	// 1/sqrt(n), which is much smaller than this
+	maxCorrelation = 0.1
+	// minStuckEntropy - output with less min-entropy than this (in bits per
+	// byte) is considered stuck
+	minStuckEntropy = 0.5
+	// stuckAlphaExp - a run of identical bytes is considered stuck when it's
+	// less likely than 2^-stuckAlphaExp. This is much stricter than the
+	// repetition count test, which is expected to fail now and then.
+	stuckAlphaExp = 40
+)
+
+// ErrDiagnosisFailed - a diagnosis found faults in at least one noise mode
+var ErrDiagnosisFailed = errors.New("noise mode diagnosis failed")
+
+// Diagnosis - measurements of the output of one noise mode
+type Diagnosis struct {
+	// Mode - the noise mode
+	Mode NoiseMode `json:"mode"`
+	// Expected - the min-entropy the mode is expected to have, in bits per
+	// byte (see WithMinEntropy) - this is 0 for the silent modes
+	Expected float64 `json:"expected"`
+	// Bytes - the number of bytes measured
+	Bytes int `json:"bytes"`
+	// Duration - how long it took to read them
+	Duration time.Duration `json:"duration"`
+	// Throughput - in bytes per second
+	Throughput float64 `json:"throughput"`
+	// Bias - the proportion of 1 bits, minus 0.5
+	Bias float64 `json:"bias"`
+	// Entropy - the Shannon entropy, in bits per byte
+	Entropy float64 `json:"entropy"`
+	// MinEntropy - the Most Common Value min-entropy estimate, in bits per
+	// byte (see sp80090b.MostCommonValue)
+	MinEntropy float64 `json:"min_entropy"`
+	// LongestRepeat - the longest run of identical bytes
+	LongestRepeat int `json:"longest_repeat"`
+	// RepeatRate - the proportion of bytes that are the same as the one
+	// before
+	RepeatRate float64 `json:"repeat_rate"`
+	// Correlation - the autocorrelation with the largest magnitude, at lags
+	// from 1 to 64 bytes
+	Correlation float64 `json:"correlation"`
+	// CorrelationLag - the lag of Correlation, in bytes
+	CorrelationLag int `json:"correlation_lag"`
+	// Faults - any problems found
+	Faults Fault `json:"faults"`
+}
+
+// Diagnose reads samples bytes (or as many as arrive within the timeout) in
+// each noise mode in turn, measures them, and flags any modes that look dead,
+// stuck, or dominated by interference. If onMode is non-nil, it's called with
+// each mode's Diagnosis as soon as it's ready.
+//
+// The health tests aren't run, so that broken modes can be measured.
+func (o *OneRNG) Diagnose(ctx context.Context, samples int, timeout time.Duration, onMode func(*Diagnosis)) ([]*Diagnosis, error) {
+	out := make([]*Diagnosis, 0, len(NoiseModes))
+	for _, mode := range NoiseModes {
+		d, err := o.DiagnoseMode(ctx, mode, samples, timeout)
+		if err != nil {
+			return out, err
+		}
+
+		out = append(out, d)
+		if onMode != nil {
+			onMode(d)
+		}
+	}
+
+	return out, nil
+}
+
+// DiagnoseMode reads samples bytes (or as many as arrive within the timeout)
+// in the given noise mode, and measures them. See Diagnose.
+func (o *OneRNG) DiagnoseMode(ctx context.Context, mode NoiseMode, samples int, timeout time.Duration) (*Diagnosis, error) {
+	data, elapsed, err := o.sample(ctx, mode, samples, timeout)
+	if err != nil {
+		return nil, err
+	}
+
+	d := diagnose(data, o.getMinEntropy(mode))
+	d.Mode = mode
+	d.Duration = elapsed
+	if elapsed > 0 {
+		d.Throughput = float64(len(data)) / elapsed.Seconds()
+	}
+
+	return d, nil
+}
+
+// sample reads up to n bytes in the given mode, stopping early when the
+// timeout expires or the device stops sending data
+func (o *OneRNG) sample(ctx context.Context, mode NoiseMode, n int, timeout time.Duration) ([]byte, time.Duration, error) {
+	sctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	s, err := o.Open(sctx, mode)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer s.Close()
+
+	// running out of time, or data, just means a short sample
+	stopped := func(err error) bool {
+		return ctx.Err() == nil && (errors.Is(err, context.DeadlineExceeded) || errors.Is(err, ErrTimeout))
+	}
+
+	// faulty data is what's being looked for, so it's not health-tested
+	r := readerFunc(s.readUntested)
+
+	buf := make([]byte, 4096)
+	for start := time.Now(); time.Since(start) < diagnoseSettle; {
+		_, err = r.Read(buf)
+		if stopped(err) {
+			return nil, 0, nil
+		}
+		if err != nil {
+			return nil, 0, err
+		}
+	}
+
+	start := time.Now()
+	data := make([]byte, n)
+	read, err := io.ReadFull(r, data)
+	elapsed := time.Since(start)
+	if err != nil && !stopped(err) {
+		return nil, 0, err
+	}
+
+	return data[:read], elapsed, nil
+}
+
+// diagnose measures the data, and flags faults given the expected
+// min-entropy (in bits per byte)
+func diagnose(data []byte, expected float64) *Diagnosis {
+	d := &Diagnosis{Expected: expected, Bytes: len(data)}
+	if len(data) == 0 {
+		if expected > 0 {
+			d.Faults |= FaultDead
+		}
+
+		return d
+	}
+
+	var counts [256]int
+	ones, repeats, run := 0, 0, 0
+	for i, b := range data {
+		counts[b]++
+		ones += bits.OnesCount8(b)
+
+		if i > 0 && b == data[i-1] {
+			repeats++
+			run++
+		} else {
+			run = 1
+		}
+		d.LongestRepeat = max(d.LongestRepeat, run)
+	}
+
+	n := float64(len(data))
+	d.Bias = float64(ones)/(8*n) - 0.5
+	d.RepeatRate = float64(repeats) / n
+	for _, c := range counts {
+		if c > 0 {
+			p := float64(c) / n
+			d.Entropy -= p * math.Log2(p)
+		}
+	}
+	d.MinEntropy = sp80090b.MostCommonValue(data)
+	d.Correlation, d.CorrelationLag = maxAutocorrelation(data, maxCorrelationLag)
+
+	if expected <= 0 {
+		// there should be no signal at all
+		if d.MinEntropy > 1 {
+			d.Faults |= FaultInterference
+		}
+
+		return d
+	}
+
+	stuckRepeat := 1 + int(math.Ceil(stuckAlphaExp/math.Min(expected, 8)))
+	if d.MinEntropy < minStuckEntropy || d.LongestRepeat >= stuckRepeat {
+		d.Faults |= FaultStuck
+	} else if math.Abs(d.Correlation) > maxCorrelation || d.MinEntropy < expected/2 {
+		d.Faults |= FaultInterference
+	}
+
+	return d
+}
+
+// maxAutocorrelation returns the autocorrelation of the byte values with the
+// largest magnitude, at lags from 1 to maxLag, and its lag. It's 0 for
+// constant data.
+func maxAutocorrelation(data []byte, maxLag int) (float64, int) {
+	mean := 0.0
+	for _, b := range data {
+		mean += float64(b)
+	}
+	mean /= float64(len(data))
+
+	x := make([]float64, len(data))
+	variance := 0.0
+	for i, b := range data {
+		x[i] = float64(b) - mean
+		variance += x[i] * x[i]
+	}
+	if variance == 0 {
+		return 0, 0
+	}
+
+	best, bestLag := 0.0, 0
+	for lag := 1; lag <= maxLag && lag < len(x); lag++ {
+		sum := 0.0
+		for i := lag; i < len(x); i++ {
+			sum += x[i] * x[i-lag]
+		}
+
+		if r := sum / variance; math.Abs(r) > math.Abs(best) {
+			best, bestLag = r, lag
+		}
+	}
+
+	return best, bestLag
+}
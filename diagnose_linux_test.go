@@ -0,0 +1,57 @@
+package onerng
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/hairyhenderson/go-onerng/emulator"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDiagnose_Emulated(t *testing.T) {
+	o := startEmulator(t, &emulator.Device{})
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	modes := []NoiseMode{}
+	ds, err := o.Diagnose(ctx, 4096, 500*time.Millisecond, func(d *Diagnosis) {
+		modes = append(modes, d.Mode)
+	})
+	require.NoError(t, err)
+	require.Len(t, ds, 8)
+	assert.Equal(t, NoiseModes, modes)
+
+	for _, d := range ds {
+		assert.Equal(t, Fault(0), d.Faults, "%s: %s", d.Mode, d.Faults)
+
+		// the emulator sends nothing in the silent modes
+		if d.Mode&DisableAvalanche != 0 && d.Mode&EnableRF == 0 {
+			assert.Zero(t, d.Bytes, d.Mode.String())
+		} else {
+			assert.Equal(t, 4096, d.Bytes, d.Mode.String())
+			assert.Positive(t, d.Throughput)
+		}
+	}
+}
+
+func TestDiagnose_EmulatedStuck(t *testing.T) {
+	// alternating bytes, forever
+	src := readerFunc(func(p []byte) (int, error) {
+		for i := range p {
+			p[i] = byte(i % 2)
+		}
+
+		return len(p), nil
+	})
+	o := startEmulator(t, &emulator.Device{Source: src})
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	d, err := o.DiagnoseMode(ctx, Default, 4096, time.Second)
+	require.NoError(t, err)
+	assert.Equal(t, Default, d.Mode)
+	assert.Equal(t, FaultInterference, d.Faults)
+	assert.InDelta(t, -1.0, d.Correlation, 1e-3)
+}
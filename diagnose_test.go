@@ -0,0 +1,87 @@
+package onerng
+
+import (
+	"bytes"
+	"encoding/json"
+	"math/rand/v2"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNoiseMode_String(t *testing.T) {
+	assert.Equal(t, "avalanche", Default.String())
+	assert.Equal(t, "avalanche/raw", DisableWhitener.String())
+	assert.Equal(t, "avalanche+rf", EnableRF.String())
+	assert.Equal(t, "silent", Silent.String())
+	assert.Equal(t, "rf/raw", (DisableAvalanche | EnableRF | DisableWhitener).String())
+
+	assert.Len(t, NoiseModes, 8)
+}
+
+func TestFault_String(t *testing.T) {
+	assert.Equal(t, "ok", Fault(0).String())
+	assert.Equal(t, "stuck", FaultStuck.String())
+	assert.Equal(t, "dead, interference", (FaultDead | FaultInterference).String())
+
+	b, err := json.Marshal(&Diagnosis{Faults: FaultDead})
+	require.NoError(t, err)
+	assert.Contains(t, string(b), `"faults":"dead"`)
+}
+
+func TestDiagnose(t *testing.T) {
+	random := make([]byte, 65536)
+	_, _ = rand.NewChaCha8([32]byte{}).Read(random)
+
+	d := diagnose(random, 7)
+	assert.Equal(t, Fault(0), d.Faults, d.Faults.String())
+	assert.Equal(t, 65536, d.Bytes)
+	assert.InDelta(t, 0, d.Bias, 0.01)
+	assert.Greater(t, d.Entropy, 7.9)
+	assert.Greater(t, d.MinEntropy, 7.0)
+	assert.Less(t, d.LongestRepeat, 4)
+	assert.InDelta(t, 1.0/256, d.RepeatRate, 0.001)
+	assert.Less(t, d.Correlation, 0.05)
+
+	// silent modes are expected to produce nothing
+	d = diagnose(nil, 7)
+	assert.Equal(t, FaultDead, d.Faults)
+	d = diagnose(nil, 0)
+	assert.Equal(t, Fault(0), d.Faults)
+
+	// ...and any entropy there means something's leaking in
+	d = diagnose(random, 0)
+	assert.Equal(t, FaultInterference, d.Faults)
+
+	d = diagnose(bytes.Repeat([]byte{0x42}, 4096), 7)
+	assert.Equal(t, FaultStuck, d.Faults)
+	assert.Equal(t, 4096, d.LongestRepeat)
+	assert.Zero(t, d.Correlation)
+
+	// one long run is enough
+	stuck := bytes.Clone(random)
+	copy(stuck[1000:], bytes.Repeat([]byte{0}, 10))
+	d = diagnose(stuck, 7)
+	assert.Equal(t, FaultStuck, d.Faults)
+
+	// a strong periodic signal (like mains hum) with some noise on top
+	periodic := make([]byte, 65536)
+	for i := range periodic {
+		periodic[i] = byte(i%16*8) + random[i]%64
+	}
+	d = diagnose(periodic, 7)
+	assert.Equal(t, FaultInterference, d.Faults)
+	assert.Equal(t, 16, d.CorrelationLag)
+	assert.Greater(t, d.Correlation, 0.5)
+}
+
+func TestMaxAutocorrelation(t *testing.T) {
+	r, lag := maxAutocorrelation([]byte{0, 255, 0, 255, 0, 255, 0, 255}, 4)
+	assert.Equal(t, 1, lag)
+	assert.InDelta(t, -7.0/8, r, 1e-9)
+
+	r, lag = maxAutocorrelation([]byte{1, 1, 1}, 4)
+	assert.Zero(t, r)
+	assert.Zero(t, lag)
+}
@@ -0,0 +1,91 @@
+package onerng
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+const (
+	// VendorID - the OneRNG's USB vendor ID (OpenMoko, Inc.)
+	VendorID = "1d50"
+	// ProductID - the OneRNG's USB product ID
+	ProductID = "6086"
+
+	// DefaultSysfsRoot - where sysfs is normally mounted
+	DefaultSysfsRoot = "/sys"
+)
+
+// DeviceInfo - describes a OneRNG attached to the system
+type DeviceInfo struct {
+	// Path to the device node (e.g. /dev/ttyACM0)
+	Path string
+	// Serial is the USB serial number, which stays the same when the device
+	// is unplugged and plugged back in
+	Serial string
+	// BusLocation is the USB bus and port the device is attached to, in the
+	// kernel's notation (e.g. 1-1.2)
+	BusLocation string
+}
+
+// Discover finds all OneRNGs currently attached to the system, by looking for
+// ttys belonging to USB devices with the OneRNG's vendor and product IDs.
+//
+// This relies on sysfs, so only works on Linux. On other systems no devices
+// will be found.
+func Discover() ([]DeviceInfo, error) {
+	return DiscoverIn(DefaultSysfsRoot)
+}
+
+// DiscoverIn is like Discover, but uses the sysfs tree rooted at the given
+// directory.
+func DiscoverIn(sysfsRoot string) ([]DeviceInfo, error) {
+	ttyDir := filepath.Join(sysfsRoot, "class", "tty")
+	entries, err := os.ReadDir(ttyDir)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	devs := []DeviceInfo{}
+	for _, e := range entries {
+		// the tty's "device" is the USB interface, and its parent is the USB
+		// device itself - resolve the links first, since filepath.Join would
+		// otherwise lexically remove the ".."
+		iface, err := filepath.EvalSymlinks(filepath.Join(ttyDir, e.Name(), "device"))
+		if err != nil {
+			// not all ttys have devices (i.e. virtual consoles)
+			continue
+		}
+		usbDev := filepath.Dir(iface)
+
+		if readAttr(usbDev, "idVendor") != VendorID || readAttr(usbDev, "idProduct") != ProductID {
+			continue
+		}
+
+		devs = append(devs, DeviceInfo{
+			Path:        filepath.Join("/dev", e.Name()),
+			Serial:      readAttr(usbDev, "serial"),
+			BusLocation: filepath.Base(usbDev),
+		})
+	}
+
+	sort.Slice(devs, func(i, j int) bool { return devs[i].Path < devs[j].Path })
+
+	return devs, nil
+}
+
+// readAttr reads a sysfs attribute, returning an empty string if it can't be
+// read
+func readAttr(dir, name string) string {
+	b, err := os.ReadFile(filepath.Join(dir, name))
+	if err != nil {
+		return ""
+	}
+
+	return string(bytes.TrimSpace(b))
+}
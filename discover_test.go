@@ -0,0 +1,55 @@
+package onerng
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeUSBTTY creates a sysfs-like tree for a USB CDC-ACM device, with the
+// same layout of symlinks as the real thing
+func fakeUSBTTY(t *testing.T, root, tty, bus, vid, pid, serial string) {
+	t.Helper()
+
+	usbDev := filepath.Join(root, "devices", "pci0000:00", "0000:00:14.0", "usb1", bus)
+	iface := filepath.Join(usbDev, bus+":1.0")
+	ttyDir := filepath.Join(iface, "tty", tty)
+	require.NoError(t, os.MkdirAll(ttyDir, 0o755))
+
+	for k, v := range map[string]string{"idVendor": vid, "idProduct": pid, "serial": serial} {
+		require.NoError(t, os.WriteFile(filepath.Join(usbDev, k), []byte(v+"\n"), 0o644))
+	}
+
+	require.NoError(t, os.Symlink(filepath.Join("..", "..", "..", bus+":1.0"), filepath.Join(ttyDir, "device")))
+
+	classDir := filepath.Join(root, "class", "tty")
+	require.NoError(t, os.MkdirAll(classDir, 0o755))
+	rel, err := filepath.Rel(classDir, ttyDir)
+	require.NoError(t, err)
+	require.NoError(t, os.Symlink(rel, filepath.Join(classDir, tty)))
+}
+
+func TestDiscoverIn(t *testing.T) {
+	root := t.TempDir()
+
+	devs, err := DiscoverIn(root)
+	require.NoError(t, err)
+	assert.Empty(t, devs)
+
+	fakeUSBTTY(t, root, "ttyACM1", "1-1.2", "1d50", "6086", "00000001")
+	fakeUSBTTY(t, root, "ttyACM0", "1-1.1", "1546", "01a7", "modem")
+	fakeUSBTTY(t, root, "ttyACM2", "2-3", "1d50", "6086", "00000002")
+
+	// a virtual console, with no device
+	require.NoError(t, os.MkdirAll(filepath.Join(root, "class", "tty", "tty0"), 0o755))
+
+	devs, err = DiscoverIn(root)
+	require.NoError(t, err)
+	assert.Equal(t, []DeviceInfo{
+		{Path: "/dev/ttyACM1", Serial: "00000001", BusLocation: "1-1.2"},
+		{Path: "/dev/ttyACM2", Serial: "00000002", BusLocation: "2-3"},
+	}, devs)
+}
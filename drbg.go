@@ -0,0 +1,150 @@
+package onerng
+
+import (
+	"context"
+	"fmt"
+	"hash"
+	"io"
+	"math"
+	"sync"
+
+	"github.com/hairyhenderson/go-onerng/drbg"
+)
+
+// CTRDRBG instantiates an SP 800-90A CTR_DRBG (AES-256, with the derivation
+// function - see package drbg) with entropy input and a nonce read from the
+// device in the given mode, and the personalization string (which may be
+// nil). The returned Reader reseeds from the device whenever it needs to -
+// set its ReseedBytes, ReseedInterval and PredictionResistance fields to
+// reseed more often.
+//
+// Enough data is read to hold the DRBG's security strength in min-entropy,
+// going by the mode's claimed min-entropy (see WithMinEntropy). The device
+// must have passed its start-up tests (see Init). The seeds all come from one
+// health-tested Session, which stays open until the Reader is closed, so
+// other operations wait until then.
+func (o *OneRNG) CTRDRBG(ctx context.Context, mode NoiseMode, personalization []byte) (*drbg.Reader, error) {
+	return o.newDRBG(ctx, mode, 256, func(entropy, nonce []byte) (drbg.DRBG, error) {
+		return drbg.NewCTR(entropy, nonce, personalization)
+	})
+}
+
+// HMACDRBG instantiates an SP 800-90A HMAC_DRBG using the given hash function
+// (such as sha256.New or sha512.New), seeded from the device like CTRDRBG
+func (o *OneRNG) HMACDRBG(ctx context.Context, mode NoiseMode, h func() hash.Hash, personalization []byte) (*drbg.Reader, error) {
+	return o.newDRBG(ctx, mode, 256, func(entropy, nonce []byte) (drbg.DRBG, error) {
+		return drbg.NewHMAC(h, entropy, nonce, personalization)
+	})
+}
+
+// HashDRBG instantiates an SP 800-90A Hash_DRBG using the given hash function
+// (such as sha256.New or sha512.New), seeded from the device like CTRDRBG
+func (o *OneRNG) HashDRBG(ctx context.Context, mode NoiseMode, h func() hash.Hash, personalization []byte) (*drbg.Reader, error) {
+	return o.newDRBG(ctx, mode, 256, func(entropy, nonce []byte) (drbg.DRBG, error) {
+		return drbg.NewHash(h, entropy, nonce, personalization)
+	})
+}
+
+// newDRBG instantiates a DRBG of the given security strength (in bits) with
+// entropy input and a nonce (of half the strength) from the device
+func (o *OneRNG) newDRBG(ctx context.Context, mode NoiseMode, strength int,
+	instantiate func(entropy, nonce []byte) (drbg.DRBG, error),
+) (*drbg.Reader, error) {
+	h := o.getMinEntropy(mode)
+	if h <= 0 {
+		return nil, fmt.Errorf("can't seed a DRBG in noise mode %d (%s), which has no entropy", mode, mode)
+	}
+
+	entropy := o.entropySource(ctx, mode)
+	seed := make([]byte, bytesFor(strength, h)+bytesFor(strength/2, h))
+	_, err := io.ReadFull(entropy, seed)
+	if err != nil {
+		_ = entropy.Close()
+
+		return nil, fmt.Errorf("failed to read entropy input: %w", err)
+	}
+
+	split := bytesFor(strength, h)
+	d, err := instantiate(seed[:split], seed[split:])
+	clear(seed)
+	if err != nil {
+		_ = entropy.Close()
+
+		return nil, err
+	}
+
+	return &drbg.Reader{DRBG: d, Entropy: entropy, EntropyBytes: split}, nil
+}
+
+// bytesFor returns the number of bytes holding n bits of min-entropy, with h
+// bits per byte - and at least n bits
+func bytesFor(n int, h float64) int {
+	return int(math.Ceil(float64(n) / math.Min(h, 8)))
+}
+
+// entropySource returns a source of seed material from the device in the
+// given mode. It reads from one Session, opened on the first read and kept
+// open until it's closed, so the health tests see all of the data it's drawn
+// from, not just each seed. Reads fail until the device has passed its
+// start-up tests (see Init).
+func (o *OneRNG) entropySource(ctx context.Context, mode NoiseMode) *entropySource {
+	return &entropySource{o: o, ctx: ctx, mode: mode}
+}
+
+// entropySource - see OneRNG.entropySource
+type entropySource struct {
+	o   *OneRNG
+	ctx context.Context
+	s   *Session
+	mu  sync.Mutex
+
+	mode   NoiseMode
+	closed bool
+}
+
+func (e *entropySource) Read(p []byte) (int, error) {
+	if !e.o.Ready() {
+		return 0, fmt.Errorf("%w: can't read seed material before the start-up tests pass", ErrNotReady)
+	}
+
+	s, err := e.session()
+	if err != nil {
+		return 0, err
+	}
+
+	return s.Read(p)
+}
+
+// session returns the session, opening it if needed
+func (e *entropySource) session() (*Session, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if e.closed {
+		return nil, ErrSessionClosed
+	}
+
+	if e.s == nil {
+		s, err := e.o.Open(e.ctx, e.mode)
+		if err != nil {
+			return nil, err
+		}
+		e.s = s
+	}
+
+	return e.s, nil
+}
+
+// Close closes the session, if it was opened. It's safe to call while a read
+// is waiting, which it interrupts.
+func (e *entropySource) Close() error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.closed = true
+	if e.s == nil {
+		return nil
+	}
+
+	return e.s.Close()
+}
@@ -0,0 +1,198 @@
+package drbg
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"encoding/binary"
+	"fmt"
+)
+
+// CTR_DRBG parameters for AES-256 (SP 800-90A table 3)
+const (
+	ctrKeyLen   = 32
+	ctrBlockLen = aes.BlockSize
+	ctrSeedLen  = ctrKeyLen + ctrBlockLen
+	// ctrReseedInterval - the maximum number of requests between reseeds
+	ctrReseedInterval = 1 << 48
+	// ctrMaxRequest - the maximum number of bytes per request (2^19 bits)
+	ctrMaxRequest = 1 << 16
+	// ctrStrength - the security strength, in bits
+	ctrStrength = 256
+)
+
+// CTR - a CTR_DRBG (SP 800-90A 10.2), with AES-256 and the derivation
+// function. It is not safe for concurrent use.
+type CTR struct {
+	block   cipher.Block
+	v       [ctrBlockLen]byte
+	counter uint64
+}
+
+var _ DRBG = (*CTR)(nil)
+
+// NewCTR instantiates a CTR_DRBG with the entropy input, nonce, and
+// personalization string (which may be nil). The entropy input must hold at
+// least 256 bits of min-entropy, and the nonce at least 128 bits (or be
+// otherwise unique).
+func NewCTR(entropy, nonce, personalization []byte) (*CTR, error) {
+	if len(entropy) < ctrStrength/8 {
+		return nil, fmt.Errorf("%w: %d bytes, need at least %d", ErrEntropyTooShort, len(entropy), ctrStrength/8)
+	}
+	if len(nonce) < ctrStrength/16 {
+		return nil, fmt.Errorf("%w: %d byte nonce, need at least %d", ErrEntropyTooShort, len(nonce), ctrStrength/16)
+	}
+
+	d := &CTR{}
+	d.setKey(make([]byte, ctrKeyLen))
+	d.update(blockCipherDF(concat(entropy, nonce, personalization)))
+	d.counter = 1
+
+	return d, nil
+}
+
+// Reseed mixes fresh entropy input (at least 256 bits of min-entropy) and the
+// additional input (which may be nil) into the state
+func (d *CTR) Reseed(entropy, additional []byte) error {
+	if len(entropy) < ctrStrength/8 {
+		return fmt.Errorf("%w: %d bytes, need at least %d", ErrEntropyTooShort, len(entropy), ctrStrength/8)
+	}
+
+	d.update(blockCipherDF(concat(entropy, additional)))
+	d.counter = 1
+
+	return nil
+}
+
+// Generate fills out with output, mixing in the additional input (which may
+// be nil). It returns ErrReseedRequired once the reseed interval is reached.
+func (d *CTR) Generate(out, additional []byte) error {
+	if len(out) > ctrMaxRequest {
+		return fmt.Errorf("%w: %d bytes, at most %d allowed", ErrRequestTooLarge, len(out), ctrMaxRequest)
+	}
+	if d.counter > ctrReseedInterval {
+		return ErrReseedRequired
+	}
+
+	var provided []byte
+	if len(additional) > 0 {
+		provided = blockCipherDF(additional)
+		d.update(provided)
+	}
+
+	var block [ctrBlockLen]byte
+	for i := 0; i < len(out); i += ctrBlockLen {
+		increment(d.v[:])
+		d.block.Encrypt(block[:], d.v[:])
+		copy(out[i:], block[:])
+	}
+
+	d.update(provided)
+	d.counter++
+
+	return nil
+}
+
+// SecurityStrength - 256 bits
+func (d *CTR) SecurityStrength() int {
+	return ctrStrength
+}
+
+// MaxRequest - 64KiB
+func (d *CTR) MaxRequest() int {
+	return ctrMaxRequest
+}
+
+// update - CTR_DRBG_Update (10.2.1.2). A nil provided is treated as all zeros.
+func (d *CTR) update(provided []byte) {
+	temp := make([]byte, ctrSeedLen)
+	for i := 0; i < ctrSeedLen; i += ctrBlockLen {
+		increment(d.v[:])
+		d.block.Encrypt(temp[i:], d.v[:])
+	}
+	for i := range provided {
+		temp[i] ^= provided[i]
+	}
+
+	d.setKey(temp[:ctrKeyLen])
+	copy(d.v[:], temp[ctrKeyLen:])
+}
+
+func (d *CTR) setKey(key []byte) {
+	// the key is always 32 bytes, so this can't fail
+	d.block, _ = aes.NewCipher(key)
+}
+
+// blockCipherDF - Block_Cipher_df (10.3.2), returning seedlen bytes
+func blockCipherDF(input []byte) []byte {
+	// S = L || N || input || 0x80, padded with zeros to a multiple of the
+	// block length
+	s := make([]byte, 8, 8+len(input)+ctrBlockLen)
+	binary.BigEndian.PutUint32(s, uint32(len(input)))
+	binary.BigEndian.PutUint32(s[4:], ctrSeedLen)
+	s = append(s, input...)
+	s = append(s, 0x80)
+	for len(s)%ctrBlockLen != 0 {
+		s = append(s, 0)
+	}
+
+	key := make([]byte, ctrKeyLen)
+	for i := range key {
+		key[i] = byte(i)
+	}
+	block, _ := aes.NewCipher(key)
+
+	temp := make([]byte, 0, ctrSeedLen)
+	iv := make([]byte, ctrBlockLen)
+	for i := uint32(0); len(temp) < ctrSeedLen; i++ {
+		binary.BigEndian.PutUint32(iv, i)
+		temp = append(temp, bcc(block, iv, s)...)
+	}
+
+	block, _ = aes.NewCipher(temp[:ctrKeyLen])
+	x := temp[ctrKeyLen:ctrSeedLen]
+	out := make([]byte, ctrSeedLen)
+	for i := 0; i < ctrSeedLen; i += ctrBlockLen {
+		block.Encrypt(out[i:], x)
+		x = out[i : i+ctrBlockLen]
+	}
+
+	return out
+}
+
+// bcc - the BCC function (10.3.3) applied to iv || data
+func bcc(block cipher.Block, iv, data []byte) []byte {
+	chain := make([]byte, ctrBlockLen)
+	block.Encrypt(chain, iv)
+	for i := 0; i < len(data); i += ctrBlockLen {
+		for j := range chain {
+			chain[j] ^= data[i+j]
+		}
+		block.Encrypt(chain, chain)
+	}
+
+	return chain
+}
+
+// increment adds 1 to the big-endian counter
+func increment(v []byte) {
+	for i := len(v) - 1; i >= 0; i-- {
+		v[i]++
+		if v[i] != 0 {
+			return
+		}
+	}
+}
+
+func concat(parts ...[]byte) []byte {
+	n := 0
+	for _, p := range parts {
+		n += len(p)
+	}
+
+	out := make([]byte, 0, n)
+	for _, p := range parts {
+		out = append(out, p...)
+	}
+
+	return out
+}
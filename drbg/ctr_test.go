@@ -0,0 +1,123 @@
+package drbg
+
+import (
+	"crypto/aes"
+	"encoding/hex"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newCTR(entropy, nonce, personalization []byte) (DRBG, error) {
+	return NewCTR(entropy, nonce, personalization)
+}
+
+func TestCTR_KnownAnswers(t *testing.T) {
+	vectors := []vector{
+		{
+			// CAVP drbgvectors_no_reseed/CTR_DRBG.rsp, [AES-256 use df]
+			// COUNT = 0
+			name:     "CAVP no reseed",
+			entropy:  "36401940fa8b1fba91a1661f211d78a0b9389a74e5bccfece8d766af1a6d3b14",
+			nonce:    "496f25b0f1301b4f501be30380a137eb",
+			returned: "5862eb38bd558dd978a696e6df164782ddd887e7e9a6c9f3f1fbafb78941b535a64912dfd224c6dc7454e5250b3d97165e16260c2faf1cc7735cb75fb4f07e1d",
+		},
+		{
+			// CAVP drbgvectors_pr_false/CTR_DRBG.rsp, [AES-256 use df]
+			// COUNT = 0
+			name:          "CAVP reseed",
+			entropy:       "2d4c9f46b981c6a0b2b5d8c69391e569ff13851437ebc0fc00d616340252fed5",
+			nonce:         "0bf814b411f65ec4866be1abb59d3c32",
+			entropyReseed: "93500fae4fa32b86033b7a7bac9d37e710dcc67ca266bc8607d665937766d207",
+			returned:      "322dd28670e75c0ea638f3cb68d6a9d6e50ddfd052b772a7b1d78263a7b8978b6740c2b65a9550c3a76325866fa97e16d74006bc96f26249b9f0a90d076f08e5",
+		},
+		// these have every input set, and were cross-checked against
+		// OpenSSL 3's CTR-DRBG
+		{
+			name:             "reseed, with personalization and additional input",
+			entropy:          "5c88e7a226e11ad1204cb8d30cd5d6ff6cba69bc32da73134928e17c90c54086",
+			nonce:            "820d5d8baf762ec66dcd56fed15c78bf",
+			personalization:  "169b5b823c62b64ca7e5f8456a13c8d5d06f4ece522a58bc2b8a784dcf3609b0",
+			entropyReseed:    "3291bc266108f011ff111da05fd72d27cc3313135a51b96a3db485f290a3bbb4",
+			additionalReseed: "bfbdba2f74582ec6142a85c316a3f3bb2810ba058e7c69f6df4dde77d13a2012",
+			additional: [2]string{
+				"e80fb65ac70384bd8bab0358d60b7cbe96de5b2de7c095e0d8695852e9c673af",
+				"2033db067e905124ef78ea8237c71990f6cbbc5bebda4fec57849d81d945c3ea",
+			},
+			returned: "2ed837a4a38cd0c5c63e4cf76bf88fb987236e94d5ca64fe847e3bfb207ff726b499ca843263730dd7a89354b3ad514d38b53453be9225d9557826cdd9c9f0c1",
+		},
+		{
+			name:            "prediction resistance, with personalization and additional input",
+			entropy:         "5c88e7a226e11ad1204cb8d30cd5d6ff6cba69bc32da73134928e17c90c54086",
+			nonce:           "820d5d8baf762ec66dcd56fed15c78bf",
+			personalization: "169b5b823c62b64ca7e5f8456a13c8d5d06f4ece522a58bc2b8a784dcf3609b0",
+			additional: [2]string{
+				"e80fb65ac70384bd8bab0358d60b7cbe96de5b2de7c095e0d8695852e9c673af",
+				"2033db067e905124ef78ea8237c71990f6cbbc5bebda4fec57849d81d945c3ea",
+			},
+			entropyPR: [2]string{
+				"c996ee030afc07d5e9583b72358baec6ace2dc3dbd64f01ecd2bd10f06a598e9",
+				"5144ee6a9eb6566b84f518702d137c4e27ca21e1807005c771f3e4d3b0a2b5f7",
+			},
+			returned: "c0dde371d5fdcf9fd7e84e4345b30ab15009729b54c5466e73550b88015ad55190444c82118cd11f1f0a0b65b1bc06fd610227e66c0e41158c17be8074f47fae",
+		},
+	}
+
+	for _, v := range vectors {
+		t.Run(v.name, func(t *testing.T) {
+			v.run(t, newCTR)
+		})
+	}
+}
+
+func TestNewCTR_ShortInput(t *testing.T) {
+	_, err := NewCTR(make([]byte, 31), make([]byte, 16), nil)
+	require.ErrorIs(t, err, ErrEntropyTooShort)
+
+	_, err = NewCTR(make([]byte, 32), make([]byte, 15), nil)
+	require.ErrorIs(t, err, ErrEntropyTooShort)
+
+	d, err := NewCTR(make([]byte, 32), make([]byte, 16), nil)
+	require.NoError(t, err)
+	require.ErrorIs(t, d.Reseed(make([]byte, 16), nil), ErrEntropyTooShort)
+}
+
+func TestCTR_Limits(t *testing.T) {
+	d, err := NewCTR(make([]byte, 32), make([]byte, 16), nil)
+	require.NoError(t, err)
+	assert.Equal(t, 256, d.SecurityStrength())
+
+	require.ErrorIs(t, d.Generate(make([]byte, d.MaxRequest()+1), nil), ErrRequestTooLarge)
+	require.NoError(t, d.Generate(make([]byte, d.MaxRequest()), nil))
+
+	d.counter = ctrReseedInterval + 1
+	require.ErrorIs(t, d.Generate(make([]byte, 16), nil), ErrReseedRequired)
+	require.NoError(t, d.Reseed(make([]byte, 32), nil))
+	require.NoError(t, d.Generate(make([]byte, 16), nil))
+}
+
+func TestCTR_PartialBlocks(t *testing.T) {
+	// output that isn't a whole number of blocks is a prefix of the output
+	// for a whole number
+	d1, err := NewCTR(make([]byte, 32), make([]byte, 16), nil)
+	require.NoError(t, err)
+	d2, err := NewCTR(make([]byte, 32), make([]byte, 16), nil)
+	require.NoError(t, err)
+
+	a := make([]byte, 2*aes.BlockSize)
+	b := make([]byte, aes.BlockSize+3)
+	require.NoError(t, d1.Generate(a, nil))
+	require.NoError(t, d2.Generate(b, nil))
+	assert.Equal(t, hex.EncodeToString(a[:len(b)]), hex.EncodeToString(b))
+}
+
+func TestIncrement(t *testing.T) {
+	v := []byte{0x00, 0xff, 0xff}
+	increment(v)
+	assert.Equal(t, []byte{0x01, 0x00, 0x00}, v)
+
+	v = []byte{0xff, 0xff}
+	increment(v)
+	assert.Equal(t, []byte{0x00, 0x00}, v)
+}
@@ -0,0 +1,206 @@
+/*
+Package drbg implements deterministic random bit generators from NIST SP
+800-90A ("Recommendation for Random Number Generation Using Deterministic
+Random Bit Generators").
+
+Three are implemented: CTR_DRBG (with AES-256 and the derivation function),
+HMAC_DRBG, and Hash_DRBG (with SHA-256 or SHA-512), all at a security
+strength of 256 bits.
+
+A DRBG is instantiated with entropy input (and a nonce) from an entropy source
+such as the OneRNG, and then generates output from its internal state,
+occasionally reseeding with fresh entropy input:
+
+	d, err := drbg.NewCTR(entropy, nonce, nil)
+	if err != nil {
+		return err
+	}
+	r := &drbg.Reader{DRBG: d, Entropy: source, EntropyBytes: 37}
+	_, err = io.ReadFull(r, out)
+
+The Reader takes care of reseeding when the DRBG requires it, after a number
+of bytes or an interval, and for prediction resistance.
+*/
+package drbg
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"time"
+)
+
+// Errors returned by the DRBGs
+var (
+	// ErrReseedRequired - the DRBG has generated as many times as it's
+	// allowed to (its reseed interval) and must be reseeded
+	ErrReseedRequired = errors.New("DRBG reseed required")
+	// ErrRequestTooLarge - more output was requested from one call to
+	// Generate than the DRBG allows
+	ErrRequestTooLarge = errors.New("DRBG request too large")
+	// ErrEntropyTooShort - the entropy input (or nonce) is shorter than the
+	// DRBG's security strength requires
+	ErrEntropyTooShort = errors.New("DRBG entropy input too short")
+)
+
+// DRBG - a deterministic random bit generator
+type DRBG interface {
+	// Generate fills out with output, mixing in the additional input (which
+	// may be nil). At most MaxRequest bytes can be generated at once.
+	Generate(out, additional []byte) error
+	// Reseed mixes fresh entropy input, and the additional input (which may
+	// be nil), into the state
+	Reseed(entropy, additional []byte) error
+	// SecurityStrength - the security strength, in bits
+	SecurityStrength() int
+	// MaxRequest - the largest number of bytes Generate can return at once
+	MaxRequest() int
+}
+
+// Reader - an io.Reader which generates output from a DRBG, and reseeds it
+// from an entropy source. It is not safe for concurrent use.
+type Reader struct {
+	// DRBG - the instantiated DRBG
+	DRBG DRBG
+	// Entropy - the entropy source, for reseeding
+	Entropy io.Reader
+	// EntropyBytes - the number of bytes to read from Entropy for each
+	// reseed. These must hold at least the DRBG's security strength in
+	// min-entropy. The default is enough for full-entropy input.
+	EntropyBytes int
+	// ReseedBytes - reseed after generating this many bytes (0 to only
+	// reseed when the DRBG requires it)
+	ReseedBytes int64
+	// ReseedInterval - reseed after this much time has passed since the last
+	// reseed (0 to never reseed based on time)
+	ReseedInterval time.Duration
+	// PredictionResistance - reseed before every read, so that output can't
+	// be predicted even if the state was compromised earlier
+	PredictionResistance bool
+
+	// now returns the current time - overridden in tests
+	now       func() time.Time
+	seeded    time.Time
+	generated int64
+	reseeds   int
+}
+
+// Read generates len(p) bytes of output
+func (r *Reader) Read(p []byte) (int, error) {
+	err := r.Generate(p, nil, r.PredictionResistance)
+	if err != nil {
+		return 0, err
+	}
+
+	return len(p), nil
+}
+
+// Generate fills out with output, mixing in the additional input (which may
+// be nil). If predictionResistance is true, the DRBG is reseeded first, as
+// with a prediction resistance request in SP 800-90A (9.3.1).
+func (r *Reader) Generate(out, additional []byte, predictionResistance bool) error {
+	if r.seeded.IsZero() {
+		// the DRBG was just instantiated
+		r.seeded = r.timeNow()
+	}
+
+	for len(out) > 0 {
+		n := min(len(out), r.DRBG.MaxRequest())
+		if r.ReseedBytes > 0 {
+			n = int(min(int64(n), max(r.ReseedBytes-r.generated, 1)))
+		}
+
+		if predictionResistance || r.reseedDue() {
+			// as in SP 800-90A, the additional input is used for the
+			// reseed, and not again for generation
+			err := r.Reseed(additional)
+			if err != nil {
+				return err
+			}
+			additional = nil
+			predictionResistance = false
+		}
+
+		err := r.DRBG.Generate(out[:n], additional)
+		if errors.Is(err, ErrReseedRequired) {
+			err = r.Reseed(additional)
+			if err != nil {
+				return err
+			}
+			additional = nil
+			err = r.DRBG.Generate(out[:n], nil)
+		}
+		if err != nil {
+			return err
+		}
+
+		r.generated += int64(n)
+		out = out[n:]
+	}
+
+	return nil
+}
+
+// Reseed reads fresh entropy input from r.Entropy, and reseeds the DRBG with
+// it and the additional input (which may be nil)
+func (r *Reader) Reseed(additional []byte) error {
+	if r.Entropy == nil {
+		return fmt.Errorf("%w: no entropy source", ErrReseedRequired)
+	}
+
+	entropy := make([]byte, r.entropyBytes())
+	_, err := io.ReadFull(r.Entropy, entropy)
+	if err != nil {
+		return fmt.Errorf("failed to read entropy input: %w", err)
+	}
+
+	err = r.DRBG.Reseed(entropy, additional)
+	if err != nil {
+		return err
+	}
+
+	r.seeded = r.timeNow()
+	r.generated = 0
+	r.reseeds++
+
+	return nil
+}
+
+// Close closes the entropy source, if it's an io.Closer. The Reader can't be
+// reseeded after that.
+func (r *Reader) Close() error {
+	if c, ok := r.Entropy.(io.Closer); ok {
+		return c.Close()
+	}
+
+	return nil
+}
+
+// Reseeds returns the number of times the DRBG has been reseeded
+func (r *Reader) Reseeds() int {
+	return r.reseeds
+}
+
+func (r *Reader) reseedDue() bool {
+	if r.ReseedBytes > 0 && r.generated >= r.ReseedBytes {
+		return true
+	}
+
+	return r.ReseedInterval > 0 && r.timeNow().Sub(r.seeded) >= r.ReseedInterval
+}
+
+func (r *Reader) entropyBytes() int {
+	if r.EntropyBytes <= 0 {
+		return r.DRBG.SecurityStrength() / 8
+	}
+
+	return r.EntropyBytes
+}
+
+func (r *Reader) timeNow() time.Time {
+	if r.now == nil {
+		return time.Now()
+	}
+
+	return r.now()
+}
@@ -0,0 +1,198 @@
+package drbg
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// vector - a known-answer test, in the same form as the CAVP DRBG vectors:
+// the DRBG is instantiated, optionally reseeded, then generates twice (with
+// prediction resistance if entropyPR is set), and the second output is
+// compared
+type vector struct {
+	name             string
+	entropy          string
+	nonce            string
+	personalization  string
+	entropyReseed    string
+	additionalReseed string
+	additional       [2]string
+	entropyPR        [2]string
+	returned         string
+}
+
+func (v vector) run(t *testing.T, newDRBG func(entropy, nonce, personalization []byte) (DRBG, error)) {
+	t.Helper()
+
+	d, err := newDRBG(unhex(t, v.entropy), unhex(t, v.nonce), unhex(t, v.personalization))
+	require.NoError(t, err)
+
+	if v.entropyReseed != "" {
+		require.NoError(t, d.Reseed(unhex(t, v.entropyReseed), unhex(t, v.additionalReseed)))
+	}
+
+	out := make([]byte, len(v.returned)/2)
+	for i, add := range v.additional {
+		if v.entropyPR[i] != "" {
+			require.NoError(t, d.Reseed(unhex(t, v.entropyPR[i]), unhex(t, add)))
+			add = ""
+		}
+		require.NoError(t, d.Generate(out, unhex(t, add)))
+	}
+
+	assert.Equal(t, v.returned, hex.EncodeToString(out))
+}
+
+func unhex(t *testing.T, s string) []byte {
+	t.Helper()
+
+	if s == "" {
+		return nil
+	}
+
+	b, err := hex.DecodeString(s)
+	require.NoError(t, err)
+
+	return b
+}
+
+// countingReader returns zeros, and counts how much was read
+type countingReader struct {
+	n int
+}
+
+func (r *countingReader) Read(p []byte) (int, error) {
+	clear(p)
+	r.n += len(p)
+
+	return len(p), nil
+}
+
+func newTestReader(t *testing.T) (*Reader, *countingReader) {
+	t.Helper()
+
+	d, err := NewCTR(make([]byte, 32), make([]byte, 16), nil)
+	require.NoError(t, err)
+
+	entropy := &countingReader{}
+
+	return &Reader{DRBG: d, Entropy: entropy}, entropy
+}
+
+func TestReader_NoReseed(t *testing.T) {
+	r, entropy := newTestReader(t)
+
+	// requests larger than the DRBG's maximum are split up
+	out := make([]byte, 3*r.DRBG.MaxRequest()+5)
+	n, err := r.Read(out)
+	require.NoError(t, err)
+	assert.Equal(t, len(out), n)
+	assert.Zero(t, r.Reseeds())
+	assert.Zero(t, entropy.n)
+}
+
+func TestReader_ReseedBytes(t *testing.T) {
+	r, entropy := newTestReader(t)
+	r.ReseedBytes = 1000
+	r.EntropyBytes = 40
+
+	_, err := r.Read(make([]byte, 999))
+	require.NoError(t, err)
+	assert.Zero(t, r.Reseeds())
+
+	_, err = r.Read(make([]byte, 2500))
+	require.NoError(t, err)
+	// at 1000, 2000 and 3000 bytes
+	assert.Equal(t, 3, r.Reseeds())
+	assert.Equal(t, 120, entropy.n)
+}
+
+func TestReader_ReseedInterval(t *testing.T) {
+	r, entropy := newTestReader(t)
+	now := time.Unix(0, 0)
+	r.now = func() time.Time { return now }
+	r.ReseedInterval = time.Minute
+
+	_, err := r.Read(make([]byte, 16))
+	require.NoError(t, err)
+
+	now = now.Add(59 * time.Second)
+	_, err = r.Read(make([]byte, 16))
+	require.NoError(t, err)
+	assert.Zero(t, r.Reseeds())
+
+	now = now.Add(time.Second)
+	_, err = r.Read(make([]byte, 16))
+	require.NoError(t, err)
+	assert.Equal(t, 1, r.Reseeds())
+	// by default, the entropy input is the security strength
+	assert.Equal(t, 32, entropy.n)
+}
+
+func TestReader_PredictionResistance(t *testing.T) {
+	r, _ := newTestReader(t)
+	r.PredictionResistance = true
+
+	for range 3 {
+		_, err := r.Read(make([]byte, 16))
+		require.NoError(t, err)
+	}
+	assert.Equal(t, 3, r.Reseeds())
+
+	// which is the same as reseeding before each request, with the
+	// additional input only used for the reseed
+	d, err := NewCTR(make([]byte, 32), make([]byte, 16), nil)
+	require.NoError(t, err)
+	r2 := &Reader{DRBG: d, Entropy: &countingReader{}}
+
+	additional := []byte("additional")
+	got := make([]byte, 32)
+	require.NoError(t, r2.Generate(got, additional, true))
+
+	d, err = NewCTR(make([]byte, 32), make([]byte, 16), nil)
+	require.NoError(t, err)
+	require.NoError(t, d.Reseed(make([]byte, 32), additional))
+	expected := make([]byte, 32)
+	require.NoError(t, d.Generate(expected, nil))
+
+	assert.Equal(t, expected, got)
+}
+
+func TestReader_ReseedRequired(t *testing.T) {
+	r, entropy := newTestReader(t)
+	r.DRBG.(*CTR).counter = ctrReseedInterval + 1
+
+	_, err := r.Read(make([]byte, 16))
+	require.NoError(t, err)
+	assert.Equal(t, 1, r.Reseeds())
+	assert.Equal(t, 32, entropy.n)
+}
+
+func TestReader_EntropyErrors(t *testing.T) {
+	r, _ := newTestReader(t)
+	r.PredictionResistance = true
+	r.Entropy = nil
+
+	_, err := r.Read(make([]byte, 16))
+	require.ErrorIs(t, err, ErrReseedRequired)
+
+	r.Entropy = bytes.NewReader(make([]byte, 10))
+	_, err = r.Read(make([]byte, 16))
+	require.ErrorIs(t, err, io.ErrUnexpectedEOF)
+
+	broken := errors.New("broken")
+	r.Entropy = readerFunc(func([]byte) (int, error) { return 0, broken })
+	_, err = r.Read(make([]byte, 16))
+	require.ErrorIs(t, err, broken)
+}
+
+type readerFunc func(p []byte) (int, error)
+
+func (f readerFunc) Read(p []byte) (int, error) { return f(p) }
@@ -0,0 +1,143 @@
+package drbg
+
+import (
+	"encoding/binary"
+	"fmt"
+	"hash"
+)
+
+// Hash - a Hash_DRBG (SP 800-90A 10.1.1). It is not safe for concurrent use.
+type Hash struct {
+	h       func() hash.Hash
+	v       []byte
+	c       []byte
+	counter uint64
+}
+
+var _ DRBG = (*Hash)(nil)
+
+// NewHash instantiates a Hash_DRBG using the given hash function (such as
+// sha256.New or sha512.New, which must have at least 256 bits of output),
+// with the entropy input, nonce, and personalization string (which may be
+// nil). The entropy input must hold at least 256 bits of min-entropy, and the
+// nonce at least 128 bits (or be otherwise unique).
+func NewHash(h func() hash.Hash, entropy, nonce, personalization []byte) (*Hash, error) {
+	err := checkSeed(h, entropy, nonce)
+	if err != nil {
+		return nil, err
+	}
+
+	d := &Hash{h: h}
+	d.seed(d.df(entropy, nonce, personalization))
+	d.counter = 1
+
+	return d, nil
+}
+
+// Reseed mixes fresh entropy input (at least 256 bits of min-entropy) and the
+// additional input (which may be nil) into the state
+func (d *Hash) Reseed(entropy, additional []byte) error {
+	if len(entropy) < hashStrength/8 {
+		return fmt.Errorf("%w: %d bytes, need at least %d", ErrEntropyTooShort, len(entropy), hashStrength/8)
+	}
+
+	d.seed(d.df([]byte{0x01}, d.v, entropy, additional))
+	d.counter = 1
+
+	return nil
+}
+
+// Generate fills out with output, mixing in the additional input (which may
+// be nil). It returns ErrReseedRequired once the reseed interval is reached.
+func (d *Hash) Generate(out, additional []byte) error {
+	if len(out) > hashMaxRequest {
+		return fmt.Errorf("%w: %d bytes, at most %d allowed", ErrRequestTooLarge, len(out), hashMaxRequest)
+	}
+	if d.counter > hashReseedInterval {
+		return ErrReseedRequired
+	}
+
+	if len(additional) > 0 {
+		addMod(d.v, d.hash([]byte{0x02}, d.v, additional))
+	}
+
+	// Hashgen
+	data := append([]byte{}, d.v...)
+	for i := 0; i < len(out); {
+		i += copy(out[i:], d.hash(data))
+		increment(data)
+	}
+
+	var counter [8]byte
+	binary.BigEndian.PutUint64(counter[:], d.counter)
+	addMod(d.v, d.hash([]byte{0x03}, d.v))
+	addMod(d.v, d.c)
+	addMod(d.v, counter[:])
+	d.counter++
+
+	return nil
+}
+
+// SecurityStrength - 256 bits
+func (d *Hash) SecurityStrength() int {
+	return hashStrength
+}
+
+// MaxRequest - 64KiB
+func (d *Hash) MaxRequest() int {
+	return hashMaxRequest
+}
+
+// seed sets V to the seed, and derives C from it
+func (d *Hash) seed(seed []byte) {
+	d.v = seed
+	d.c = d.df([]byte{0x00}, d.v)
+}
+
+// seedLen - the length of V and C, in bytes: 440 bits for hashes with up to
+// 256 bits of output, and 888 bits for larger ones (SP 800-90A table 2)
+func (d *Hash) seedLen() int {
+	if d.h().Size() <= 32 {
+		return 440 / 8
+	}
+
+	return 888 / 8
+}
+
+// df - Hash_df (10.3.1), returning seedlen bytes derived from the
+// concatenated input
+func (d *Hash) df(input ...[]byte) []byte {
+	n := d.seedLen()
+	out := make([]byte, 0, n+d.h().Size())
+
+	var prefix [5]byte
+	binary.BigEndian.PutUint32(prefix[1:], uint32(n*8))
+	for counter := byte(1); len(out) < n; counter++ {
+		prefix[0] = counter
+		out = append(out, d.hash(append([][]byte{prefix[:]}, input...)...)...)
+	}
+
+	return out[:n]
+}
+
+func (d *Hash) hash(data ...[]byte) []byte {
+	h := d.h()
+	for _, p := range data {
+		h.Write(p)
+	}
+
+	return h.Sum(nil)
+}
+
+// addMod adds x to v (both big-endian), modulo 2^(8*len(v))
+func addMod(v, x []byte) {
+	carry := 0
+	for i, j := len(v)-1, len(x)-1; i >= 0; i, j = i-1, j-1 {
+		sum := int(v[i]) + carry
+		if j >= 0 {
+			sum += int(x[j])
+		}
+		v[i] = byte(sum)
+		carry = sum >> 8
+	}
+}
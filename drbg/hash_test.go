@@ -0,0 +1,209 @@
+package drbg
+
+import (
+	"crypto/sha256"
+	"crypto/sha512"
+	"hash"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHash_KnownAnswers(t *testing.T) {
+	// apart from the CAVP vectors, these have every input set, and were
+	// cross-checked against OpenSSL 3's Hash-DRBG. The SHA-512 vectors cover
+	// the 888-bit seedlen.
+	testdata := []struct {
+		h       func() hash.Hash
+		name    string
+		vectors []vector
+	}{
+		{
+			name: "SHA-256",
+			h:    sha256.New,
+			vectors: []vector{
+				{
+					// CAVP drbgvectors_no_reseed/Hash_DRBG.rsp, [SHA-256] COUNT = 0
+					name:     "CAVP, no reseed",
+					entropy:  "a65ad0f345db4e0effe875c3a2e71f42c7129d620ff5c119a9ef55f05185e0fb",
+					nonce:    "8581f9317517276e06e9607ddbcbcc2e",
+					returned: "d3e160c35b99f340b2628264d1751060e0045da383ff57a57d73a673d2b8d80daaf6a6c35a91bb4579d73fd0c8fed111b0391306828adfed528f018121b3febdc343e797b87dbb63db1333ded9d1ece177cfa6b71fe8ab1da46624ed6415e51ccde2c7ca86e283990eeaeb91120415528b2295910281b02dd431f4c9f70427df",
+				},
+				{
+					// CAVP drbgvectors_pr_false/Hash_DRBG.rsp, [SHA-256] COUNT = 0
+					name:             "CAVP, reseed",
+					entropy:          "6c623aea73bc8a59e28c6cd9c7c7ec8ca2e75190bd5dcae5978cf0c199c23f4f",
+					nonce:            "e55db067a0ed537e66886b7cda02f772",
+					personalization:  "1e59d798810083d1ff848e90b25c9927e3dfb55a0888b0339566a9f9ca7542dc",
+					entropyReseed:    "9ab40164744c7d00c78b4196f6f917ec33d70030a0812cd4606c5a25387568a9",
+					additionalReseed: "4e8bead7cbba7a7bc9ae1e1617222c4139661347599950e7225d1e2faa5d57f5",
+					additional: [2]string{
+						"dcb22a5d9f149858636f3ede2253e419816fb7b1103194451ed6a573a8fe6271",
+						"8f9d5c78cdabc32e71ac3b3c49239caddf96053250f4fd92056efbd0be487d36",
+					},
+					returned: "6e98a3b1f686f6ffa79355c9d8a5ab7f93312159d52659a2298315f10007c71adabc0b5ccb4164c0949fbdb221b43acdb62bed3099596f2d7bd5d0048173dd2360a543b234ab61a441ddb9299af84ca45c6e618fd521366dbf509d4ec06174da924361d642b107e5564ac1b32340dd2f3158bf4c00bcb4dcf12c6d67af4b74ee",
+				},
+				{
+					// CAVP drbgvectors_pr_true/Hash_DRBG.rsp, [SHA-256] COUNT = 0
+					name:            "CAVP, prediction resistance",
+					entropy:         "29e1a42709b7e84dbe50788fbad8cb609c127eec3262636a513fd9059fb8bae4",
+					nonce:           "f3a521f28dffbd97574c405b69b636ad",
+					personalization: "c99a1147d8db401f4fcf763867296758e26404a30a4a9fa496a717f21f5d749b",
+					additional: [2]string{
+						"17254ea392aead0dc94992d867813497d3fd6dc7669667150afe6c28a2b89946",
+						"157dde59ceb2c662c8665fbe623ec75873fd0c5ccce79a9f5b7098ec8ed77b67",
+					},
+					entropyPR: [2]string{
+						"d5266c01e10d72dd7e8a3bf717cccb8f643ca233314e3e74f106f46b090e5c73",
+						"c7e5f03d26bdf9553338e64ba64ce5b5751b04f9c69992221495f2227b9799d0",
+					},
+					returned: "e304de9ffd885cf917ead78f05939b8cf54709fc2d0c799a98b543486337204477b1060bceae2a22f7ff42b6cb4b4bc0610ae2b67558a7c54b65e45bb9f1a86981b74705b48cdbf7d8decf87868267bd948e9394aa4357f8dbbf30612a0eb5b131884c220e442d36778e8d74091d8a27c070fe690469e07f3aabeef7c629bfab",
+				},
+				{
+					name:             "reseed, with personalization and additional input",
+					entropy:          "d36bd0f87f319fb509c1d755a0f0d34a3fb30030115e87e55a57cd1f754e1b8d",
+					nonce:            "88a602c87d2fe513cb0641d94729b1c6",
+					personalization:  "9efa4567ee3b84b3e57d49ea1e6e1154f21128a81acf5fd6d06be110b9f0b491",
+					entropyReseed:    "4646714b5a7fd4aab6eefa3d3169e09b544f301110ffceee6ceb3ba05a38fee8",
+					additionalReseed: "555643c27599d71ebd3996248c425d25cca77352972d2d5c047e3fb0687826f8",
+					additional: [2]string{
+						"353baa07666ca7421a114999f086ca533dce3600acb65e11b438ee8477606310",
+						"a0247d52744a60207136068d959da284503d2545797e6d470309185731d3f112",
+					},
+					returned: "763090cef53d64ce7688ef2087809bb2d9823c3cc7c80f207e78911a80a791c5e61d5b8606edb0aa2ade7f2fc07453a328811161d7e075ddd9fef438627d2f54642c1ecf1286b1b2b76098ac7ca668fb93f184a2da7b62a6f265555349085680150da898e67d6afebd5510d136cb21b76eec8757a7812ac47e7dcdb43445f9c8",
+				},
+				{
+					name:            "prediction resistance, with personalization and additional input",
+					entropy:         "d36bd0f87f319fb509c1d755a0f0d34a3fb30030115e87e55a57cd1f754e1b8d",
+					nonce:           "88a602c87d2fe513cb0641d94729b1c6",
+					personalization: "9efa4567ee3b84b3e57d49ea1e6e1154f21128a81acf5fd6d06be110b9f0b491",
+					additional: [2]string{
+						"353baa07666ca7421a114999f086ca533dce3600acb65e11b438ee8477606310",
+						"a0247d52744a60207136068d959da284503d2545797e6d470309185731d3f112",
+					},
+					entropyPR: [2]string{
+						"509f418b36ee1118f12c138e0db50874a5f5ca3f370f62d25f4584aa757894f9",
+						"4a08cd313005d96e3a6c04c69c9b47d0c7dcef98596e433110828fdc9865ae30",
+					},
+					returned: "c1e17596080582ba4af8e54abc90703c2559f5ba7fe5f019c0c60f6a986ff148d20807267f840575f0042d79c61bc6432fc0788ca5ecc2eaa659ceaa45df47a6e34b145724707259706ec780bed7c1666ed44fcdad93caffea97551c6923e661204b91976508a5149b8b7aa50ff1733242516304d446994f1c455b6943d304d7",
+				},
+			},
+		},
+		{
+			name: "SHA-512",
+			h:    sha512.New,
+			vectors: []vector{
+				{
+					// CAVP drbgvectors_pr_false/Hash_DRBG.rsp, [SHA-512] COUNT = 0
+					name:             "CAVP, reseed",
+					entropy:          "4b23595b0a3640cfabb0ec34df6a613308b0448488a5d9ff99da4278e072eb34",
+					nonce:            "8e696bffd9ca3a71d2e2f05e600c8364",
+					personalization:  "010ba93ea68a3d4a200e5145859e299c5b5349b7645fb5bbcad687aba7d67313",
+					entropyReseed:    "04de4babdbe143bde99aa4452f9aa43b0a164eb927555c0496aa0fc9328a521c",
+					additionalReseed: "2b0c7c3efb36b71b917a44086d168313675b426b17c5ab3d0eb6af753f6040e0",
+					additional: [2]string{
+						"d0b7d1d12ab15d3bba8f4eba07fee0974838962b247be480683b8e3d4a91033a",
+						"66c78ca12e45bdca003b49cb6440b977dd85b167e7c803890ed1a73666eaa869",
+					},
+					returned: "4008cbd8281dc82fd6c368f650ef2609bb771e80c63d478a77fa938248dcbb8b79e54ead0265f6ff1ebfafe4e387c6e27df9f03e4a5225e86a4436e56ebf03b3be2cfbcb49c89c92ec1dfa5ee445dd4f6f64e02a2423a0b18ebd02eec52f5cc21bc3565e796b3ded6552f1b5a574a201c3b11018222806f9618d23d77fd02db879cf87fe24ed7ba11b3b108b559633db1f95c5121b28011aa4dd20399bd4978e1f8b8880c333a47ff1750679bf28d329347b26d347aae90ee562ae8029579cbe0336e066d6b8ba5e0169fec804c30189a4434c1bf8a5b0a249951d3d89554da38ff0751b8b1fef9ae18a0aa2bc477736d199a06f61d400039a4cc03869bb10ca",
+				},
+				{
+					// CAVP drbgvectors_pr_true/Hash_DRBG.rsp, [SHA-512] COUNT = 0
+					name:            "CAVP, prediction resistance",
+					entropy:         "0cfbae94684321e071e66da09e0410128b7a14ff1692014c98fac7f384e8362f",
+					nonce:           "3a217aec700c191b05e4a11803d5f651",
+					personalization: "e638d92f217337982258337ae63c3b07b30fc5668e8c123ee0e530fc0187f251",
+					additional: [2]string{
+						"30580498cac41c4a514dd913131dd0ef932dfc246710ffb09b33c952fe5e7e69",
+						"ce49a8abe2d1b66582eb54c66022fc856324cfd8c9504077b063f3d58e832fa3",
+					},
+					entropyPR: [2]string{
+						"fa65a1561e9699272c147531b29015053ea0e504c1978cc791fd75eaff51c005",
+						"7cb7d7de34f568cd8e5808226db8f414f1da556cf00796dc12ecdd8ad049b1c3",
+					},
+					returned: "174df659adf93390e0543d04a55d1c23ef5faf8b1a49d36b6010a0ebdd2f77b35b5a8cf942236619a4304b5c0eaad098ca977a219f2c327f49b5ee4d2a29f48ec91623ce288930b8fe9092ff8ce4aee8e90f6ae9cbf8514d1636f8bda841b49aa3043605e49581c42f2e54a3b9f1dbe51e78996314df8e3cb43687721d1c660b37f118331dcf8b0acec110489741df45f2cd5c2aa2a989cd3635fb52ec156b855307e30e34092eff182886470ee9ce0df25c54ddcfe1ca1ae713a2063dc16274fb36dd957a3a3fcc80d2a0e908e0e43cd0d55e4241afc3ebf6f36248528f0dcfa780aa417f3dabdb7be6622c0c8c8df5973392c913c777454c393587e050afdf",
+				},
+				{
+					name:             "reseed, with personalization and additional input",
+					entropy:          "35ed466134348320b1cf31b6b2aa8eba37364a19fba27d21a9180aaca2d30a49",
+					nonce:            "f89a5c700fbcc9f031d6f051f6e150bc",
+					personalization:  "557fd70c852e3bd4dd11e795ee64c02944e0e1699a5a922d3c68a8b9aad0f483",
+					entropyReseed:    "4c7298184d617b7be2270df8cafe644b308dff443bad81bb7dff85eff50cd0cf",
+					additionalReseed: "70a64945d910035763ed19c06a787021d0f474b893ed8d3882c3478be6635471",
+					additional: [2]string{
+						"1040e1759231704cbb58440652a541f5f9ae4e288742776d79b728b4ab3cea65",
+						"3e9174a75dbf4537cf7345c869c6f8dd34f815459bc1e2fdc2799dc6ca9c0724",
+					},
+					returned: "053529d8f1997ac1b7911ca10d2d1853eb378cda6ceccbe5c5ecf0793d282ffadd4653d24a96a3fde442abd5b316a66bf79f5a2fbdfbdb67c8b935979de0b31f3ca8748cf6bb4be49efade3d0a47a69eda0ee0f69cb0997392f2fc66cc56dcf2e3cb89dcb6ce7941c603c108ad02d8bd0fe35fd1d8af7e38e01fcd504aee0aa5f01a7e8f73199008a7895c45cf8d485c177bf38fb626c38eb0e47c106f91abce9b50dda5f978522d1d6b7601b548e9a6977da65310d7ba32ed4ea1aae57d68814e35312237957335583ae1d9fc4f52e4e0bb173fb0ea37844c513f49aab8946709f099b26ec13685b06a30fd381193620d7bc4013a979f5cd581ed51099657ef",
+				},
+				{
+					name:            "prediction resistance, with personalization and additional input",
+					entropy:         "35ed466134348320b1cf31b6b2aa8eba37364a19fba27d21a9180aaca2d30a49",
+					nonce:           "f89a5c700fbcc9f031d6f051f6e150bc",
+					personalization: "557fd70c852e3bd4dd11e795ee64c02944e0e1699a5a922d3c68a8b9aad0f483",
+					additional: [2]string{
+						"1040e1759231704cbb58440652a541f5f9ae4e288742776d79b728b4ab3cea65",
+						"3e9174a75dbf4537cf7345c869c6f8dd34f815459bc1e2fdc2799dc6ca9c0724",
+					},
+					entropyPR: [2]string{
+						"979d203f56becfbeebdac128066eeafe06fb5e0c7c4459b8b2ba8bc912ef80d1",
+						"050b50cb951a961b7659050e453b9b8f6e90c7b7a4ddceb2aecc8bcefce5e09c",
+					},
+					returned: "9fa99c124da45fa78d54fa5c81c307d40158e1207b859bb095a1cea86ce2ab180895053cba64d6a183ad16e94f7348ef59ff60e5614f17ed8a95604ce62f339cea35325a2af4053c093f1e5953abf19fa6d9fe0278ecb68021cf49708d5f8f7f36427ec539bbc3a56cff1c63d2e14ec20ad0ce5b243381adeb5a2b666718c42489f3b7ea079849560a99758654cd4c23e0021e1c6f67bd4dbd48627a30fd301cab73080eb7bdc2aa7fde589a39bf2193e902b155495ce45f18f8c4f73b240559737cc3d81f09d1ff1022b867e02131b6fb7ba05bf98d891c7349a6434522d05f16d1229fc6799b5ec45c869314cc71904f8458de983e2198f9315f50dc24465a",
+				},
+			},
+		},
+	}
+
+	for _, d := range testdata {
+		for _, v := range d.vectors {
+			t.Run(d.name+" "+v.name, func(t *testing.T) {
+				v.run(t, func(entropy, nonce, personalization []byte) (DRBG, error) {
+					return NewHash(d.h, entropy, nonce, personalization)
+				})
+			})
+		}
+	}
+}
+
+func TestNewHash_Errors(t *testing.T) {
+	_, err := NewHash(sha256.New, make([]byte, 31), make([]byte, 16), nil)
+	require.ErrorIs(t, err, ErrEntropyTooShort)
+
+	_, err = NewHash(sha512.New, make([]byte, 32), make([]byte, 15), nil)
+	require.ErrorIs(t, err, ErrEntropyTooShort)
+
+	_, err = NewHash(sha256.New224, make([]byte, 32), make([]byte, 16), nil)
+	require.Error(t, err)
+
+	d, err := NewHash(sha256.New, make([]byte, 32), make([]byte, 16), nil)
+	require.NoError(t, err)
+	require.ErrorIs(t, d.Reseed(make([]byte, 16), nil), ErrEntropyTooShort)
+}
+
+func TestHash_Limits(t *testing.T) {
+	d, err := NewHash(sha512.New, make([]byte, 32), make([]byte, 16), nil)
+	require.NoError(t, err)
+	assert.Equal(t, 256, d.SecurityStrength())
+	assert.Len(t, d.v, 111)
+
+	require.ErrorIs(t, d.Generate(make([]byte, d.MaxRequest()+1), nil), ErrRequestTooLarge)
+	require.NoError(t, d.Generate(make([]byte, d.MaxRequest()), nil))
+
+	d.counter = hashReseedInterval + 1
+	require.ErrorIs(t, d.Generate(make([]byte, 16), nil), ErrReseedRequired)
+	require.NoError(t, d.Reseed(make([]byte, 32), nil))
+	require.NoError(t, d.Generate(make([]byte, 16), nil))
+}
+
+func TestAddMod(t *testing.T) {
+	v := []byte{0x00, 0xff, 0xff}
+	addMod(v, []byte{0x01})
+	assert.Equal(t, []byte{0x01, 0x00, 0x00}, v)
+
+	// overflow wraps around, and x may be longer than v
+	v = []byte{0xff, 0xff}
+	addMod(v, []byte{0x01, 0x00, 0x02})
+	assert.Equal(t, []byte{0x00, 0x01}, v)
+}
@@ -0,0 +1,144 @@
+package drbg
+
+import (
+	"crypto/hmac"
+	"fmt"
+	"hash"
+)
+
+// parameters for the hash-based DRBGs (SP 800-90A table 2)
+const (
+	// hashReseedInterval - the maximum number of requests between reseeds
+	hashReseedInterval = 1 << 48
+	// hashMaxRequest - the maximum number of bytes per request (2^19 bits)
+	hashMaxRequest = 1 << 16
+	// hashStrength - the security strength, in bits, with SHA-256 or
+	// stronger
+	hashStrength = 256
+)
+
+// HMAC - an HMAC_DRBG (SP 800-90A 10.1.2). It is not safe for concurrent use.
+type HMAC struct {
+	h       func() hash.Hash
+	k       []byte
+	v       []byte
+	counter uint64
+}
+
+var _ DRBG = (*HMAC)(nil)
+
+// NewHMAC instantiates an HMAC_DRBG using the given hash function (such as
+// sha256.New or sha512.New, which must have at least 256 bits of output),
+// with the entropy input, nonce, and personalization string (which may be
+// nil). The entropy input must hold at least 256 bits of min-entropy, and the
+// nonce at least 128 bits (or be otherwise unique).
+func NewHMAC(h func() hash.Hash, entropy, nonce, personalization []byte) (*HMAC, error) {
+	err := checkSeed(h, entropy, nonce)
+	if err != nil {
+		return nil, err
+	}
+
+	size := h().Size()
+	d := &HMAC{h: h, k: make([]byte, size), v: make([]byte, size)}
+	for i := range d.v {
+		d.v[i] = 0x01
+	}
+	d.update(entropy, nonce, personalization)
+	d.counter = 1
+
+	return d, nil
+}
+
+// Reseed mixes fresh entropy input (at least 256 bits of min-entropy) and the
+// additional input (which may be nil) into the state
+func (d *HMAC) Reseed(entropy, additional []byte) error {
+	if len(entropy) < hashStrength/8 {
+		return fmt.Errorf("%w: %d bytes, need at least %d", ErrEntropyTooShort, len(entropy), hashStrength/8)
+	}
+
+	d.update(entropy, additional)
+	d.counter = 1
+
+	return nil
+}
+
+// Generate fills out with output, mixing in the additional input (which may
+// be nil). It returns ErrReseedRequired once the reseed interval is reached.
+func (d *HMAC) Generate(out, additional []byte) error {
+	if len(out) > hashMaxRequest {
+		return fmt.Errorf("%w: %d bytes, at most %d allowed", ErrRequestTooLarge, len(out), hashMaxRequest)
+	}
+	if d.counter > hashReseedInterval {
+		return ErrReseedRequired
+	}
+
+	if len(additional) > 0 {
+		d.update(additional)
+	}
+
+	for i := 0; i < len(out); i += len(d.v) {
+		d.v = d.mac(d.v)
+		copy(out[i:], d.v)
+	}
+
+	d.update(additional)
+	d.counter++
+
+	return nil
+}
+
+// SecurityStrength - 256 bits
+func (d *HMAC) SecurityStrength() int {
+	return hashStrength
+}
+
+// MaxRequest - 64KiB
+func (d *HMAC) MaxRequest() int {
+	return hashMaxRequest
+}
+
+// update - HMAC_DRBG_Update (10.1.2.2), with the provided data given in parts
+func (d *HMAC) update(provided ...[]byte) {
+	empty := true
+	for _, p := range provided {
+		empty = empty && len(p) == 0
+	}
+
+	for _, b := range []byte{0x00, 0x01} {
+		if b == 0x01 && empty {
+			return
+		}
+
+		m := hmac.New(d.h, d.k)
+		m.Write(d.v)
+		m.Write([]byte{b})
+		for _, p := range provided {
+			m.Write(p)
+		}
+		d.k = m.Sum(nil)
+		d.v = d.mac(d.v)
+	}
+}
+
+func (d *HMAC) mac(data []byte) []byte {
+	m := hmac.New(d.h, d.k)
+	m.Write(data)
+
+	return m.Sum(nil)
+}
+
+// checkSeed checks the hash function's output size, and the lengths of the
+// entropy input and nonce, for the hash-based DRBGs
+func checkSeed(h func() hash.Hash, entropy, nonce []byte) error {
+	if size := h().Size(); size < hashStrength/8 {
+		return fmt.Errorf("hash output too small for a %d-bit DRBG: %d bits", hashStrength, size*8)
+	}
+	if len(entropy) < hashStrength/8 {
+		return fmt.Errorf("%w: %d bytes, need at least %d", ErrEntropyTooShort, len(entropy), hashStrength/8)
+	}
+	if len(nonce) < hashStrength/16 {
+		return fmt.Errorf("%w: %d byte nonce, need at least %d", ErrEntropyTooShort, len(nonce), hashStrength/16)
+	}
+
+	return nil
+}
@@ -0,0 +1,175 @@
+package drbg
+
+import (
+	"crypto/sha256"
+	"crypto/sha512"
+	"hash"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHMAC_KnownAnswers(t *testing.T) {
+	// apart from the CAVP vectors, these have every input set, and were
+	// cross-checked against OpenSSL 3's HMAC-DRBG
+	testdata := []struct {
+		h       func() hash.Hash
+		name    string
+		vectors []vector
+	}{
+		{
+			name: "SHA-256",
+			h:    sha256.New,
+			vectors: []vector{
+				{
+					// CAVP drbgvectors_no_reseed/HMAC_DRBG.rsp, [SHA-256] COUNT = 0
+					name:     "CAVP, no reseed",
+					entropy:  "ca851911349384bffe89de1cbdc46e6831e44d34a4fb935ee285dd14b71a7488",
+					nonce:    "659ba96c601dc69fc902940805ec0ca8",
+					returned: "e528e9abf2dece54d47c7e75e5fe302149f817ea9fb4bee6f4199697d04d5b89d54fbb978a15b5c443c9ec21036d2460b6f73ebad0dc2aba6e624abf07745bc107694bb7547bb0995f70de25d6b29e2d3011bb19d27676c07162c8b5ccde0668961df86803482cb37ed6d5c0bb8d50cf1f50d476aa0458bdaba806f48be9dcb8",
+				},
+				{
+					// CAVP drbgvectors_pr_false/HMAC_DRBG.rsp, [SHA-256] COUNT = 0
+					name:          "CAVP, reseed",
+					entropy:       "06032cd5eed33f39265f49ecb142c511da9aff2af71203bffaf34a9ca5bd9c0d",
+					nonce:         "0e66f71edc43e42a45ad3c6fc6cdc4df",
+					entropyReseed: "01920a4e669ed3a85ae8a33b35a74ad7fb2a6bb4cf395ce00334a9c9a5a5d552",
+					returned:      "76fc79fe9b50beccc991a11b5635783a83536add03c157fb30645e611c2898bb2b1bc215000209208cd506cb28da2a51bdb03826aaf2bd2335d576d519160842e7158ad0949d1a9ec3e66ea1b1a064b005de914eac2e9d4f2d72a8616a80225422918250ff66a41bd2f864a6a38cc5b6499dc43f7f2bd09e1e0f8f5885935124",
+				},
+				{
+					name:             "reseed, with personalization and additional input",
+					entropy:          "641056169ed616996cd07d15c4bb554c9d584dd37f666284277f6fb11270a138",
+					nonce:            "12247dfc8656b1b67477e7d37b3c7dbb",
+					personalization:  "41556a7070e5d87e7afb104784decd71f7cca91166e1a3956bfd34d464af4d25",
+					entropyReseed:    "8df957de682bdf4b941d6984f9e54084890fab776bd9a812eefaa0767b7c7768",
+					additionalReseed: "2d2b5526c93a76497104d0a620c29af41af11e60a55539cfbd0e95429885f2e0",
+					additional: [2]string{
+						"b933d1526818bef24217dfe8386475b4882cad8e4b5320a92bbd6b7a960e31c4",
+						"b76cc2ba8730759a3d6c1b7d10e441b1707b6486918ed5a83c5ddfa63eef8e29",
+					},
+					returned: "813e2d09fe421c4404399ed0453074a440196ed3d3f892f6402052ba1548f60f793518b96722ff19945e47ce84f8deff66fe96292882c22e7c01bf090abc03c00d141f7021c7e23cc92d0d63b4643be37b9665f90306db91a20a4c04a23b2409db43707a2c144ce082e40a1964c0ec1f1134a6bdfe34c9fa7e1e3dd43fd491f5",
+				},
+				{
+					name:            "prediction resistance, with personalization and additional input",
+					entropy:         "641056169ed616996cd07d15c4bb554c9d584dd37f666284277f6fb11270a138",
+					nonce:           "12247dfc8656b1b67477e7d37b3c7dbb",
+					personalization: "41556a7070e5d87e7afb104784decd71f7cca91166e1a3956bfd34d464af4d25",
+					additional: [2]string{
+						"b933d1526818bef24217dfe8386475b4882cad8e4b5320a92bbd6b7a960e31c4",
+						"b76cc2ba8730759a3d6c1b7d10e441b1707b6486918ed5a83c5ddfa63eef8e29",
+					},
+					entropyPR: [2]string{
+						"7d4aa5979b4e1a5820ab428873f906fb1cec6d83a1c2ae0490ff5369fb346963",
+						"c5f7f2ec9533b959a89033fcaea113b6cc8014ce01368cc89aac5e31cae94fb7",
+					},
+					returned: "17f6c857833e9f52c9e18767af7a042334e7188dab764640ff4eec0f08e91a1ab1d958fd7999ab85ae4d35792137621fa562ba3a6b0b644d7d049c8e48ab4c2a5f5d65b0a0e561ebb519a32785fd5a7d814bcb2e7de41b2ab445a0fe942468c833b8808b65f981b12fcf6df057d9c5680bb21bbaa9bc25ac9466d852fd598bb8",
+				},
+			},
+		},
+		{
+			name: "SHA-512",
+			h:    sha512.New,
+			vectors: []vector{
+				{
+					// CAVP drbgvectors_pr_false/HMAC_DRBG.rsp, [SHA-512] COUNT = 0
+					name:             "CAVP, reseed",
+					entropy:          "da740cbc36057a8e282ae717fe7dfbb245e9e5d49908a0119c5dbcf0a1f2d5ab",
+					nonce:            "46561ff612217ba3ff91baa06d4b5440",
+					personalization:  "fc227293523ecb5b1e28c87863626627d958acc558a672b148ce19e2abd2dde4",
+					entropyReseed:    "1d61d4d8a41c3254b92104fd555adae0569d1835bb52657ec7fbba0fe03579c5",
+					additionalReseed: "b9ed8e35ad018a375b61189c8d365b00507cb1b4510d21cac212356b5bbaa8b2",
+					additional: [2]string{
+						"b7998998eaf9e5d34e64ff7f03de765b31f407899d20535573e670c1b402c26a",
+						"2089d49d63e0c4df58879d0cb1ba998e5b3d1a7786b785e7cf13ca5ea5e33cfd",
+					},
+					returned: "5b70f3e4da95264233efbab155b828d4e231b67cc92757feca407cc9615a660871cb07ad1a2e9a99412feda8ee34dc9c57fa08d3f8225b30d29887d20907d12330fffd14d1697ba0756d37491b0a8814106e46c8677d49d9157109c402ad0c247a2f50cd5d99e538c850b906937a05dbb8888d984bc77f6ca00b0e3bc97b16d6d25814a54aa12143afddd8b2263690565d545f4137e593bb3ca88a37b0aadf79726b95c61906257e6dc47acd5b6b7e4b534243b13c16ad5a0a1163c0099fce43f428cd27c3e6463cf5e9a9621f4b3d0b3d4654316f4707675df39278d5783823049477dcce8c57fdbd576711c91301e9bd6bb0d3e72dc46d480ed8f61fd63811",
+				},
+				{
+					// CAVP drbgvectors_pr_true/HMAC_DRBG.rsp, [SHA-512] COUNT = 0
+					name:            "CAVP, prediction resistance",
+					entropy:         "3aca6b55561521007c9ece085e9a6635e346fa804335d6ad42ebd6814c017fa8",
+					nonce:           "aa7fd3c3dd5d03d9b8efc7f70574581f",
+					personalization: "4bc9a485ec840d377ae4504aa1df41e444c4231687f3d7851c26c275bc687463",
+					additional: [2]string{
+						"b39c43539fdc24343085cbb65b8d36c54732476d781104c355c391a951313a30",
+						"b6850edd4622675ef5a507eab911e249d63fcf62f330cc8a16bb2ccc5858de5d",
+					},
+					entropyPR: [2]string{
+						"4cc19fae5a456f8a53a656d23a0b665d6ddf7f43020a5febbb552714e447565d",
+						"637386b3ab33f78fd9751c7b7e67e1e15f6e50ddc548a1eb5813f6d0d48381bf",
+					},
+					returned: "546664042bef33064da28a5718f2c2e5f72d7725e3fbe87ad2ee90fbfe6c114ed36440fbbccf29698b4360bc4ad74650de13825838106adc53002bc389ee900691649b972f3187b84d05cecc8fd034497dd99c6c997d1914b4ef838d84abf23fae7f3ac9efdcdc04c003ac642c5126b00f9f24bf1431a4f19ef0b5f3d230aab3fdf091ba31b7ddcacdf2566f2cfab30f55b3123e733829b697b7c8b248420ab98ba6f11b017175256368e8d8361102c9e6d57386becbeabda092dd57aec65bc20ebee78eea7294571e168c454066d256b81bb8b7bb469207a18ebedbb4348fbe97a4d86d2bd095c41f6de59aa0800e131e98181886a2633cdcc550914d83b327",
+				},
+				{
+					name:             "reseed, with personalization and additional input",
+					entropy:          "6d1ba1dca7906962aa273fa16237a0ce43ad59c437869cde7f92643f97005066",
+					nonce:            "a55d92ecadaf8399c42d09b894fd8aad",
+					personalization:  "441f8e6405474ff3367cc4ac0718f7d05b1ea708ea40ad29fe6996aa6635d7b9",
+					entropyReseed:    "0358de9a4852d28c2a4a693a804f832b2363ac9f2f1a492cbad8db81b0878dac",
+					additionalReseed: "755d269ae3019018b29f1738bb6ebef4372a5ef0951ddf657e4c38e79546fbe5",
+					additional: [2]string{
+						"622e4a4e927a8e5d12ee7fea24668c6e1526653df144a6ad7d677ef0c766ae5c",
+						"3c2517ba6d36a17899b305706aea0eb173128003de8f7df7d5b2b195ba27beb9",
+					},
+					returned: "65c373a0e718f774ab8ceae435bec687230d6d12f6908c23c3446c496a9b8cfa9fc6102a9e59c62fb8d0e7a958f537de4d3f4f5e6c98c57c7a7b9a02f891d20caca2d92fa72dc980cd74c87477dee7743d0f86cc04f8af7b12584aa74e4dc80cb3ad68f04c04ea09dd35c8df3df86bf3fd43ef4c27ecf8ce4441a11c24a86e74a5a7c51567b7cfec93cd34a0b9768a2cdf341fd4a7e6a02d87bffeff34d544cdcacbe6f01c7b9c1fff0fc2ef1c4fa9981fd291f22ad707c250b937b402ae89425acedf69b7d2f102fcccf5c7e0035c864043daa8da71efc915074ec0d106e561d6850767f75a56182a77922188412c53a1ba7127649abf3ef909d488c15e6552",
+				},
+				{
+					name:            "prediction resistance, with personalization and additional input",
+					entropy:         "6d1ba1dca7906962aa273fa16237a0ce43ad59c437869cde7f92643f97005066",
+					nonce:           "a55d92ecadaf8399c42d09b894fd8aad",
+					personalization: "441f8e6405474ff3367cc4ac0718f7d05b1ea708ea40ad29fe6996aa6635d7b9",
+					additional: [2]string{
+						"622e4a4e927a8e5d12ee7fea24668c6e1526653df144a6ad7d677ef0c766ae5c",
+						"3c2517ba6d36a17899b305706aea0eb173128003de8f7df7d5b2b195ba27beb9",
+					},
+					entropyPR: [2]string{
+						"052eb08b7f9fca25f50835d07df97648cf2b52e0d706212e4eeb2a9380f69f76",
+						"98085dd6b418a5122e130000da3aadf850f632a451def49a8faf7999a513fd9f",
+					},
+					returned: "c3031bb1a6b4f07836594c398d431c123b4e7b83d3f5f425fd4c107c2733b5f77a0c85030c179a4df661d6057090f647bc79187fc87bc916d1253887b292e34d486420617f18f4cd84994260382699a20895804da41621d032117baf68e059b089652b85c58a45f0348a607374d964b525cc091085d21f6302dfad4691d2fc63845d372c043b2511cd872bd62f780284231ea73abea01af970ba78bcdaa8e70fcb34b484755c644ab9eb6099e8ee8d6d5673cc5351c09a98082ad35832f22f916cff96b65e561b38d6476102c5396657619a5dca36a6899eb6cc1d602b121cf2708bdf340b1900e0110bf93dfaa2256287f4d48f2b3cc1c889eb54e78c3ecc62",
+				},
+			},
+		},
+	}
+
+	for _, d := range testdata {
+		for _, v := range d.vectors {
+			t.Run(d.name+" "+v.name, func(t *testing.T) {
+				v.run(t, func(entropy, nonce, personalization []byte) (DRBG, error) {
+					return NewHMAC(d.h, entropy, nonce, personalization)
+				})
+			})
+		}
+	}
+}
+
+func TestNewHMAC_Errors(t *testing.T) {
+	_, err := NewHMAC(sha256.New, make([]byte, 31), make([]byte, 16), nil)
+	require.ErrorIs(t, err, ErrEntropyTooShort)
+
+	_, err = NewHMAC(sha256.New, make([]byte, 32), make([]byte, 15), nil)
+	require.ErrorIs(t, err, ErrEntropyTooShort)
+
+	// SHA-224 is too weak for 256-bit security
+	_, err = NewHMAC(sha256.New224, make([]byte, 32), make([]byte, 16), nil)
+	require.Error(t, err)
+
+	d, err := NewHMAC(sha512.New, make([]byte, 32), make([]byte, 16), nil)
+	require.NoError(t, err)
+	require.ErrorIs(t, d.Reseed(make([]byte, 16), nil), ErrEntropyTooShort)
+}
+
+func TestHMAC_Limits(t *testing.T) {
+	d, err := NewHMAC(sha256.New, make([]byte, 32), make([]byte, 16), nil)
+	require.NoError(t, err)
+	assert.Equal(t, 256, d.SecurityStrength())
+
+	require.ErrorIs(t, d.Generate(make([]byte, d.MaxRequest()+1), nil), ErrRequestTooLarge)
+	require.NoError(t, d.Generate(make([]byte, d.MaxRequest()), nil))
+
+	d.counter = hashReseedInterval + 1
+	require.ErrorIs(t, d.Generate(make([]byte, 16), nil), ErrReseedRequired)
+	require.NoError(t, d.Reseed(make([]byte, 32), nil))
+	require.NoError(t, d.Generate(make([]byte, 16), nil))
+}
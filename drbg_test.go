@@ -0,0 +1,135 @@
+package onerng
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/sha512"
+	"io"
+	"math/rand/v2"
+	"testing"
+
+	"github.com/hairyhenderson/go-onerng/drbg"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCTRDRBG(t *testing.T) {
+	ctx := context.Background()
+	o := New("/dev/null", WithDialer(randomDialer()), WithWarmup(0))
+	_, err := o.Init(ctx, Default)
+	require.NoError(t, err)
+
+	r, err := o.CTRDRBG(ctx, Default, []byte("test"))
+	require.NoError(t, err)
+	defer r.Close()
+
+	// with 7 bits of min-entropy per byte, 256 bits takes 37 bytes, and the
+	// 128-bit nonce takes 19
+	assert.Equal(t, 37, r.EntropyBytes)
+
+	data := make([]byte, 16384)
+	_, _ = rand.NewChaCha8([32]byte{}).Read(data)
+	d, err := drbg.NewCTR(data[:37], data[37:56], []byte("test"))
+	require.NoError(t, err)
+
+	expected := make([]byte, 100)
+	require.NoError(t, d.Generate(expected, nil))
+	got := make([]byte, 100)
+	_, err = r.Read(got)
+	require.NoError(t, err)
+	assert.Equal(t, expected, got)
+
+	// reseeding reads on from the same session
+	r.PredictionResistance = true
+	require.NoError(t, d.Reseed(data[56:93], nil))
+	require.NoError(t, d.Generate(expected, nil))
+	_, err = r.Read(got)
+	require.NoError(t, err)
+	assert.Equal(t, expected, got)
+	assert.Equal(t, 1, r.Reseeds())
+}
+
+func TestHashDRBGs(t *testing.T) {
+	data := make([]byte, 16384)
+	_, _ = rand.NewChaCha8([32]byte{}).Read(data)
+
+	ctx := context.Background()
+	o := New("/dev/null", WithDialer(randomDialer()), WithWarmup(0))
+	_, err := o.Init(ctx, Default)
+	require.NoError(t, err)
+
+	// raw avalanche noise has 2 bits of min-entropy per byte, so 128 bytes
+	// are needed for the entropy input, and 64 for the nonce
+	r, err := o.HMACDRBG(ctx, DisableWhitener, sha512.New, []byte("test"))
+	require.NoError(t, err)
+	assert.Equal(t, 128, r.EntropyBytes)
+
+	d, err := drbg.NewHMAC(sha512.New, data[:128], data[128:192], []byte("test"))
+	require.NoError(t, err)
+	expected := make([]byte, 100)
+	require.NoError(t, d.Generate(expected, nil))
+	got := make([]byte, 100)
+	_, err = r.Read(got)
+	require.NoError(t, err)
+	assert.Equal(t, expected, got)
+
+	// the device is held until the first DRBG is closed
+	require.NoError(t, r.Close())
+	r, err = o.HashDRBG(ctx, Default, sha256.New, nil)
+	require.NoError(t, err)
+	defer r.Close()
+
+	h, err := drbg.NewHash(sha256.New, data[:37], data[37:56], nil)
+	require.NoError(t, err)
+	require.NoError(t, h.Generate(expected, nil))
+	_, err = r.Read(got)
+	require.NoError(t, err)
+	assert.Equal(t, expected, got)
+}
+
+func TestCTRDRBG_Errors(t *testing.T) {
+	o := New("/dev/null", WithDialer(randomDialer()))
+	_, err := o.CTRDRBG(context.Background(), Silent, nil)
+	require.Error(t, err)
+
+	// the start-up tests haven't run
+	_, err = o.CTRDRBG(context.Background(), Default, nil)
+	require.ErrorIs(t, err, ErrNotReady)
+
+	// not enough data
+	o = New("/dev/null", WithDialer(DialerFunc(func(context.Context, string) (Transport, error) {
+		return newFakeDev("not much"), nil
+	})))
+	o.ready.Store(true)
+	_, err = o.CTRDRBG(context.Background(), Default, nil)
+	require.Error(t, err)
+	assert.NotErrorIs(t, err, ErrNotReady)
+
+	// the device is released
+	_, err = o.Open(context.Background(), Default)
+	require.NoError(t, err)
+}
+
+func TestEntropySource(t *testing.T) {
+	ctx := context.Background()
+	o := New("/dev/null", WithDialer(randomDialer()), WithWarmup(0))
+	_, err := o.Init(ctx, Default)
+	require.NoError(t, err)
+
+	data := make([]byte, 16384)
+	_, _ = rand.NewChaCha8([32]byte{}).Read(data)
+
+	// successive reads come from the same session
+	e := o.entropySource(ctx, Default)
+	p := make([]byte, 8)
+	_, err = io.ReadFull(e, p)
+	require.NoError(t, err)
+	assert.Equal(t, data[:8], p)
+	_, err = io.ReadFull(e, p)
+	require.NoError(t, err)
+	assert.Equal(t, data[8:16], p)
+
+	require.NoError(t, e.Close())
+	_, err = e.Read(p)
+	require.ErrorIs(t, err, ErrSessionClosed)
+}
@@ -0,0 +1,152 @@
+package egd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// ErrClosed - the Buffer was closed
+var ErrClosed = errors.New("buffer closed")
+
+// Buffer - a fixed-size buffer of random data, written by a producer (such as
+// OneRNG.Read) and read by the server. Writes block while the buffer is full.
+// It is safe for concurrent use.
+type Buffer struct {
+	err error
+	// changed is closed (and replaced) whenever data is added or removed,
+	// or the buffer is closed
+	changed chan struct{}
+	buf     []byte
+	mu      sync.Mutex
+	size    int
+}
+
+// NewBuffer returns an empty Buffer holding up to size bytes. The size must be
+// positive - a buffer with no room could never be written to.
+func NewBuffer(size int) (*Buffer, error) {
+	if size <= 0 {
+		return nil, fmt.Errorf("invalid buffer size %d: must be positive", size)
+	}
+
+	return &Buffer{size: size, buf: make([]byte, 0, size), changed: make(chan struct{})}, nil
+}
+
+// Write adds p to the buffer, waiting for space as needed. It fails once the
+// buffer is closed.
+func (b *Buffer) Write(p []byte) (int, error) {
+	n := 0
+	for len(p) > 0 {
+		b.mu.Lock()
+		if b.err != nil {
+			b.mu.Unlock()
+
+			return n, b.err
+		}
+
+		c := min(b.size-len(b.buf), len(p))
+		if c == 0 {
+			wait := b.changed
+			b.mu.Unlock()
+			<-wait
+
+			continue
+		}
+
+		b.buf = append(b.buf, p[:c]...)
+		b.broadcast()
+		b.mu.Unlock()
+
+		n += c
+		p = p[c:]
+	}
+
+	return n, nil
+}
+
+// CloseWithError closes the buffer - writes fail from then on, and once the
+// buffered data has been read, so do blocking reads. The error is returned
+// from both, or ErrClosed if err is nil.
+func (b *Buffer) CloseWithError(err error) {
+	if err == nil {
+		err = ErrClosed
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.err == nil {
+		b.err = err
+		b.broadcast()
+	}
+}
+
+// Len returns the number of bytes buffered
+func (b *Buffer) Len() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return len(b.buf)
+}
+
+// ReadAvailable reads as much of p as is buffered, without waiting, and
+// returns the number of bytes read
+func (b *Buffer) ReadAvailable(p []byte) int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.take(p)
+}
+
+// ReadFull fills p, waiting for more data as needed. If the context is done or
+// the buffer is closed first, the data already taken is lost.
+func (b *Buffer) ReadFull(ctx context.Context, p []byte) error {
+	for len(p) > 0 {
+		b.mu.Lock()
+		n := b.take(p)
+		p = p[n:]
+		if len(p) == 0 {
+			b.mu.Unlock()
+
+			return nil
+		}
+		if b.err != nil {
+			b.mu.Unlock()
+
+			return b.err
+		}
+		wait := b.changed
+		b.mu.Unlock()
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-wait:
+		}
+	}
+
+	return nil
+}
+
+// take removes up to len(p) bytes from the front of the buffer into p - the
+// lock must be held
+func (b *Buffer) take(p []byte) int {
+	n := copy(p, b.buf)
+	if n == 0 {
+		return 0
+	}
+
+	rest := copy(b.buf, b.buf[n:])
+	clear(b.buf[rest:])
+	b.buf = b.buf[:rest]
+	b.broadcast()
+
+	return n
+}
+
+// broadcast wakes everything waiting for a change - the lock must be held
+func (b *Buffer) broadcast() {
+	close(b.changed)
+	b.changed = make(chan struct{})
+}
@@ -0,0 +1,110 @@
+package egd
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newBuffer(t *testing.T, size int) *Buffer {
+	t.Helper()
+
+	b, err := NewBuffer(size)
+	require.NoError(t, err)
+
+	return b
+}
+
+func TestNewBuffer_InvalidSize(t *testing.T) {
+	for _, size := range []int{0, -1} {
+		_, err := NewBuffer(size)
+		assert.Error(t, err, "size %d", size)
+	}
+}
+
+func TestBuffer(t *testing.T) {
+	b := newBuffer(t, 8)
+	n, err := b.Write([]byte("abcde"))
+	require.NoError(t, err)
+	assert.Equal(t, 5, n)
+	assert.Equal(t, 5, b.Len())
+
+	p := make([]byte, 3)
+	assert.Equal(t, 3, b.ReadAvailable(p))
+	assert.Equal(t, "abc", string(p))
+
+	p = make([]byte, 10)
+	assert.Equal(t, 2, b.ReadAvailable(p))
+	assert.Equal(t, "de", string(p[:2]))
+	assert.Zero(t, b.ReadAvailable(p))
+}
+
+func TestBuffer_WriteBlocksWhenFull(t *testing.T) {
+	b := newBuffer(t, 4)
+
+	done := make(chan error)
+	go func() {
+		_, err := b.Write([]byte("abcdefgh"))
+		done <- err
+	}()
+
+	require.Eventually(t, func() bool { return b.Len() == 4 }, time.Second, time.Millisecond)
+	select {
+	case <-done:
+		t.Fatal("write didn't block")
+	default:
+	}
+
+	p := make([]byte, 8)
+	require.NoError(t, b.ReadFull(context.Background(), p))
+	assert.Equal(t, "abcdefgh", string(p))
+	require.NoError(t, <-done)
+}
+
+func TestBuffer_ReadFullWaits(t *testing.T) {
+	b := newBuffer(t, 16)
+
+	go func() {
+		for _, c := range "abcd" {
+			time.Sleep(time.Millisecond)
+			_, _ = b.Write([]byte{byte(c)})
+		}
+	}()
+
+	p := make([]byte, 4)
+	require.NoError(t, b.ReadFull(context.Background(), p))
+	assert.Equal(t, "abcd", string(p))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	err := b.ReadFull(ctx, p)
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+}
+
+func TestBuffer_CloseWithError(t *testing.T) {
+	b := newBuffer(t, 4)
+	_, err := b.Write([]byte("ab"))
+	require.NoError(t, err)
+
+	readErr := errors.New("unplugged")
+	b.CloseWithError(readErr)
+	// only the first error counts
+	b.CloseWithError(nil)
+
+	_, err = b.Write([]byte("c"))
+	require.ErrorIs(t, err, readErr)
+
+	// the buffered data can still be read, but no more
+	p := make([]byte, 3)
+	err = b.ReadFull(context.Background(), p)
+	require.ErrorIs(t, err, readErr)
+
+	b = newBuffer(t, 4)
+	b.CloseWithError(nil)
+	_, err = b.Write([]byte("c"))
+	require.ErrorIs(t, err, ErrClosed)
+}
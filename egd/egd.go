@@ -0,0 +1,292 @@
+/*
+Package egd implements a server for the Entropy Gathering Daemon (EGD)
+protocol, which OpenSSL, GnuPG, and others can use to get random data over a
+Unix socket.
+
+Each request is a command byte, followed by its arguments:
+
+	0x00                   get the entropy count - the reply is a 4-byte
+	                       big-endian number of bits available
+	0x01 N                 read up to N bytes without blocking - the reply is
+	                       a count byte, followed by that many bytes
+	0x02 N                 read N bytes, blocking until they're available -
+	                       the reply is the N bytes
+	0x03 B1 B2 N DATA...   write N bytes of DATA, which hold (B1<<8 | B2) bits
+	                       of entropy - there's no reply, and the data is
+	                       discarded, so one client can't affect what's
+	                       served to another
+	0x04                   get the server's process ID - the reply is a count
+	                       byte, followed by the PID in ASCII
+
+The data served comes from a Buffer, which is filled by something else (such
+as OneRNG.Read):
+
+	b, err := egd.NewBuffer(4096)
+	...
+	go func() {
+		_, err := o.Read(ctx, b, -1, onerng.Default)
+		b.CloseWithError(err)
+	}()
+
+	l, err := net.Listen("unix", path)
+	...
+	s := &egd.Server{Buffer: b, MaxBytesPerConn: 1 << 20}
+	err = s.Serve(ctx, l)
+*/
+package egd
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// The EGD commands
+const (
+	CmdEntropyCount byte = 0x00
+	CmdRead         byte = 0x01
+	CmdReadBlocking byte = 0x02
+	CmdWrite        byte = 0x03
+	CmdPID          byte = 0x04
+)
+
+// ErrLimitExceeded - a connection asked for more data than it's allowed
+var ErrLimitExceeded = errors.New("per-connection limit exceeded")
+
+// Server - serves random data from a Buffer over the EGD protocol
+type Server struct {
+	// Buffer - where the data comes from
+	Buffer *Buffer
+	// OnError is called with the error that ended a connection, if any.
+	// Optional.
+	OnError func(error)
+	// EntropyPerByte - the entropy of each byte of data, in bits, for the
+	// entropy count - defaults to 8
+	EntropyPerByte float64
+	// MaxConns - the most connections to serve at once - others wait to be
+	// accepted (0 for no limit)
+	MaxConns int
+	// MaxBytesPerConn - the most data to serve on one connection, after
+	// which it's closed (0 for no limit)
+	MaxBytesPerConn int64
+	// IdleTimeout - close connections that don't send a complete command
+	// in this long (0 for no timeout)
+	IdleTimeout time.Duration
+	// PID - the process ID to report - defaults to this process's
+	PID int
+}
+
+// Serve accepts connections on l and serves them, until the context is done
+// or accepting fails. It closes l before returning, and waits for the
+// connections to finish.
+func (s *Server) Serve(ctx context.Context, l net.Listener) error {
+	// the connections are cancelled before waiting for them
+	var wg sync.WaitGroup
+	defer wg.Wait()
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	go func() {
+		<-ctx.Done()
+		_ = l.Close()
+	}()
+
+	var sem chan struct{}
+	if s.MaxConns > 0 {
+		sem = make(chan struct{}, s.MaxConns)
+	}
+
+	for {
+		if sem != nil {
+			select {
+			case sem <- struct{}{}:
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		}
+
+		c, err := l.Accept()
+		if err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+
+			return err
+		}
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if sem != nil {
+				defer func() { <-sem }()
+			}
+
+			err := s.ServeConn(ctx, c)
+			if err != nil && s.OnError != nil {
+				s.OnError(err)
+			}
+		}()
+	}
+}
+
+// ServeConn serves commands on one connection until the client disconnects,
+// the context is done, or an error occurs. It closes the connection before
+// returning.
+func (s *Server) ServeConn(ctx context.Context, c net.Conn) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	stop := context.AfterFunc(ctx, func() { _ = c.Close() })
+	defer stop()
+	defer c.Close()
+
+	cc := &conn{Conn: c, s: s}
+	for {
+		err := cc.command(ctx)
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+
+			return err
+		}
+	}
+}
+
+// conn - one client connection
+type conn struct {
+	net.Conn
+	s      *Server
+	served int64
+}
+
+// command reads and handles one command
+func (c *conn) command(ctx context.Context) error {
+	if c.s.IdleTimeout > 0 {
+		err := c.SetReadDeadline(time.Now().Add(c.s.IdleTimeout))
+		if err != nil {
+			return err
+		}
+	}
+
+	var cmd [1]byte
+	_, err := io.ReadFull(c, cmd[:])
+	if err != nil {
+		return err
+	}
+
+	switch cmd[0] {
+	case CmdEntropyCount:
+		return c.entropyCount()
+	case CmdRead:
+		return c.read(ctx, false)
+	case CmdReadBlocking:
+		return c.read(ctx, true)
+	case CmdWrite:
+		return c.write()
+	case CmdPID:
+		return c.pid()
+	default:
+		return fmt.Errorf("unknown EGD command 0x%02x", cmd[0])
+	}
+}
+
+func (c *conn) entropyCount() error {
+	h := c.s.EntropyPerByte
+	if h <= 0 {
+		h = 8
+	}
+	bits := math.Floor(float64(c.s.Buffer.Len()) * min(h, 8))
+
+	var reply [4]byte
+	binary.BigEndian.PutUint32(reply[:], uint32(bits))
+	_, err := c.Write(reply[:])
+
+	return err
+}
+
+func (c *conn) read(ctx context.Context, blocking bool) error {
+	var n [1]byte
+	_, err := io.ReadFull(c, n[:])
+	if err != nil {
+		return unexpected(err)
+	}
+
+	if c.s.MaxBytesPerConn > 0 && c.served+int64(n[0]) > c.s.MaxBytesPerConn {
+		return fmt.Errorf("%w: %d bytes requested after %d served (limit %d)",
+			ErrLimitExceeded, n[0], c.served, c.s.MaxBytesPerConn)
+	}
+
+	// the count byte, then the data
+	reply := make([]byte, 1+int(n[0]))
+	defer clear(reply)
+
+	if blocking {
+		err = c.s.Buffer.ReadFull(ctx, reply[1:])
+		if err != nil {
+			return err
+		}
+		// blocking reads have no count byte
+		reply = reply[1:]
+		c.served += int64(len(reply))
+	} else {
+		got := c.s.Buffer.ReadAvailable(reply[1:])
+		reply[0] = byte(got)
+		reply = reply[:1+got]
+		c.served += int64(got)
+	}
+
+	_, err = c.Write(reply)
+
+	return err
+}
+
+func (c *conn) write() error {
+	// 2 bytes of entropy bits, and a count byte
+	var hdr [3]byte
+	_, err := io.ReadFull(c, hdr[:])
+	if err != nil {
+		return unexpected(err)
+	}
+
+	// the data is accepted (for compatibility) but discarded
+	_, err = io.CopyN(io.Discard, c, int64(hdr[2]))
+	if err != nil {
+		return unexpected(err)
+	}
+
+	return nil
+}
+
+func (c *conn) pid() error {
+	pid := c.s.PID
+	if pid == 0 {
+		pid = os.Getpid()
+	}
+
+	s := strconv.Itoa(pid)
+	_, err := c.Write(append([]byte{byte(len(s))}, s...))
+
+	return err
+}
+
+// unexpected turns an EOF in the middle of a command into an unexpected EOF
+func unexpected(err error) error {
+	if errors.Is(err, io.EOF) {
+		return io.ErrUnexpectedEOF
+	}
+
+	return err
+}
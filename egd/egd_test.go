@@ -0,0 +1,233 @@
+package egd
+
+import (
+	"context"
+	"encoding/binary"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// client - a minimal EGD client
+type client struct {
+	t *testing.T
+	net.Conn
+}
+
+func (c *client) send(p ...byte) {
+	c.t.Helper()
+
+	_, err := c.Write(p)
+	require.NoError(c.t, err)
+}
+
+func (c *client) recv(n int) []byte {
+	c.t.Helper()
+
+	p := make([]byte, n)
+	_, err := io.ReadFull(c, p)
+	require.NoError(c.t, err)
+
+	return p
+}
+
+func (c *client) entropyCount() uint32 {
+	c.send(CmdEntropyCount)
+
+	return binary.BigEndian.Uint32(c.recv(4))
+}
+
+func (c *client) read(n byte) []byte {
+	c.send(CmdRead, n)
+
+	return c.recv(int(c.recv(1)[0]))
+}
+
+func (c *client) readBlocking(n byte) []byte {
+	c.send(CmdReadBlocking, n)
+
+	return c.recv(int(n))
+}
+
+// serve starts a server on a temporary socket, and returns a function to
+// connect to it
+func serve(t *testing.T, s *Server) func() *client {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "egd.sock")
+	l, err := net.Listen("unix", path)
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- s.Serve(ctx, l) }()
+	t.Cleanup(func() {
+		cancel()
+		require.ErrorIs(t, <-done, context.Canceled)
+	})
+
+	return func() *client {
+		c, err := net.Dial("unix", path)
+		require.NoError(t, err)
+		t.Cleanup(func() { _ = c.Close() })
+
+		return &client{t: t, Conn: c}
+	}
+}
+
+func filled(t *testing.T, data []byte) *Buffer {
+	t.Helper()
+
+	b := newBuffer(t, 1024)
+	_, err := b.Write(data)
+	require.NoError(t, err)
+
+	return b
+}
+
+func TestServer(t *testing.T) {
+	data := make([]byte, 300)
+	for i := range data {
+		data[i] = byte(i)
+	}
+	b := filled(t, data)
+	dial := serve(t, &Server{Buffer: b, PID: 1234})
+	c := dial()
+
+	assert.Equal(t, uint32(300*8), c.entropyCount())
+
+	assert.Equal(t, data[:10], c.read(10))
+	assert.Equal(t, data[10:265], c.readBlocking(255))
+	assert.Equal(t, uint32(35*8), c.entropyCount())
+
+	// a non-blocking read gets what's there
+	assert.Equal(t, data[265:], c.read(100))
+	assert.Empty(t, c.read(100))
+
+	c.send(CmdPID)
+	assert.Equal(t, "1234", string(c.recv(int(c.recv(1)[0]))))
+
+	// a blocking read waits for more data
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		_, _ = b.Write([]byte("more"))
+	}()
+	assert.Equal(t, []byte("more"), c.readBlocking(4))
+
+	// written data is discarded - it doesn't change what's served, and
+	// isn't credited
+	_, _ = b.Write([]byte{0x0f, 0xf0})
+	c.send(CmdWrite, 0, 16, 2, 0xff, 0xff)
+	assert.Equal(t, uint32(16), c.entropyCount())
+	assert.Equal(t, []byte{0x0f, 0xf0}, c.read(2))
+
+	// another client works at the same time
+	c2 := dial()
+	assert.Equal(t, uint32(0), c2.entropyCount())
+}
+
+func TestServer_DefaultPID(t *testing.T) {
+	c := serve(t, &Server{Buffer: newBuffer(t, 1)})()
+
+	c.send(CmdPID)
+	pid, err := strconv.Atoi(string(c.recv(int(c.recv(1)[0]))))
+	require.NoError(t, err)
+	assert.Positive(t, pid)
+}
+
+func TestServer_EntropyPerByte(t *testing.T) {
+	c := serve(t, &Server{Buffer: filled(t, make([]byte, 100)), EntropyPerByte: 2.5})()
+	assert.Equal(t, uint32(250), c.entropyCount())
+}
+
+func TestServer_MaxBytesPerConn(t *testing.T) {
+	errs := make(chan error, 1)
+	dial := serve(t, &Server{
+		Buffer:          filled(t, make([]byte, 1000)),
+		MaxBytesPerConn: 100,
+		OnError:         func(err error) { errs <- err },
+	})
+
+	c := dial()
+	assert.Len(t, c.readBlocking(60), 60)
+	assert.Len(t, c.read(40), 40)
+
+	// over the limit, so the connection's closed
+	c.send(CmdRead, 1)
+	_, err := c.Read(make([]byte, 1))
+	require.ErrorIs(t, err, io.EOF)
+	require.ErrorIs(t, <-errs, ErrLimitExceeded)
+
+	// new connections get a fresh allowance
+	assert.Len(t, dial().read(100), 100)
+}
+
+func TestServer_MaxConns(t *testing.T) {
+	dial := serve(t, &Server{Buffer: filled(t, make([]byte, 100)), MaxConns: 1})
+
+	c1 := dial()
+	assert.Equal(t, uint32(800), c1.entropyCount())
+
+	// the second connection isn't served until the first is closed
+	c2 := dial()
+	c2.send(CmdEntropyCount)
+	require.NoError(t, c2.SetReadDeadline(time.Now().Add(50*time.Millisecond)))
+	_, err := c2.Read(make([]byte, 4))
+	require.ErrorIs(t, err, os.ErrDeadlineExceeded)
+
+	require.NoError(t, c1.Close())
+	require.NoError(t, c2.SetReadDeadline(time.Time{}))
+	assert.Equal(t, uint32(800), binary.BigEndian.Uint32(c2.recv(4)))
+}
+
+func TestServer_IdleTimeout(t *testing.T) {
+	c := serve(t, &Server{Buffer: newBuffer(t, 1), IdleTimeout: 10 * time.Millisecond})()
+
+	// the server hangs up
+	_, err := c.Read(make([]byte, 1))
+	require.ErrorIs(t, err, io.EOF)
+}
+
+func TestServer_BadCommands(t *testing.T) {
+	errs := make(chan error, 1)
+	dial := serve(t, &Server{Buffer: newBuffer(t, 1), OnError: func(err error) { errs <- err }})
+
+	c := dial()
+	c.send(0x42)
+	_, err := c.Read(make([]byte, 1))
+	require.ErrorIs(t, err, io.EOF)
+	require.ErrorContains(t, <-errs, "unknown EGD command 0x42")
+
+	// a truncated command
+	c = dial()
+	c.send(CmdWrite, 0, 8, 4, 1)
+	require.NoError(t, c.Close())
+	require.ErrorIs(t, <-errs, io.ErrUnexpectedEOF)
+
+	// a clean disconnect isn't an error
+	c = dial()
+	assert.Equal(t, uint32(0), c.entropyCount())
+	require.NoError(t, c.Close())
+	select {
+	case err := <-errs:
+		t.Fatalf("unexpected error %v", err)
+	case <-time.After(10 * time.Millisecond):
+	}
+}
+
+func TestServer_BufferClosed(t *testing.T) {
+	b := newBuffer(t, 10)
+	c := serve(t, &Server{Buffer: b})()
+
+	b.CloseWithError(nil)
+	c.send(CmdReadBlocking, 1)
+	_, err := c.Read(make([]byte, 1))
+	require.ErrorIs(t, err, io.EOF)
+}
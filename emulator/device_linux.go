@@ -0,0 +1,294 @@
+package emulator
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/hairyhenderson/go-onerng/internal/termios"
+)
+
+// Device - an emulated OneRNG, attached to the master side of a PTY
+type Device struct {
+	// Source provides the noise sent while the device is running. Defaults to
+	// crypto/rand.
+	Source io.Reader
+	// ID is the hardware ID reported in response to cmdI. It's sent wrapped in
+	// "___" markers, as the firmware does.
+	ID string
+	// Image is the firmware image sent in response to cmdX. It's followed by
+	// ImagePadding zero bytes, which is how the end of the image is detected.
+	Image []byte
+	// ImagePadding is the number of zero bytes sent after Image - defaults to
+	// 256.
+	ImagePadding int
+	// Version is the hardware version reported in response to cmdv. Defaults
+	// to 3.
+	Version int
+	// Cooked leaves the PTY's line discipline in its default (canonical, echo)
+	// state, like a freshly-enumerated /dev/ttyACM device. By default the PTY
+	// is put in raw mode.
+	Cooked bool
+
+	master *os.File
+	// slave is held open so that the master doesn't see EIO every time the
+	// client closes the device
+	slave *os.File
+	path  string
+
+	wake chan struct{}
+	done chan struct{}
+	wg   sync.WaitGroup
+
+	mu       sync.Mutex
+	cmds     []string
+	pending  []byte
+	mode     byte
+	running  bool
+	closed   bool
+	closeErr error
+}
+
+// noise-generation mode bits, as set by cmd0-cmd7 - these mirror the
+// onerng.NoiseMode flags
+const (
+	modeDisableWhitener = 1 << iota
+	modeEnableRF
+	modeDisableAvalanche
+
+	// modeSilent is cmd4 - the avalanche diode is disabled, RF disabled
+	modeSilent = modeDisableAvalanche
+)
+
+const (
+	// chunkSize is the size of each write of noise to the PTY
+	chunkSize = 64
+)
+
+// Start allocates the PTY and starts emulating the device. Use Path to find
+// the device to open.
+func (d *Device) Start() error {
+	if d.master != nil {
+		return errors.New("emulator already started")
+	}
+
+	master, path, err := termios.OpenPTY()
+	if err != nil {
+		return fmt.Errorf("failed to allocate pty: %w", err)
+	}
+
+	slave, err := os.OpenFile(path, os.O_RDWR, 0)
+	if err != nil {
+		_ = master.Close()
+
+		return fmt.Errorf("failed to open pty slave %s: %w", path, err)
+	}
+
+	if !d.Cooked {
+		t, err := termios.Get(slave)
+		if err == nil {
+			termios.MakeRaw(t)
+			err = termios.Set(slave, t)
+		}
+		if err != nil {
+			_ = slave.Close()
+			_ = master.Close()
+
+			return fmt.Errorf("failed to set raw mode: %w", err)
+		}
+	}
+
+	if d.Source == nil {
+		d.Source = rand.Reader
+	}
+	if d.Version == 0 {
+		d.Version = 3
+	}
+	if d.ImagePadding == 0 {
+		d.ImagePadding = 256
+	}
+
+	d.master = master
+	d.slave = slave
+	d.path = path
+	d.mode = modeSilent
+	d.wake = make(chan struct{}, 1)
+	d.done = make(chan struct{})
+
+	d.wg.Add(2)
+	go d.readCommands()
+	go d.writeOutput()
+
+	return nil
+}
+
+// Path returns the path to the slave side of the PTY, for use as the OneRNG's
+// device path.
+func (d *Device) Path() string {
+	return d.path
+}
+
+// Commands returns the commands received so far, without the trailing
+// newline (i.e. "cmdO").
+func (d *Device) Commands() []string {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	return append([]string{}, d.cmds...)
+}
+
+// Running reports whether the device is currently sending output (i.e. cmdO
+// was the most recent run/pause command).
+func (d *Device) Running() bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	return d.running
+}
+
+// Close stops the emulator and releases the PTY. Clients with the device
+// open will see I/O errors, as if it had been unplugged.
+func (d *Device) Close() error {
+	d.mu.Lock()
+	if d.closed || d.master == nil {
+		d.mu.Unlock()
+
+		return d.closeErr
+	}
+	d.closed = true
+	close(d.done)
+	d.mu.Unlock()
+
+	err := d.master.Close()
+	if serr := d.slave.Close(); err == nil {
+		err = serr
+	}
+	d.wg.Wait()
+
+	d.mu.Lock()
+	d.closeErr = err
+	d.mu.Unlock()
+
+	return err
+}
+
+// readCommands reads and handles commands from the client until the PTY is
+// closed. The firmware only looks at the character following "cmd", so
+// anything else on a line is ignored.
+func (d *Device) readCommands() {
+	defer d.wg.Done()
+
+	s := bufio.NewScanner(d.master)
+	s.Split(scanCommands)
+	for s.Scan() {
+		line := s.Text()
+		if !strings.HasPrefix(line, "cmd") || len(line) < 4 {
+			continue
+		}
+		d.handle(line[:4])
+	}
+}
+
+// scanCommands splits on either CR or NL, since a cooked tty will translate
+// one to the other.
+func scanCommands(data []byte, atEOF bool) (advance int, token []byte, err error) {
+	if i := bytes.IndexAny(data, "\r\n"); i >= 0 {
+		return i + 1, data[:i], nil
+	}
+	if atEOF && len(data) > 0 {
+		return len(data), data, nil
+	}
+
+	return 0, nil, nil
+}
+
+//nolint:gocyclo
+func (d *Device) handle(cmd string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.cmds = append(d.cmds, cmd)
+
+	switch c := cmd[3]; {
+	case c >= '0' && c <= '7':
+		d.mode = c - '0'
+	case c == 'v':
+		d.pending = append(d.pending, fmt.Sprintf("Version %d\n", d.Version)...)
+	case c == 'I':
+		d.pending = append(d.pending, fmt.Sprintf("___%s___\n", d.ID)...)
+	case c == 'X':
+		d.pending = append(d.pending, d.Image...)
+		d.pending = append(d.pending, make([]byte, d.ImagePadding)...)
+	case c == 'w':
+		// flush the entropy pool - nothing to do here
+	case c == 'O':
+		d.running = true
+	case c == 'o':
+		d.running = false
+	}
+
+	select {
+	case d.wake <- struct{}{}:
+	default:
+	}
+}
+
+// next returns the next chunk of output to send, or nil if there's nothing
+// to send right now
+func (d *Device) next() ([]byte, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if !d.running {
+		return nil, nil
+	}
+
+	if len(d.pending) > 0 {
+		n := min(len(d.pending), chunkSize)
+		b := d.pending[:n]
+		d.pending = d.pending[n:]
+
+		return b, nil
+	}
+
+	// with both noise sources disabled, there's nothing to send
+	if d.mode&modeDisableAvalanche != 0 && d.mode&modeEnableRF == 0 {
+		return nil, nil
+	}
+
+	b := make([]byte, chunkSize)
+	_, err := io.ReadFull(d.Source, b)
+
+	return b, err
+}
+
+// writeOutput sends the device's output to the client whenever it's running
+func (d *Device) writeOutput() {
+	defer d.wg.Done()
+
+	for {
+		b, err := d.next()
+		if err != nil {
+			return
+		}
+		if b == nil {
+			select {
+			case <-d.done:
+				return
+			case <-d.wake:
+			}
+
+			continue
+		}
+
+		if _, err := d.master.Write(b); err != nil {
+			return
+		}
+	}
+}
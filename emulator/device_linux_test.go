@@ -0,0 +1,107 @@
+package emulator
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func startDevice(t *testing.T, d *Device) *os.File {
+	t.Helper()
+	require.NoError(t, d.Start())
+	t.Cleanup(func() { _ = d.Close() })
+
+	f, err := os.OpenFile(d.Path(), os.O_RDWR, 0)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = f.Close() })
+
+	return f
+}
+
+func TestVersionAndID(t *testing.T) {
+	d := &Device{ID: "deadbeef"}
+	f := startDevice(t, d)
+
+	_, err := f.WriteString("cmdo\ncmd4\ncmdv\ncmdI\ncmdO\n")
+	require.NoError(t, err)
+
+	require.NoError(t, f.SetReadDeadline(time.Now().Add(time.Second)))
+	s := bufio.NewScanner(f)
+	require.True(t, s.Scan())
+	assert.Equal(t, "Version 3", s.Text())
+	require.True(t, s.Scan())
+	assert.Equal(t, "___deadbeef___", s.Text())
+
+	assert.Equal(t, []string{"cmdo", "cmd4", "cmdv", "cmdI", "cmdO"}, d.Commands())
+	assert.True(t, d.Running())
+}
+
+func TestNoise(t *testing.T) {
+	d := &Device{Source: bytes.NewReader(bytes.Repeat([]byte{0xa5}, 1024))}
+	f := startDevice(t, d)
+
+	_, err := f.WriteString("cmd0\ncmdO\n")
+	require.NoError(t, err)
+
+	require.NoError(t, f.SetReadDeadline(time.Now().Add(time.Second)))
+	b := make([]byte, 512)
+	_, err = io.ReadFull(f, b)
+	require.NoError(t, err)
+	assert.Equal(t, bytes.Repeat([]byte{0xa5}, 512), b)
+}
+
+func TestSilent(t *testing.T) {
+	d := &Device{}
+	f := startDevice(t, d)
+
+	_, err := f.WriteString("cmd4\ncmdO\n")
+	require.NoError(t, err)
+
+	require.NoError(t, f.SetReadDeadline(time.Now().Add(50*time.Millisecond)))
+	_, err = f.Read(make([]byte, 1))
+	assert.True(t, os.IsTimeout(err), err)
+}
+
+func TestImage(t *testing.T) {
+	img := []byte{0xfe, 0xed, 0xbe, 0xef, 0x20, 0x14, 1, 2, 3}
+	d := &Device{Image: img, ImagePadding: 16}
+	f := startDevice(t, d)
+
+	_, err := f.WriteString("cmdo\ncmd4\ncmdX\ncmdO\n")
+	require.NoError(t, err)
+
+	require.NoError(t, f.SetReadDeadline(time.Now().Add(time.Second)))
+	b := make([]byte, len(img)+16)
+	_, err = io.ReadFull(f, b)
+	require.NoError(t, err)
+	assert.Equal(t, append(img, make([]byte, 16)...), b)
+}
+
+func TestClose(t *testing.T) {
+	d := &Device{}
+	f := startDevice(t, d)
+
+	require.NoError(t, d.Close())
+	assert.NoError(t, d.Close())
+
+	_, err := f.Read(make([]byte, 1))
+	assert.Error(t, err)
+}
+
+func TestScanCommands(t *testing.T) {
+	s := bufio.NewScanner(bytes.NewBufferString("cmdO\r\ncmdo\rcmd4"))
+	s.Split(scanCommands)
+	lines := []string{}
+	for s.Scan() {
+		if s.Text() != "" {
+			lines = append(lines, s.Text())
+		}
+	}
+	assert.Equal(t, []string{"cmdO", "cmdo", "cmd4"}, lines)
+}
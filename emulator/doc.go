@@ -0,0 +1,20 @@
+/*
+Package emulator provides a virtual OneRNG, driven through a Linux
+pseudo-terminal.
+
+The emulated device understands the same commands as the real firmware
+(see https://github.com/OneRNG/firmware/blob/master/cdc_app.c), so a
+*onerng.OneRNG can be pointed at the slave side of the PTY in tests:
+
+	d := &emulator.Device{Version: 3, Image: img}
+	if err := d.Start(); err != nil {
+		return err
+	}
+	defer d.Close()
+
+	o := &onerng.OneRNG{Path: d.Path()}
+	version, err := o.Version(ctx)
+
+This package is only functional on Linux.
+*/
+package emulator
@@ -0,0 +1,66 @@
+package onerng
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"syscall"
+)
+
+// Errors returned by this package. These are usually wrapped with more
+// detail, so use errors.Is to check for them.
+var (
+	// ErrDeviceNotFound - the device doesn't exist, or has disappeared
+	ErrDeviceNotFound = errors.New("OneRNG device not found")
+	// ErrDeviceBusy - the device is in use by another process
+	ErrDeviceBusy = errors.New("OneRNG device busy")
+	// ErrTimeout - the device stopped sending data
+	ErrTimeout = errors.New("timed out waiting for data from the OneRNG")
+	// ErrNotOneRNG - the device didn't respond the way a OneRNG would
+	ErrNotOneRNG = errors.New("device is not a OneRNG")
+	// ErrShortRead - less data was read than requested
+	ErrShortRead = errors.New("unexpected short read")
+	// ErrBadImage - the firmware image is malformed
+	ErrBadImage = errors.New("bad firmware image")
+	// ErrSignatureInvalid - the firmware image's signature couldn't be
+	// verified
+	ErrSignatureInvalid = errors.New("firmware signature invalid")
+	// ErrHealthTest - a continuous health test failed, so the noise source
+	// is probably broken (see HealthError)
+	ErrHealthTest = errors.New("noise source health test failed")
+	// ErrInsufficientEntropy - not enough entropy has been credited to
+	// emit output (see Accountant)
+	ErrInsufficientEntropy = errors.New("insufficient entropy credited")
+)
+
+// timeoutError wraps a read timeout as ErrTimeout, while still satisfying
+// os.IsTimeout
+type timeoutError struct {
+	err error
+}
+
+func (e *timeoutError) Error() string {
+	return fmt.Sprintf("%s: %v", ErrTimeout, e.err)
+}
+
+func (e *timeoutError) Unwrap() error { return e.err }
+
+func (e *timeoutError) Is(target error) bool { return target == ErrTimeout }
+
+// Timeout is always true
+func (e *timeoutError) Timeout() bool { return true }
+
+// classifyOpenErr wraps errors from opening the device with the appropriate
+// sentinel error
+func classifyOpenErr(err error) error {
+	switch {
+	case errors.Is(err, os.ErrNotExist),
+		errors.Is(err, syscall.ENODEV),
+		errors.Is(err, syscall.ENXIO):
+		return fmt.Errorf("%w: %w", ErrDeviceNotFound, err)
+	case errors.Is(err, syscall.EBUSY):
+		return fmt.Errorf("%w: %w", ErrDeviceBusy, err)
+	default:
+		return err
+	}
+}
@@ -0,0 +1,40 @@
+package onerng
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"syscall"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTimeoutError(t *testing.T) {
+	var err error = &timeoutError{err: os.ErrDeadlineExceeded}
+	assert.ErrorIs(t, err, ErrTimeout)
+	assert.ErrorIs(t, err, os.ErrDeadlineExceeded)
+	assert.True(t, os.IsTimeout(err))
+	assert.Equal(t, "timed out waiting for data from the OneRNG: i/o timeout", err.Error())
+}
+
+func TestClassifyOpenErr(t *testing.T) {
+	err := classifyOpenErr(&fs.PathError{Op: "open", Path: "/dev/ttyACM0", Err: syscall.ENOENT})
+	assert.ErrorIs(t, err, ErrDeviceNotFound)
+	assert.ErrorIs(t, err, os.ErrNotExist)
+
+	err = classifyOpenErr(&fs.PathError{Op: "open", Path: "/dev/ttyACM0", Err: syscall.ENXIO})
+	assert.ErrorIs(t, err, ErrDeviceNotFound)
+
+	err = classifyOpenErr(&fs.PathError{Op: "open", Path: "/dev/ttyACM0", Err: syscall.EBUSY})
+	assert.ErrorIs(t, err, ErrDeviceBusy)
+
+	orig := &fs.PathError{Op: "open", Path: "/dev/ttyACM0", Err: syscall.EACCES}
+	assert.Equal(t, orig, classifyOpenErr(orig))
+}
+
+func TestLockError_Is(t *testing.T) {
+	var err error = &LockError{Path: "/dev/ttyACM0", PID: 42}
+	assert.ErrorIs(t, err, ErrDeviceBusy)
+	assert.False(t, errors.Is(err, ErrDeviceNotFound))
+}
@@ -0,0 +1,50 @@
+package onerng
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	"github.com/hairyhenderson/go-onerng/expand"
+)
+
+// Expander returns a fast ChaCha20 keystream generator with fast key erasure
+// (see package expand), seeded from the device in the given mode, for when
+// more data is needed than the device can produce. It reseeds from the device
+// in the background - set its ReseedBytes and ReseedInterval fields to
+// choose how often. Close it when done, to stop the background reseeding.
+//
+// Each seed is enough data to hold 256 bits of min-entropy, going by the
+// mode's claimed min-entropy (see WithMinEntropy). The device must have passed
+// its start-up tests (see Init). The seeds all come from one health-tested
+// Session, which stays open until the Reader is closed, so other operations
+// wait until then.
+func (o *OneRNG) Expander(ctx context.Context, mode NoiseMode) (*expand.Reader, error) {
+	h := o.getMinEntropy(mode)
+	if h <= 0 {
+		return nil, fmt.Errorf("can't seed an expander in noise mode %d (%s), which has no entropy", mode, mode)
+	}
+
+	n := bytesFor(expand.SeedSize*8, h)
+	entropy := o.entropySource(ctx, mode)
+
+	seed := make([]byte, n)
+	_, err := io.ReadFull(entropy, seed)
+	if err != nil {
+		_ = entropy.Close()
+
+		return nil, fmt.Errorf("failed to read seed: %w", err)
+	}
+
+	r, err := expand.New(seed)
+	clear(seed)
+	if err != nil {
+		_ = entropy.Close()
+
+		return nil, err
+	}
+
+	r.ReseedFrom(ctx, entropy, n)
+
+	return r, nil
+}
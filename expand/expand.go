@@ -0,0 +1,338 @@
+/*
+Package expand implements a fast keystream generator, for expanding a small
+amount of entropy from a slow source (such as the OneRNG) into as much random
+data as needed.
+
+The generator is ChaCha20 with fast key erasure: each batch of keystream is
+generated under a fresh key, the first 32 bytes of the batch become the key for
+the next batch, and the old key is overwritten, along with the cipher state
+expanded from it. Output is also erased from the generator as soon as it's
+returned. An attacker who later compromises the generator's state can't
+recover anything it has already output.
+
+	r, err := expand.New(seed)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+	r.ReseedBytes = 1 << 30
+	r.ReseedFrom(ctx, source, 32)
+	_, err = io.ReadFull(r, out)
+
+With ReseedFrom, fresh seed material is mixed into the key from time to time,
+read in the background so that reads never wait for the source.
+*/
+package expand
+
+import (
+	"context"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"golang.org/x/crypto/chacha20"
+)
+
+const (
+	// SeedSize - the minimum length of a seed, which should be full entropy
+	SeedSize = chacha20.KeySize
+
+	// batchSize - the number of bytes of keystream generated under each key,
+	// including the next key
+	batchSize = 64 * 64
+)
+
+// ErrClosed - the Reader was closed
+var ErrClosed = errors.New("expander closed")
+
+// zeros - the keystream is XORed with this
+var zeros [batchSize]byte
+
+// Reader - an io.Reader generating a ChaCha20 keystream with fast key
+// erasure. It is safe for concurrent use.
+type Reader struct {
+	// ReseedBytes - with ReseedFrom, reseed after generating this many bytes
+	// (0 to never reseed based on output)
+	ReseedBytes int64
+	// ReseedInterval - with ReseedFrom, reseed after this much time has
+	// passed since the last reseed (0 to never reseed based on time)
+	ReseedInterval time.Duration
+
+	// now returns the current time - overridden in tests
+	now func() time.Time
+
+	// want - signals the background reseeder that a seed is needed
+	want chan struct{}
+	// seeds - seeds read in the background
+	seeds  chan []byte
+	cancel context.CancelFunc
+	done   chan struct{}
+	err    error
+
+	seeded    time.Time
+	buf       []byte
+	batch     [batchSize]byte
+	key       [chacha20.KeySize]byte
+	mu        sync.Mutex
+	generated int64
+	reseeds   int
+	closed    bool
+}
+
+// New returns a Reader keyed from seed, which must be at least SeedSize bytes
+// long. Longer seeds (with less than full entropy) are compressed.
+func New(seed []byte) (*Reader, error) {
+	if len(seed) < SeedSize {
+		return nil, fmt.Errorf("seed too short (%d bytes, must be at least %d)", len(seed), SeedSize)
+	}
+
+	r := &Reader{}
+	r.key = sha256.Sum256(seed)
+	r.seeded = r.timeNow()
+
+	return r, nil
+}
+
+// Read fills p with keystream
+func (r *Reader) Read(p []byte) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.closed {
+		return 0, ErrClosed
+	}
+
+	err := r.maybeReseed()
+	if err != nil {
+		return 0, err
+	}
+
+	n := 0
+	for n < len(p) {
+		if len(r.buf) == 0 {
+			if n > 0 {
+				// a long read may make a reseed due partway through
+				err = r.maybeReseed()
+				if err != nil {
+					return n, err
+				}
+			}
+
+			if len(p)-n >= batchSize-SeedSize {
+				// generate straight into p, skipping the buffer
+				r.generate(p[n : n+batchSize-SeedSize])
+				n += batchSize - SeedSize
+				r.generated += batchSize - SeedSize
+
+				continue
+			}
+
+			r.generate(r.batch[SeedSize:])
+			r.buf = r.batch[SeedSize:]
+		}
+
+		c := copy(p[n:], r.buf)
+		clear(r.buf[:c])
+		r.buf = r.buf[c:]
+		n += c
+		r.generated += int64(c)
+	}
+
+	return n, nil
+}
+
+// generate fills out with the keystream following the next key, which
+// replaces the current one. The cipher is erased afterwards, so that it
+// doesn't hold on to the old key until it's garbage collected.
+func (r *Reader) generate(out []byte) {
+	// a zero nonce is fine, since a key is never used twice
+	var nonce [chacha20.NonceSize]byte
+	c, err := chacha20.NewUnauthenticatedCipher(r.key[:], nonce[:])
+	if err != nil {
+		// can only happen with the wrong key or nonce size
+		panic(err)
+	}
+	defer func() { *c = chacha20.Cipher{} }()
+
+	c.XORKeyStream(r.key[:], zeros[:SeedSize])
+	c.XORKeyStream(out, zeros[:len(out)])
+}
+
+// Reseed mixes seed into the key. Any buffered keystream is discarded.
+func (r *Reader) Reseed(seed []byte) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.reseed(seed)
+}
+
+func (r *Reader) reseed(seed []byte) {
+	h := sha256.New()
+	h.Write(r.key[:])
+	h.Write(seed)
+	h.Sum(r.key[:0])
+	clear(seed)
+
+	clear(r.buf)
+	r.buf = nil
+	r.seeded = r.timeNow()
+	r.generated = 0
+	r.reseeds++
+}
+
+// Reseeds returns the number of times the Reader has been reseeded
+func (r *Reader) Reseeds() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.reseeds
+}
+
+// ReseedFrom starts reseeding from src in the background, reading seedBytes
+// (at least SeedSize) for each reseed, whenever ReseedBytes or ReseedInterval
+// say it's due. Reads don't wait for the seed - it's mixed in on the first
+// read after it's arrived. If reading from src fails, reads fail from then on
+// whenever a reseed is due.
+//
+// The background reader stops when ctx is done or the Reader is closed. If
+// it's in the middle of reading a seed, Close doesn't wait for it - the seed
+// is erased when the read returns. If src is an io.Closer, it's closed when
+// the background reader stops. ReseedFrom must only be called once.
+func (r *Reader) ReseedFrom(ctx context.Context, src io.Reader, seedBytes int) {
+	ctx, cancel := context.WithCancel(ctx)
+
+	r.mu.Lock()
+	r.want = make(chan struct{}, 1)
+	r.seeds = make(chan []byte, 1)
+	r.cancel = cancel
+	r.done = make(chan struct{})
+	r.mu.Unlock()
+
+	go r.reseeder(ctx, src, max(seedBytes, SeedSize))
+}
+
+func (r *Reader) reseeder(ctx context.Context, src io.Reader, seedBytes int) {
+	defer close(r.done)
+	if c, ok := src.(io.Closer); ok {
+		defer c.Close()
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-r.want:
+		}
+
+		seed := make([]byte, seedBytes)
+		_, err := io.ReadFull(src, seed)
+		if err != nil {
+			r.mu.Lock()
+			r.err = fmt.Errorf("failed to read seed: %w", err)
+			r.mu.Unlock()
+
+			return
+		}
+
+		if !r.offer(seed) {
+			return
+		}
+	}
+}
+
+// offer hands a seed from the background reseeder to the next read, or
+// erases it if the Reader's been closed (or a seed's already waiting).
+// Returns false if the Reader's been closed.
+func (r *Reader) offer(seed []byte) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.closed {
+		clear(seed)
+
+		return false
+	}
+
+	select {
+	case r.seeds <- seed:
+	default:
+		clear(seed)
+	}
+
+	return true
+}
+
+// maybeReseed mixes in a seed from the background reseeder if one's arrived,
+// and asks for one if a reseed is due
+func (r *Reader) maybeReseed() error {
+	if r.seeds == nil {
+		return nil
+	}
+
+	select {
+	case seed := <-r.seeds:
+		r.reseed(seed)
+	default:
+	}
+
+	if !r.reseedDue() {
+		return nil
+	}
+
+	if r.err != nil {
+		return r.err
+	}
+
+	select {
+	case r.want <- struct{}{}:
+	default:
+		// already asked
+	}
+
+	return nil
+}
+
+func (r *Reader) reseedDue() bool {
+	if r.ReseedBytes > 0 && r.generated >= r.ReseedBytes {
+		return true
+	}
+
+	return r.ReseedInterval > 0 && r.timeNow().Sub(r.seeded) >= r.ReseedInterval
+}
+
+// Close stops the background reseeder, if there is one, and erases the key
+// and any buffered keystream. It doesn't wait for a seed that's being read.
+func (r *Reader) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.cancel != nil {
+		r.cancel()
+	}
+
+	if r.seeds != nil {
+		select {
+		case seed := <-r.seeds:
+			clear(seed)
+		default:
+		}
+	}
+
+	clear(r.key[:])
+	clear(r.batch[:])
+	r.buf = nil
+	r.closed = true
+
+	return nil
+}
+
+func (r *Reader) timeNow() time.Time {
+	if r.now == nil {
+		return time.Now()
+	}
+
+	return r.now()
+}
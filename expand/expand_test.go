@@ -0,0 +1,292 @@
+package expand
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"errors"
+	"io"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/chacha20"
+)
+
+var testSeed = bytes.Repeat([]byte{0x42}, SeedSize)
+
+// keystream returns the first n bytes of ChaCha20 keystream under key, with a
+// zero nonce
+func keystream(t *testing.T, key []byte, n int) []byte {
+	t.Helper()
+
+	c, err := chacha20.NewUnauthenticatedCipher(key, make([]byte, chacha20.NonceSize))
+	require.NoError(t, err)
+
+	out := make([]byte, n)
+	c.XORKeyStream(out, out)
+
+	return out
+}
+
+func TestNew(t *testing.T) {
+	_, err := New(make([]byte, SeedSize-1))
+	require.Error(t, err)
+
+	r, err := New(testSeed)
+	require.NoError(t, err)
+
+	// the first batch's keystream, after the next key
+	key := sha256.Sum256(testSeed)
+	batch := keystream(t, key[:], batchSize)
+
+	out := make([]byte, 100)
+	_, err = io.ReadFull(r, out)
+	require.NoError(t, err)
+	assert.Equal(t, batch[SeedSize:SeedSize+100], out)
+
+	// the key's been replaced with the start of the batch
+	assert.Equal(t, batch[:SeedSize], r.key[:])
+
+	// and the output's been erased from the buffer
+	assert.Equal(t, make([]byte, 100), r.batch[SeedSize:SeedSize+100])
+}
+
+func TestRead_BatchBoundaries(t *testing.T) {
+	key := sha256.Sum256(testSeed)
+	batch1 := keystream(t, key[:], batchSize)
+	batch2 := keystream(t, batch1[:SeedSize], batchSize)
+	expected := append(batch1[SeedSize:], batch2[SeedSize:]...)
+
+	// the same stream comes out however it's read
+	for _, size := range []int{1, 7, 1000, batchSize - SeedSize, batchSize, len(expected)} {
+		r, err := New(testSeed)
+		require.NoError(t, err)
+
+		out := []byte{}
+		buf := make([]byte, size)
+		for len(out) < len(expected) {
+			n, err := r.Read(buf[:min(size, len(expected)-len(out))])
+			require.NoError(t, err)
+			out = append(out, buf[:n]...)
+		}
+		assert.Equal(t, expected, out, "reads of %d bytes", size)
+	}
+}
+
+func TestReseed(t *testing.T) {
+	r1, err := New(testSeed)
+	require.NoError(t, err)
+	r2, err := New(testSeed)
+	require.NoError(t, err)
+
+	a := make([]byte, 64)
+	b := make([]byte, 64)
+	_, _ = r1.Read(a)
+	_, _ = r2.Read(b)
+	require.Equal(t, a, b)
+
+	seed := bytes.Repeat([]byte{1}, SeedSize)
+	r2.Reseed(seed)
+	assert.Equal(t, 1, r2.Reseeds())
+	// the seed's been erased
+	assert.Equal(t, make([]byte, SeedSize), seed)
+
+	_, _ = r1.Read(a)
+	_, _ = r2.Read(b)
+	assert.NotEqual(t, a, b)
+}
+
+// seedSource - counts the seeds read from it
+type seedSource struct {
+	err   error
+	mu    sync.Mutex
+	reads int
+}
+
+func (s *seedSource) Read(p []byte) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.err != nil {
+		return 0, s.err
+	}
+	s.reads++
+
+	for i := range p {
+		p[i] = byte(s.reads)
+	}
+
+	return len(p), nil
+}
+
+func TestReseedFrom(t *testing.T) {
+	r, err := New(testSeed)
+	require.NoError(t, err)
+	r.ReseedBytes = 1000
+
+	src := &seedSource{}
+	r.ReseedFrom(context.Background(), src, 40)
+
+	buf := make([]byte, 600)
+	assert.Eventually(t, func() bool {
+		_, err := r.Read(buf)
+		require.NoError(t, err)
+
+		return r.Reseeds() >= 3
+	}, 5*time.Second, time.Millisecond)
+
+	require.NoError(t, r.Close())
+	_, err = r.Read(buf)
+	require.ErrorIs(t, err, ErrClosed)
+	assert.Equal(t, make([]byte, SeedSize), r.key[:])
+}
+
+func TestReseedFrom_Interval(t *testing.T) {
+	r, err := New(testSeed)
+	require.NoError(t, err)
+
+	now := time.Unix(0, 0)
+	r.now = func() time.Time { return now }
+	r.seeded = now
+	r.ReseedInterval = time.Minute
+
+	src := &seedSource{}
+	r.ReseedFrom(context.Background(), src, SeedSize)
+	defer r.Close()
+
+	buf := make([]byte, 10)
+	_, err = r.Read(buf)
+	require.NoError(t, err)
+
+	// not due yet, so no seed is read
+	time.Sleep(10 * time.Millisecond)
+	src.mu.Lock()
+	assert.Zero(t, src.reads)
+	src.mu.Unlock()
+
+	now = now.Add(time.Minute)
+	assert.Eventually(t, func() bool {
+		_, err := r.Read(buf)
+		require.NoError(t, err)
+
+		return r.Reseeds() == 1
+	}, 5*time.Second, time.Millisecond)
+}
+
+func TestRead_ReseedDueMidRead(t *testing.T) {
+	r, err := New(testSeed)
+	require.NoError(t, err)
+	r.ReseedBytes = 100
+	// as set up by ReseedFrom, without the background reader
+	r.want = make(chan struct{}, 1)
+	r.seeds = make(chan []byte, 1)
+
+	// one long read asks for a seed as soon as the first batch is out
+	buf := make([]byte, 4*(batchSize-SeedSize))
+	n, err := r.Read(buf)
+	require.NoError(t, err)
+	assert.Equal(t, len(buf), n)
+	assert.Len(t, r.want, 1)
+
+	// if the source has failed, the read stops where the reseed is due
+	<-r.want
+	r.generated = 0
+	r.err = errors.New("unplugged")
+	n, err = r.Read(buf)
+	require.ErrorIs(t, err, r.err)
+	assert.Equal(t, batchSize-SeedSize, n)
+}
+
+func TestReseedFrom_Error(t *testing.T) {
+	r, err := New(testSeed)
+	require.NoError(t, err)
+	r.ReseedBytes = 100
+
+	srcErr := errors.New("unplugged")
+	r.ReseedFrom(context.Background(), &seedSource{err: srcErr}, SeedSize)
+	defer r.Close()
+
+	buf := make([]byte, 100)
+	// this read makes a reseed due
+	_, err = r.Read(buf)
+	require.NoError(t, err)
+
+	assert.Eventually(t, func() bool {
+		_, err := r.Read(buf)
+
+		return errors.Is(err, srcErr)
+	}, 5*time.Second, time.Millisecond)
+}
+
+func TestReseedFrom_Cancel(t *testing.T) {
+	r, err := New(testSeed)
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	r.ReseedFrom(ctx, &seedSource{}, SeedSize)
+	cancel()
+
+	select {
+	case <-r.done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("reseeder didn't stop")
+	}
+
+	// reading still works, without reseeding
+	_, err = r.Read(make([]byte, 10))
+	require.NoError(t, err)
+	require.NoError(t, r.Close())
+}
+
+// blockingSource - blocks reads until released
+type blockingSource struct {
+	reading chan struct{}
+	release chan struct{}
+}
+
+func (s *blockingSource) Read(p []byte) (int, error) {
+	close(s.reading)
+	<-s.release
+
+	for i := range p {
+		p[i] = 0x42
+	}
+
+	return len(p), nil
+}
+
+func TestClose_ReseederBlocked(t *testing.T) {
+	r, err := New(testSeed)
+	require.NoError(t, err)
+	r.ReseedBytes = 100
+
+	src := &blockingSource{reading: make(chan struct{}), release: make(chan struct{})}
+	r.ReseedFrom(context.Background(), src, SeedSize)
+
+	buf := make([]byte, 100)
+	_, err = r.Read(buf)
+	require.NoError(t, err)
+	// this read asks for a seed
+	_, err = r.Read(buf)
+	require.NoError(t, err)
+	<-src.reading
+
+	closed := make(chan error)
+	go func() { closed <- r.Close() }()
+
+	select {
+	case err := <-closed:
+		require.NoError(t, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("Close waited for the reseeder")
+	}
+
+	// the seed that arrives after Close is erased, not handed over
+	close(src.release)
+	<-r.done
+	assert.Empty(t, r.seeds)
+	assert.Equal(t, make([]byte, SeedSize), r.key[:])
+}
@@ -0,0 +1,65 @@
+package onerng
+
+import (
+	"context"
+	"math/rand/v2"
+	"testing"
+	"time"
+
+	"github.com/hairyhenderson/go-onerng/expand"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExpander(t *testing.T) {
+	data := make([]byte, 16384)
+	_, _ = rand.NewChaCha8([32]byte{}).Read(data)
+
+	ctx := context.Background()
+	o := New("/dev/null", WithDialer(randomDialer()), WithWarmup(0))
+	_, err := o.Init(ctx, Default)
+	require.NoError(t, err)
+
+	r, err := o.Expander(ctx, Default)
+	require.NoError(t, err)
+	defer r.Close()
+
+	// with 7 bits of min-entropy per byte, 256 bits takes 37 bytes
+	e, err := expand.New(data[:37])
+	require.NoError(t, err)
+
+	expected := make([]byte, 10000)
+	_, err = e.Read(expected)
+	require.NoError(t, err)
+	got := make([]byte, 10000)
+	_, err = r.Read(got)
+	require.NoError(t, err)
+	assert.Equal(t, expected, got)
+
+	// reseeding reads from the device in the background
+	r.ReseedBytes = 1
+	assert.Eventually(t, func() bool {
+		_, err := r.Read(got)
+		require.NoError(t, err)
+
+		return r.Reseeds() > 0
+	}, 5*time.Second, time.Millisecond)
+}
+
+func TestExpander_Errors(t *testing.T) {
+	o := New("/dev/null", WithDialer(randomDialer()))
+	_, err := o.Expander(context.Background(), Silent)
+	require.Error(t, err)
+
+	// the start-up tests haven't run
+	_, err = o.Expander(context.Background(), Default)
+	require.ErrorIs(t, err, ErrNotReady)
+
+	o = New("/dev/null", WithDialer(DialerFunc(func(context.Context, string) (Transport, error) {
+		return newFakeDev("not much"), nil
+	})))
+	o.ready.Store(true)
+	_, err = o.Expander(context.Background(), Default)
+	require.Error(t, err)
+	assert.NotErrorIs(t, err, ErrNotReady)
+}
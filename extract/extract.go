@@ -0,0 +1,112 @@
+/*
+Package extract implements streaming randomness extractors, for removing bias
+from raw noise (such as the OneRNG's output with DisableWhitener), and for
+concentrating its entropy.
+
+Extractors are writers, which write their output to another writer:
+
+	e := extract.NewVonNeumann(out, 4)
+	_, err := io.Copy(e, raw)
+	...
+	err = e.Close()
+	fmt.Printf("%f input bits per output bit\n", e.Stats().InputPerOutput())
+
+Use NewReader to read from an extractor instead.
+
+Each extractor keeps track of how many input bits it consumed, how many bits
+it output, and how much entropy it credits to the output - see Stats.
+*/
+package extract
+
+import (
+	"bytes"
+	"io"
+)
+
+// Extractor - a streaming extractor. Close processes any buffered input that
+// can be, and discards the rest - it doesn't close the underlying writer.
+type Extractor interface {
+	io.WriteCloser
+	// Stats - the extractor's input, output, and credited entropy so far
+	Stats() Stats
+}
+
+// Stats - counts of what's passed through an extractor
+type Stats struct {
+	// InputBits - the number of input bits consumed
+	InputBits int64 `json:"input_bits"`
+	// OutputBits - the number of output bits produced
+	OutputBits int64 `json:"output_bits"`
+	// CreditedBits - the min-entropy credited to the output, in bits
+	CreditedBits float64 `json:"credited_bits"`
+}
+
+// InputPerOutput returns the number of input bits credited for each output
+// bit, or 0 if there's been no output
+func (s Stats) InputPerOutput() float64 {
+	if s.OutputBits == 0 {
+		return 0
+	}
+
+	return float64(s.InputBits) / float64(s.OutputBits)
+}
+
+// EntropyPerOutput returns the min-entropy credited to each output bit (at
+// most 1), or 0 if there's been no output
+func (s Stats) EntropyPerOutput() float64 {
+	if s.OutputBits == 0 {
+		return 0
+	}
+
+	return s.CreditedBits / float64(s.OutputBits)
+}
+
+// Reader - reads from an extractor, which reads its input from another
+// reader. Reads block until some output is available.
+type Reader struct {
+	r   io.Reader
+	e   Extractor
+	out *bytes.Buffer
+	buf []byte
+}
+
+// NewReader returns a Reader which reads input from r, through the extractor
+// created by newExtractor
+func NewReader(r io.Reader, newExtractor func(w io.Writer) Extractor) *Reader {
+	out := &bytes.Buffer{}
+
+	return &Reader{r: r, e: newExtractor(out), out: out, buf: make([]byte, 4096)}
+}
+
+func (r *Reader) Read(p []byte) (int, error) {
+	for r.out.Len() == 0 {
+		n, err := r.r.Read(r.buf)
+		if n > 0 {
+			_, werr := r.e.Write(r.buf[:n])
+			if werr != nil {
+				return 0, werr
+			}
+		}
+		if err != nil {
+			if err == io.EOF {
+				// the last of the input may still produce some output
+				cerr := r.e.Close()
+				if cerr != nil {
+					return 0, cerr
+				}
+			}
+			if r.out.Len() > 0 {
+				break
+			}
+
+			return 0, err
+		}
+	}
+
+	return r.out.Read(p)
+}
+
+// Stats - the extractor's stats (see Extractor)
+func (r *Reader) Stats() Stats {
+	return r.e.Stats()
+}
@@ -0,0 +1,80 @@
+package extract
+
+import (
+	"bytes"
+	"io"
+	"math/rand/v2"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// biasedBits returns n bytes of independent bits, each 1 with probability p
+func biasedBits(seed byte, n int, p float64) []byte {
+	rng := rand.New(rand.NewChaCha8([32]byte{seed}))
+	out := make([]byte, n)
+	for i := range out {
+		for j := 0; j < 8; j++ {
+			if rng.Float64() < p {
+				out[i] |= 1 << j
+			}
+		}
+	}
+
+	return out
+}
+
+func TestStats(t *testing.T) {
+	s := Stats{}
+	assert.Zero(t, s.InputPerOutput())
+	assert.Zero(t, s.EntropyPerOutput())
+
+	s = Stats{InputBits: 1000, OutputBits: 250, CreditedBits: 200}
+	assert.InDelta(t, 4, s.InputPerOutput(), 0)
+	assert.InDelta(t, 0.8, s.EntropyPerOutput(), 0)
+}
+
+func TestReader(t *testing.T) {
+	in := biasedBits(1, 10000, 0.5)
+
+	expected := &bytes.Buffer{}
+	e := NewVonNeumann(expected, 3)
+	_, err := e.Write(in)
+	require.NoError(t, err)
+	require.NoError(t, e.Close())
+
+	r := NewReader(bytes.NewReader(in), func(w io.Writer) Extractor {
+		return NewVonNeumann(w, 3)
+	})
+
+	// small reads, to make sure output isn't lost between them
+	got := []byte{}
+	buf := make([]byte, 7)
+	for {
+		n, err := r.Read(buf)
+		got = append(got, buf[:n]...)
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+	}
+
+	assert.Equal(t, expected.Bytes(), got)
+	assert.Equal(t, e.Stats(), r.Stats())
+}
+
+type errWriter struct{ err error }
+
+func (w errWriter) Write([]byte) (int, error) { return 0, w.err }
+
+func TestReader_WriteError(t *testing.T) {
+	r := NewReader(bytes.NewReader(make([]byte, 100)), func(io.Writer) Extractor {
+		// the extractor's output is ignored, so it can fail
+		e, _ := NewSHA256(errWriter{io.ErrShortWrite}, 1, 8)
+
+		return e
+	})
+	_, err := r.Read(make([]byte, 10))
+	require.ErrorIs(t, err, io.ErrShortWrite)
+}
@@ -0,0 +1,82 @@
+package extract
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOutputEntropy(t *testing.T) {
+	// expected values computed from the formula with 400 digits of
+	// precision
+	testdata := []struct {
+		nIn      int
+		hIn      float64
+		expected float64
+	}{
+		{512, 512, 256},
+		{512, 256, 255},
+		{512, 128, 128},
+		{256, 256, 251.68976456872704},
+		{256, 200, 200},
+		{1024, 320, 256},
+		// more entropy than bits is capped
+		{256, 1000, 251.68976456872704},
+	}
+	for _, d := range testdata {
+		assert.InDelta(t, d.expected, OutputEntropy(d.nIn, 256, 256, d.hIn), 1e-9, "nIn=%d, hIn=%f", d.nIn, d.hIn)
+	}
+}
+
+func TestSHA256(t *testing.T) {
+	out := &bytes.Buffer{}
+	s, err := NewSHA256(out, 3, 4)
+	require.NoError(t, err)
+
+	in := bytes.Repeat([]byte("a"), 250)
+	extractAll(t, s, in)
+
+	// two whole 96-byte blocks, and the remainder is discarded
+	sum := sha256.Sum256(in[:96])
+	assert.Equal(t, append(sum[:], sum[:]...), out.Bytes())
+
+	st := s.Stats()
+	assert.Equal(t, int64(2*96*8), st.InputBits)
+	assert.Equal(t, int64(2*256), st.OutputBits)
+	assert.InDelta(t, 3, st.InputPerOutput(), 0)
+	// 96 bytes with 4 bits each is 384 bits of min-entropy, which is more
+	// than enough for (almost) full entropy output
+	assert.InDelta(t, 1, st.EntropyPerOutput(), 1e-9)
+
+	// at 1:1 with 2 bits per byte, only a quarter of each output bit is
+	// entropy
+	s, err = NewSHA256(io.Discard, 1, 2)
+	require.NoError(t, err)
+	extractAll(t, s, make([]byte, 64))
+	assert.InDelta(t, 0.25, s.Stats().EntropyPerOutput(), 1e-9)
+}
+
+func TestNewSHA256_Errors(t *testing.T) {
+	_, err := NewSHA256(io.Discard, 0, 8)
+	require.Error(t, err)
+
+	_, err = NewSHA256(io.Discard, 1, 0)
+	require.Error(t, err)
+
+	_, err = NewSHA256(io.Discard, 1, 9)
+	require.Error(t, err)
+}
+
+func TestSHA256_WriteError(t *testing.T) {
+	s, err := NewSHA256(errWriter{io.ErrClosedPipe}, 1, 8)
+	require.NoError(t, err)
+
+	n, err := s.Write(make([]byte, 40))
+	require.ErrorIs(t, err, io.ErrClosedPipe)
+	assert.Equal(t, 32, n)
+	assert.Zero(t, s.Stats().OutputBits)
+}
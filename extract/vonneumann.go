@@ -0,0 +1,142 @@
+package extract
+
+import (
+	"fmt"
+	"io"
+)
+
+// vnBlockBits - iterated von Neumann extraction works on blocks of this many
+// input bits. Longer blocks are more efficient, but delay the output.
+const vnBlockBits = 4096
+
+// VonNeumann - the von Neumann debiaser, optionally iterated (Peres, 1992).
+//
+// Each pair of input bits 01 becomes a 0, 10 becomes a 1, and 00 and 11 are
+// discarded. If the input bits are independent and identically distributed,
+// the output is unbiased, whatever the input's bias - but at least three
+// quarters of the input is thrown away. Iterating recovers much of the
+// discarded entropy, by extracting again from the XOR of each pair, and from
+// the discarded pairs. With enough levels, the output approaches the input's
+// Shannon entropy.
+//
+// Input bits are taken from the most significant first. The output is
+// credited with full entropy, which relies on the input bits being
+// independent.
+type VonNeumann struct {
+	w      io.Writer
+	block  []byte
+	out    []byte
+	stats  Stats
+	levels int
+	cur    byte
+	bits   int
+}
+
+var _ Extractor = (*VonNeumann)(nil)
+
+// NewVonNeumann returns a von Neumann extractor which writes to w, with the
+// given number of levels (1 for the plain von Neumann debiaser). It panics if
+// levels is less than 1.
+func NewVonNeumann(w io.Writer, levels int) *VonNeumann {
+	if levels < 1 {
+		panic(fmt.Sprintf("extract: invalid von Neumann levels %d", levels))
+	}
+
+	return &VonNeumann{w: w, levels: levels, block: make([]byte, 0, vnBlockBits)}
+}
+
+func (v *VonNeumann) Write(p []byte) (int, error) {
+	v.out = v.out[:0]
+	for _, b := range p {
+		for i := 7; i >= 0; i-- {
+			v.block = append(v.block, (b>>i)&1)
+		}
+
+		if len(v.block) == vnBlockBits {
+			v.extract()
+		}
+	}
+
+	return v.flush(len(p))
+}
+
+// Close extracts what it can from the last partial block. Output bits left
+// over that don't make up a whole byte are discarded.
+func (v *VonNeumann) Close() error {
+	v.out = v.out[:0]
+	v.extract()
+
+	_, err := v.flush(0)
+
+	return err
+}
+
+// Stats - the extractor's input, output, and credited entropy so far
+func (v *VonNeumann) Stats() Stats {
+	return v.stats
+}
+
+func (v *VonNeumann) flush(n int) (int, error) {
+	if len(v.out) == 0 {
+		return n, nil
+	}
+
+	_, err := v.w.Write(v.out)
+	if err != nil {
+		return 0, err
+	}
+
+	bits := int64(len(v.out)) * 8
+	v.stats.OutputBits += bits
+	v.stats.CreditedBits += float64(bits)
+
+	return n, nil
+}
+
+// extract processes the current block
+func (v *VonNeumann) extract() {
+	v.stats.InputBits += int64(len(v.block))
+	peres(v.block, v.levels, v.emit)
+	v.block = v.block[:0]
+}
+
+func (v *VonNeumann) emit(bit byte) {
+	v.cur = v.cur<<1 | bit
+	v.bits++
+	if v.bits == 8 {
+		v.out = append(v.out, v.cur)
+		v.cur, v.bits = 0, 0
+	}
+}
+
+// peres - the iterated von Neumann procedure, on a slice of bits (one per
+// byte), to the given depth
+func peres(bits []byte, depth int, emit func(bit byte)) {
+	if depth == 0 || len(bits) < 2 {
+		return
+	}
+
+	// u - the XOR of each pair, v - the value of each discarded pair, which
+	// are only needed for the next level
+	next := depth > 1
+	var u, v []byte
+	if next {
+		u = make([]byte, 0, len(bits)/2)
+		v = make([]byte, 0, len(bits)/4)
+	}
+
+	for i := 0; i+1 < len(bits); i += 2 {
+		a, b := bits[i], bits[i+1]
+		if a != b {
+			emit(a)
+		} else if next {
+			v = append(v, a)
+		}
+		if next {
+			u = append(u, a^b)
+		}
+	}
+
+	peres(u, depth-1, emit)
+	peres(v, depth-1, emit)
+}
@@ -0,0 +1,112 @@
+package extract
+
+import (
+	"bytes"
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func extractAll(t *testing.T, e Extractor, in []byte) {
+	t.Helper()
+
+	_, err := e.Write(in)
+	require.NoError(t, err)
+	require.NoError(t, e.Close())
+}
+
+func TestVonNeumann(t *testing.T) {
+	testdata := []struct {
+		in, out []byte
+	}{
+		// 01 10 01 10 -> 0101, twice
+		{[]byte{0b01100110, 0b01100110}, []byte{0b01010101}},
+		// 00 and 11 are discarded
+		{[]byte{0b00110011, 0b11001100}, nil},
+		{[]byte{0b10101010, 0b00000000, 0b10101010}, []byte{0xff}},
+		// leftover bits are dropped
+		{[]byte{0b10101010}, nil},
+	}
+	for _, d := range testdata {
+		out := &bytes.Buffer{}
+		v := NewVonNeumann(out, 1)
+		extractAll(t, v, d.in)
+		assert.Equal(t, d.out, out.Bytes(), "%08b", d.in)
+		assert.Equal(t, int64(len(d.in)*8), v.Stats().InputBits)
+		assert.Equal(t, int64(len(d.out)*8), v.Stats().OutputBits)
+	}
+
+	assert.Panics(t, func() { NewVonNeumann(nil, 0) })
+}
+
+func TestPeres(t *testing.T) {
+	bits := []byte{
+		1, 1, // discarded, u=0, v=1
+		0, 1, // 0, u=1
+		1, 0, // 1, u=1
+		0, 0, // discarded, u=0, v=0
+	}
+
+	out := []byte{}
+	emit := func(b byte) { out = append(out, b) }
+
+	peres(bits, 1, emit)
+	assert.Equal(t, []byte{0, 1}, out)
+
+	// u = 0110 gives 0 from (0,1) and 1 from (1,0), and v = 10 gives 1
+	out = out[:0]
+	peres(bits, 2, emit)
+	assert.Equal(t, []byte{0, 1, 0, 1, 1}, out)
+}
+
+func TestVonNeumann_Debiases(t *testing.T) {
+	// 70% ones
+	in := biasedBits(2, 100000, 0.7)
+
+	for _, levels := range []int{1, 2, 4, 8} {
+		out := &bytes.Buffer{}
+		v := NewVonNeumann(out, levels)
+		extractAll(t, v, in)
+
+		ones := 0
+		for _, b := range out.Bytes() {
+			for i := 0; i < 8; i++ {
+				ones += int(b>>i) & 1
+			}
+		}
+		s := v.Stats()
+		assert.InDelta(t, 0.5, float64(ones)/float64(s.OutputBits), 0.005, "levels %d", levels)
+		assert.InDelta(t, 1, s.EntropyPerOutput(), 0)
+
+		// plain von Neumann gives p(1-p) output bits per input bit, and
+		// more levels approach the Shannon entropy
+		yield := 1 / s.InputPerOutput()
+		switch levels {
+		case 1:
+			assert.InDelta(t, 0.7*0.3, yield, 0.005)
+		case 8:
+			h := -0.7*math.Log2(0.7) - 0.3*math.Log2(0.3)
+			assert.Greater(t, yield, 0.85*h)
+			assert.Less(t, yield, h)
+		}
+	}
+}
+
+func TestVonNeumann_ChunkingDoesntMatter(t *testing.T) {
+	in := biasedBits(3, 5000, 0.4)
+
+	whole := &bytes.Buffer{}
+	extractAll(t, NewVonNeumann(whole, 3), in)
+
+	chunked := &bytes.Buffer{}
+	v := NewVonNeumann(chunked, 3)
+	for i := 0; i < len(in); i += 77 {
+		_, err := v.Write(in[i:min(i+77, len(in))])
+		require.NoError(t, err)
+	}
+	require.NoError(t, v.Close())
+
+	assert.Equal(t, whole.Bytes(), chunked.Bytes())
+}
@@ -0,0 +1,324 @@
+/*
+Package fips1402 implements the FIPS 140-2 statistical tests for random number
+generators - the same tests that rngtest(1) from rng-tools runs.
+
+Data is tested in blocks of 20,000 bits:
+
+	t := &fips1402.Tester{}
+	for {
+		if _, err := io.ReadFull(r, block); err != nil {
+			return err
+		}
+		result, err := t.Test(block)
+		...
+	}
+
+Or use Run to test a number of blocks read from an io.Reader.
+
+The tests are not very sensitive, and a good random source is expected to fail
+occasionally (roughly once in every thousand blocks), so a single failure
+doesn't mean much. Summary.Passed takes this into account.
+*/
+package fips1402
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/hairyhenderson/go-onerng/internal/special"
+)
+
+const (
+	// BlockBits - the number of bits in each test block
+	BlockBits = 20000
+	// BlockSize - the number of bytes in each test block
+	BlockSize = BlockBits / 8
+)
+
+// Test - one of the FIPS 140-2 tests
+type Test int
+
+// The tests, in the order they're run
+const (
+	// Monobit - the number of ones in the block
+	Monobit Test = iota
+	// Poker - the distribution of 4-bit values in the block
+	Poker
+	// Runs - the number of runs (of ones and zeros) of each length
+	Runs
+	// LongRun - no run may be 26 bits or longer
+	LongRun
+	// Continuous - no 32-bit word may be repeated immediately
+	Continuous
+
+	numTests
+)
+
+// Tests - all of the tests
+var Tests = []Test{Monobit, Poker, Runs, LongRun, Continuous}
+
+func (t Test) String() string {
+	switch t {
+	case Monobit:
+		return "Monobit"
+	case Poker:
+		return "Poker"
+	case Runs:
+		return "Runs"
+	case LongRun:
+		return "Long run"
+	case Continuous:
+		return "Continuous run"
+	default:
+		return fmt.Sprintf("Test(%d)", int(t))
+	}
+}
+
+// Result - the result of testing one block. The zero value is a pass.
+type Result uint8
+
+// Failed reports whether the given test failed
+func (r Result) Failed(t Test) bool {
+	return r&(1<<t) != 0
+}
+
+// Passed reports whether all tests passed
+func (r Result) Passed() bool {
+	return r == 0
+}
+
+func (r Result) String() string {
+	if r.Passed() {
+		return "pass"
+	}
+
+	failed := []string{}
+	for _, t := range Tests {
+		if r.Failed(t) {
+			failed = append(failed, t.String())
+		}
+	}
+
+	return "fail (" + strings.Join(failed, ", ") + ")"
+}
+
+// runIntervals - the allowed number of runs of lengths 1-5, and 6 or more,
+// for both ones and zeros (inclusive)
+var runIntervals = [6][2]int{
+	{2315, 2685},
+	{1114, 1386},
+	{527, 723},
+	{240, 384},
+	{103, 209},
+	{103, 209},
+}
+
+// longRun - the length of run that fails the long run test
+const longRun = 26
+
+// Tester runs the tests on consecutive blocks. The continuous run test
+// compares each block with the end of the previous one, so use the same
+// Tester for the whole stream. The zero value is ready to use.
+type Tester struct {
+	last   uint32
+	primed bool
+}
+
+// Test runs all tests on the given block, which must be BlockSize bytes.
+func (t *Tester) Test(block []byte) (Result, error) {
+	if len(block) != BlockSize {
+		return 0, fmt.Errorf("block must be %d bytes, not %d", BlockSize, len(block))
+	}
+
+	var r Result
+	if !monobit(block) {
+		r |= 1 << Monobit
+	}
+	if !poker(block) {
+		r |= 1 << Poker
+	}
+	runsOK, longRunOK := runs(block)
+	if !runsOK {
+		r |= 1 << Runs
+	}
+	if !longRunOK {
+		r |= 1 << LongRun
+	}
+	if !t.continuous(block) {
+		r |= 1 << Continuous
+	}
+
+	return r, nil
+}
+
+func monobit(block []byte) bool {
+	ones := 0
+	for _, b := range block {
+		for ; b != 0; b &= b - 1 {
+			ones++
+		}
+	}
+
+	return ones > 9725 && ones < 10275
+}
+
+func poker(block []byte) bool {
+	var f [16]int
+	for _, b := range block {
+		f[b>>4]++
+		f[b&0xf]++
+	}
+
+	sum := 0
+	for _, n := range f {
+		sum += n * n
+	}
+	x := 16.0/5000.0*float64(sum) - 5000
+
+	return x > 2.16 && x < 46.17
+}
+
+// runs performs the runs and long run tests, reading bits most significant
+// first
+func runs(block []byte) (runsOK, longRunOK bool) {
+	// counts of runs of each length, for zeros and ones
+	var counts [2][6]int
+	longRunOK = true
+
+	count := func(bit, length int) {
+		if length >= longRun {
+			longRunOK = false
+		}
+		counts[bit][min(length, 6)-1]++
+	}
+
+	bit, length := -1, 0
+	for _, b := range block {
+		for i := 7; i >= 0; i-- {
+			v := int(b>>i) & 1
+			if v == bit {
+				length++
+
+				continue
+			}
+			if length > 0 {
+				count(bit, length)
+			}
+			bit, length = v, 1
+		}
+	}
+	count(bit, length)
+
+	for _, c := range counts {
+		for i, n := range c {
+			if n < runIntervals[i][0] || n > runIntervals[i][1] {
+				return false, longRunOK
+			}
+		}
+	}
+
+	return true, longRunOK
+}
+
+// continuous fails if any 32-bit word is the same as the one before it
+func (t *Tester) continuous(block []byte) bool {
+	ok := true
+	for i := 0; i+4 <= len(block); i += 4 {
+		w := uint32(block[i])<<24 | uint32(block[i+1])<<16 | uint32(block[i+2])<<8 | uint32(block[i+3])
+		if t.primed && w == t.last {
+			ok = false
+		}
+		t.last, t.primed = w, true
+	}
+
+	return ok
+}
+
+// ErrFailed - too many blocks failed the tests
+var ErrFailed = errors.New("FIPS 140-2 tests failed")
+
+// Summary - the results of testing a number of blocks
+type Summary struct {
+	// TestFailures - the number of blocks that failed each test
+	TestFailures [numTests]int
+	// Blocks - the number of blocks tested
+	Blocks int
+	// Failures - the number of blocks that failed at least one test
+	Failures int
+}
+
+// Add a block's result to the summary
+func (s *Summary) Add(r Result) {
+	s.Blocks++
+	if r.Passed() {
+		return
+	}
+
+	s.Failures++
+	for _, t := range Tests {
+		if r.Failed(t) {
+			s.TestFailures[t]++
+		}
+	}
+}
+
+// Successes - the number of blocks that passed all tests
+func (s *Summary) Successes() int {
+	return s.Blocks - s.Failures
+}
+
+// Passed reports whether the source looks OK - i.e. no more blocks failed
+// than MaxFailures allows.
+func (s *Summary) Passed() bool {
+	return s.Blocks > 0 && s.Failures <= MaxFailures(s.Blocks)
+}
+
+// Err returns an error wrapping ErrFailed if the summary didn't pass, or nil
+func (s *Summary) Err() error {
+	if s.Passed() {
+		return nil
+	}
+
+	return fmt.Errorf("%w: %d of %d blocks failed (at most %d expected)",
+		ErrFailed, s.Failures, s.Blocks, MaxFailures(s.Blocks))
+}
+
+// expectedFailureRate - a (slightly pessimistic) rate at which a good source
+// fails at least one test
+const expectedFailureRate = 0.002
+
+// MaxFailures returns the largest number of failed blocks (out of n) that
+// can reasonably be expected from a good source. More failures than this
+// would happen by chance less than once in a million runs.
+func MaxFailures(n int) int {
+	// binomial distribution - find the smallest k where P(X > k) < 1e-6
+	return special.CritBinom(n, expectedFailureRate, 1-1e-6)
+}
+
+// Run tests the given number of blocks read from r. If onBlock is non-nil,
+// it's called with the result of each block.
+func Run(r io.Reader, blocks int, onBlock func(block int, res Result)) (*Summary, error) {
+	t := &Tester{}
+	s := &Summary{}
+	buf := make([]byte, BlockSize)
+
+	for i := 0; i < blocks; i++ {
+		if _, err := io.ReadFull(r, buf); err != nil {
+			return s, fmt.Errorf("failed to read block %d: %w", i, err)
+		}
+
+		res, err := t.Test(buf)
+		if err != nil {
+			return s, err
+		}
+		s.Add(res)
+
+		if onBlock != nil {
+			onBlock(i, res)
+		}
+	}
+
+	return s, nil
+}
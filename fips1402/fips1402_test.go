@@ -0,0 +1,203 @@
+package fips1402
+
+import (
+	"bytes"
+	"math/rand/v2"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// randomBlock returns a deterministic pseudo-random block which passes all
+// the tests
+func randomBlock(t *testing.T, seed byte) []byte {
+	t.Helper()
+
+	b := make([]byte, BlockSize)
+	_, err := rand.NewChaCha8([32]byte{seed}).Read(b)
+	require.NoError(t, err)
+
+	return b
+}
+
+func TestTester_Random(t *testing.T) {
+	tr := &Tester{}
+	for i := byte(0); i < 10; i++ {
+		r, err := tr.Test(randomBlock(t, i))
+		require.NoError(t, err)
+		assert.True(t, r.Passed(), r.String())
+		assert.Equal(t, "pass", r.String())
+	}
+}
+
+func TestTester_Zeros(t *testing.T) {
+	tr := &Tester{}
+	r, err := tr.Test(make([]byte, BlockSize))
+	require.NoError(t, err)
+	for _, test := range Tests {
+		assert.True(t, r.Failed(test), test)
+	}
+	assert.Equal(t, "fail (Monobit, Poker, Runs, Long run, Continuous run)", r.String())
+}
+
+func TestTester_WrongSize(t *testing.T) {
+	_, err := (&Tester{}).Test(make([]byte, 10))
+	assert.Error(t, err)
+}
+
+func TestMonobit(t *testing.T) {
+	// alternating bits are perfectly balanced...
+	b := bytes.Repeat([]byte{0x55}, BlockSize)
+	assert.True(t, monobit(b))
+
+	// ...until there are too many ones
+	for i := 0; i < 69; i++ {
+		b[i] = 0xff
+	}
+	assert.False(t, monobit(b))
+	b[68] = 0x55
+	assert.True(t, monobit(b))
+}
+
+func TestPoker(t *testing.T) {
+	assert.True(t, poker(randomBlock(t, 1)))
+
+	// every nibble value equally often is too good to be true
+	b := make([]byte, BlockSize)
+	for i := range b {
+		b[i] = byte(2*i%16)<<4 | byte((2*i+1)%16)
+	}
+	assert.False(t, poker(b))
+}
+
+func TestRuns(t *testing.T) {
+	ok, longOK := runs(randomBlock(t, 2))
+	assert.True(t, ok)
+	assert.True(t, longOK)
+
+	// a single long run of ones fails both
+	b := randomBlock(t, 2)
+	for i := 100; i < 104; i++ {
+		b[i] = 0xff
+	}
+	_, longOK = runs(b)
+	assert.False(t, longOK)
+
+	ok, longOK = runs(bytes.Repeat([]byte{0x55}, BlockSize))
+	assert.False(t, ok)
+	assert.True(t, longOK)
+}
+
+// runsBlock returns a block with the given number of runs of lengths 1-5, and
+// 6 or more, for both zeros and ones. The long runs share the bits left over.
+func runsBlock(t *testing.T, counts [6]int) []byte {
+	t.Helper()
+
+	lengths := []int{}
+	bits := BlockSize * 8 / 2
+	for i, c := range counts[:5] {
+		for range c {
+			lengths = append(lengths, i+1)
+			bits -= i + 1
+		}
+	}
+	for i := range counts[5] {
+		l := bits / counts[5]
+		if i < bits%counts[5] {
+			l++
+		}
+		require.GreaterOrEqual(t, l, 6)
+		lengths = append(lengths, l)
+	}
+
+	b := make([]byte, BlockSize)
+	pos := 0
+	for _, l := range lengths {
+		// a run of zeros, then a run of ones
+		pos += l
+		for range l {
+			b[pos/8] |= 0x80 >> (pos % 8)
+			pos++
+		}
+	}
+	require.Equal(t, BlockSize*8, pos)
+
+	return b
+}
+
+func TestRuns_Boundaries(t *testing.T) {
+	// the intervals are inclusive
+	ok, _ := runs(runsBlock(t, [6]int{2315, 1250, 625, 312, 156, 156}))
+	assert.True(t, ok)
+	ok, _ = runs(runsBlock(t, [6]int{2314, 1250, 625, 312, 156, 156}))
+	assert.False(t, ok)
+
+	ok, _ = runs(runsBlock(t, [6]int{2400, 1386, 527, 312, 156, 156}))
+	assert.True(t, ok)
+	ok, _ = runs(runsBlock(t, [6]int{2400, 1387, 527, 312, 156, 156}))
+	assert.False(t, ok)
+}
+
+func TestContinuous(t *testing.T) {
+	tr := &Tester{}
+	b := randomBlock(t, 3)
+	assert.True(t, tr.continuous(b))
+
+	// the first word of the next block repeats the last word of this one
+	next := randomBlock(t, 4)
+	copy(next, b[BlockSize-4:])
+	assert.False(t, tr.continuous(next))
+}
+
+func TestSummary(t *testing.T) {
+	s := &Summary{}
+	assert.False(t, s.Passed())
+
+	s.Add(0)
+	s.Add(1<<Runs | 1<<Poker)
+	s.Add(0)
+	assert.Equal(t, 3, s.Blocks)
+	assert.Equal(t, 1, s.Failures)
+	assert.Equal(t, 2, s.Successes())
+	assert.Equal(t, 1, s.TestFailures[Runs])
+	assert.Equal(t, 1, s.TestFailures[Poker])
+	assert.Equal(t, 0, s.TestFailures[Monobit])
+	assert.True(t, s.Passed())
+	assert.NoError(t, s.Err())
+
+	s.Add(1 << Monobit)
+	s.Add(1 << Monobit)
+	assert.False(t, s.Passed())
+	assert.ErrorIs(t, s.Err(), ErrFailed)
+}
+
+func TestMaxFailures(t *testing.T) {
+	assert.Equal(t, 1, MaxFailures(1))
+	assert.Equal(t, 2, MaxFailures(10))
+	assert.Equal(t, 5, MaxFailures(100))
+	assert.Equal(t, 12, MaxFailures(1000))
+}
+
+func TestRun(t *testing.T) {
+	data := append(randomBlock(t, 5), randomBlock(t, 6)...)
+	results := []Result{}
+	s, err := Run(bytes.NewReader(data), 2, func(i int, r Result) {
+		assert.Equal(t, len(results), i)
+		results = append(results, r)
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 2, s.Blocks)
+	assert.Len(t, results, 2)
+	assert.True(t, s.Passed())
+
+	// not enough data
+	s, err = Run(bytes.NewReader(data), 3, nil)
+	require.Error(t, err)
+	assert.Equal(t, 2, s.Blocks)
+}
+
+func TestTest_String(t *testing.T) {
+	assert.Equal(t, "Long run", LongRun.String())
+	assert.Equal(t, "Test(9)", Test(9).String())
+}
@@ -6,6 +6,7 @@ require (
 	github.com/spf13/cobra v1.8.0
 	github.com/stretchr/testify v1.8.4
 	golang.org/x/crypto v0.35.0
+	golang.org/x/sys v0.30.0
 )
 
 require (
@@ -0,0 +1,168 @@
+package onerng
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/hairyhenderson/go-onerng/internal/special"
+)
+
+// Continuous health tests, from NIST SP 800-90B section 4.4. Each byte read
+// from the device is treated as one sample.
+const (
+	// healthAlphaExp - the false positive rate of the health tests is
+	// 2^-healthAlphaExp per sample. 800-90B recommends between 2^-20 and
+	// 2^-40, but at 2^-20 even perfectly uniform data trips the repetition
+	// count test about once every 16 MB, which is far too often for a
+	// long-running reader. At 2^-40, a source that only just meets its
+	// claimed min-entropy fails about once every 2^40 samples - most of a
+	// year of continuous reading.
+	healthAlphaExp = 40
+	// aptWindow - the Adaptive Proportion Test window size for non-binary
+	// samples
+	aptWindow = 512
+)
+
+// minEntropy is the claimed min-entropy (in bits per byte) for each noise
+// mode. These are deliberately conservative - the raw (unwhitened) sources
+// are quite biased, and the RF source on its own is weak.
+var minEntropy = map[NoiseMode]float64{
+	Default:                     7,
+	DisableWhitener:             2,
+	EnableRF:                    7,
+	EnableRF | DisableWhitener:  2,
+	DisableAvalanche | EnableRF: 5,
+	DisableAvalanche | EnableRF | DisableWhitener: 1,
+}
+
+// DefaultMinEntropy returns the claimed min-entropy, in bits per byte, of the
+// data produced in the given noise mode. Modes that produce no data have no
+// entropy.
+func DefaultMinEntropy(mode NoiseMode) float64 {
+	return minEntropy[mode]
+}
+
+// HealthError - returned when a continuous health test fails, which indicates
+// that the noise source is probably broken. It matches ErrHealthTest with
+// errors.Is.
+type HealthError struct {
+	// Test - the name of the failed test
+	Test string
+	// Offset - the position in the stream of the sample that failed the test
+	Offset int64
+	// Count - the number of times Sample was seen (consecutively, for the
+	// repetition count test, or in the window for the adaptive proportion
+	// test)
+	Count int
+	// Cutoff - the count at which the test fails
+	Cutoff int
+	// Sample - the repeated sample value
+	Sample byte
+}
+
+func (e *HealthError) Error() string {
+	return fmt.Sprintf("%s test failed at offset %d: 0x%02x seen %d times (cutoff %d)",
+		e.Test, e.Offset, e.Sample, e.Count, e.Cutoff)
+}
+
+// Is reports whether target is ErrHealthTest
+func (e *HealthError) Is(target error) bool {
+	return target == ErrHealthTest
+}
+
+// HealthTest runs the SP 800-90B Repetition Count Test and Adaptive
+// Proportion Test continuously over a stream of samples. It is not safe for
+// concurrent use.
+type HealthTest struct {
+	// RCTCutoff - the Repetition Count Test fails when a sample is repeated
+	// this many times in a row
+	RCTCutoff int
+	// APTCutoff - the Adaptive Proportion Test fails when the first sample
+	// in a window of 512 occurs this many times in the window
+	APTCutoff int
+
+	offset int64
+
+	rctSample byte
+	rctCount  int
+
+	aptSample byte
+	aptCount  int
+	aptIndex  int
+}
+
+// NewHealthTest creates a HealthTest with cutoffs appropriate for the given
+// claimed min-entropy (in bits per byte), for a false positive rate of
+// 2^-40. As with WithMinEntropy, the claim must be more than 0 and at most 8
+// - anything else is invalid, and returns nil.
+func NewHealthTest(minEntropy float64) *HealthTest {
+	if !(minEntropy > 0 && minEntropy <= 8) {
+		return nil
+	}
+
+	return &HealthTest{
+		RCTCutoff: rctCutoff(minEntropy, healthAlphaExp),
+		APTCutoff: aptCutoff(minEntropy, healthAlphaExp),
+	}
+}
+
+// Check runs the tests over the next samples in the stream, returning a
+// *HealthError at the first failure. Once a test fails, the noise source
+// should be considered broken, and the data discarded.
+func (h *HealthTest) Check(p []byte) error {
+	for _, b := range p {
+		if err := h.check(b); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (h *HealthTest) check(b byte) error {
+	defer func() { h.offset++ }()
+
+	// Repetition Count Test (800-90B 4.4.1)
+	if h.offset > 0 && b == h.rctSample {
+		h.rctCount++
+		if h.rctCount >= h.RCTCutoff {
+			return &HealthError{
+				Test: "repetition count", Offset: h.offset,
+				Sample: b, Count: h.rctCount, Cutoff: h.RCTCutoff,
+			}
+		}
+	} else {
+		h.rctSample = b
+		h.rctCount = 1
+	}
+
+	// Adaptive Proportion Test (800-90B 4.4.2)
+	if h.aptIndex == 0 {
+		h.aptSample = b
+		h.aptCount = 1
+	} else if b == h.aptSample {
+		h.aptCount++
+		if h.aptCount >= h.APTCutoff {
+			return &HealthError{
+				Test: "adaptive proportion", Offset: h.offset,
+				Sample: b, Count: h.aptCount, Cutoff: h.APTCutoff,
+			}
+		}
+	}
+	h.aptIndex = (h.aptIndex + 1) % aptWindow
+
+	return nil
+}
+
+// rctCutoff computes C = 1 + ceil(-log2(alpha) / H), for alpha = 2^-alphaExp
+func rctCutoff(h, alphaExp float64) int {
+	return 1 + int(math.Ceil(alphaExp/h))
+}
+
+// aptCutoff computes C = 1 + CRITBINOM(W, 2^-H, 1 - alpha) - i.e. one more
+// than the smallest count which the number of occurrences of a sample with
+// probability 2^-H in a window of W samples exceeds with probability of at
+// most alpha = 2^-alphaExp
+func aptCutoff(h, alphaExp float64) int {
+	return 1 + special.CritBinom(aptWindow, math.Exp2(-h), 1-math.Exp2(-alphaExp))
+}
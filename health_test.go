@@ -0,0 +1,128 @@
+package onerng
+
+import (
+	"bytes"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHealthCutoffs(t *testing.T) {
+	// from SP 800-90B table 2, for W=512 and alpha=2^-20
+	testdata := []struct {
+		h        float64
+		rct, apt int
+	}{
+		{0.5, 41, 410},
+		{1, 21, 311},
+		{2, 11, 177},
+		{4, 6, 62},
+		{8, 4, 13},
+	}
+
+	for _, d := range testdata {
+		t.Run(fmt.Sprintf("%g", d.h), func(t *testing.T) {
+			assert.Equal(t, d.rct, rctCutoff(d.h, 20))
+			assert.Equal(t, d.apt, aptCutoff(d.h, 20))
+		})
+	}
+}
+
+func TestNewHealthTest(t *testing.T) {
+	// alpha=2^-40
+	testdata := []struct {
+		h        float64
+		rct, apt int
+	}{
+		{1, 41, 336},
+		{2, 21, 202},
+		{4, 11, 78},
+		{7, 7, 26},
+		{8, 6, 19},
+	}
+
+	for _, d := range testdata {
+		t.Run(fmt.Sprintf("%g", d.h), func(t *testing.T) {
+			h := NewHealthTest(d.h)
+			require.NotNil(t, h)
+			assert.Equal(t, d.rct, h.RCTCutoff)
+			assert.Equal(t, d.apt, h.APTCutoff)
+		})
+	}
+
+	assert.Nil(t, NewHealthTest(0))
+	assert.Nil(t, NewHealthTest(DefaultMinEntropy(Silent)))
+	// more than a byte can hold
+	assert.Nil(t, NewHealthTest(10))
+	assert.Nil(t, NewHealthTest(math.NaN()))
+}
+
+func TestHealthTest_Random(t *testing.T) {
+	h := NewHealthTest(DefaultMinEntropy(Default))
+	p := make([]byte, 1<<16)
+	_, err := rand.Read(p)
+	require.NoError(t, err)
+	assert.NoError(t, h.Check(p))
+
+	// runs like this turn up in uniformly random data every few megabytes,
+	// so they mustn't fail
+	assert.NoError(t, h.Check([]byte{1, 2, 3, 3, 3, 3, 3, 4}))
+}
+
+func TestHealthTest_RepetitionCount(t *testing.T) {
+	h := NewHealthTest(4)
+
+	// the repeats span calls to Check
+	require.NoError(t, h.Check([]byte{1, 2, 3, 3, 3}))
+	err := h.Check(append(bytes.Repeat([]byte{3}, 8), 4))
+
+	var herr *HealthError
+	require.True(t, errors.As(err, &herr), err)
+	assert.ErrorIs(t, err, ErrHealthTest)
+	assert.Equal(t, "repetition count", herr.Test)
+	assert.Equal(t, int64(12), herr.Offset)
+	assert.Equal(t, byte(3), herr.Sample)
+	assert.Equal(t, 11, herr.Count)
+	assert.Equal(t, 11, herr.Cutoff)
+	assert.Equal(t, "repetition count test failed at offset 12: 0x03 seen 11 times (cutoff 11)", err.Error())
+}
+
+func TestHealthTest_AdaptiveProportion(t *testing.T) {
+	h := NewHealthTest(4)
+
+	// every other sample is the same, so the RCT never fails
+	p := bytes.Repeat([]byte{0xaa, 0x00}, 100)
+	for i := range p {
+		if i%2 == 1 {
+			p[i] = byte(i)
+		}
+	}
+	err := h.Check(p)
+
+	var herr *HealthError
+	require.True(t, errors.As(err, &herr), err)
+	assert.Equal(t, "adaptive proportion", herr.Test)
+	assert.Equal(t, byte(0xaa), herr.Sample)
+	assert.Equal(t, 78, herr.Count)
+	assert.Equal(t, int64(154), herr.Offset)
+}
+
+func TestHealthTest_APTWindow(t *testing.T) {
+	h := NewHealthTest(4)
+
+	// fewer than the cutoff in each window, so it passes even though the
+	// total is much higher
+	p := make([]byte, aptWindow*4)
+	for i := range p {
+		p[i] = byte(i%255 + 1)
+		if i%aptWindow < 77*2 && i%2 == 0 {
+			p[i] = 0
+		}
+	}
+	assert.NoError(t, h.Check(p))
+}
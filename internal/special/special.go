@@ -0,0 +1,114 @@
+// Package special contains the special functions needed to compute p-values
+// for the statistical tests, and cutoffs for the health and self-tests.
+package special
+
+import "math"
+
+const (
+	epsilon = 1e-15
+	maxIter = 10000
+)
+
+// Igam - the regularized lower incomplete gamma function P(a, x)
+func Igam(a, x float64) float64 {
+	switch {
+	case x <= 0 || a <= 0:
+		return 0
+	case x > 1 && x > a:
+		return 1 - Igamc(a, x)
+	default:
+		return igamSeries(a, x)
+	}
+}
+
+// Igamc - the regularized upper incomplete gamma function Q(a, x) = 1 - P(a,
+// x), as igamc in the NIST statistical test suite
+func Igamc(a, x float64) float64 {
+	switch {
+	case x <= 0 || a <= 0:
+		return 1
+	case x < 1 || x < a:
+		return 1 - igamSeries(a, x)
+	default:
+		return igamcFraction(a, x)
+	}
+}
+
+// ChiSquareP - the probability of a chi-square statistic at least as large as
+// x, with the given degrees of freedom
+func ChiSquareP(x float64, df int) float64 {
+	return Igamc(float64(df)/2, x/2)
+}
+
+// CritBinom returns the smallest k for which the binomial cumulative
+// distribution P(X <= k), for n trials with success probability p, is at least
+// target - as CRITBINOM in spreadsheets
+func CritBinom(n int, p, target float64) int {
+	lnp, lnq := math.Log(p), math.Log1p(-p)
+	lgn, _ := math.Lgamma(float64(n + 1))
+
+	cdf := 0.0
+	for k := 0; k < n; k++ {
+		lgk, _ := math.Lgamma(float64(k + 1))
+		lgnk, _ := math.Lgamma(float64(n - k + 1))
+		cdf += math.Exp(lgn - lgk - lgnk + float64(k)*lnp + float64(n-k)*lnq)
+		if cdf >= target {
+			return k
+		}
+	}
+
+	return n
+}
+
+// prefix returns x^a e^-x / Gamma(a)
+func prefix(a, x float64) float64 {
+	lg, _ := math.Lgamma(a)
+
+	return math.Exp(a*math.Log(x) - x - lg)
+}
+
+// igamSeries computes P(a, x) with its power series
+func igamSeries(a, x float64) float64 {
+	r, c, sum := a, 1.0, 1.0
+	for range maxIter {
+		r++
+		c *= x / r
+		sum += c
+		if c <= epsilon*sum {
+			break
+		}
+	}
+
+	return prefix(a, x) * sum / a
+}
+
+// igamcFraction computes Q(a, x) with its continued fraction (modified
+// Lentz's method)
+func igamcFraction(a, x float64) float64 {
+	const tiny = 1e-300
+
+	b := x + 1 - a
+	c := 1 / tiny
+	d := 1 / b
+	h := d
+	for i := 1; i <= maxIter; i++ {
+		an := -float64(i) * (float64(i) - a)
+		b += 2
+		d = an*d + b
+		if math.Abs(d) < tiny {
+			d = tiny
+		}
+		c = b + an/c
+		if math.Abs(c) < tiny {
+			c = tiny
+		}
+		d = 1 / d
+		delta := d * c
+		h *= delta
+		if math.Abs(delta-1) < epsilon {
+			break
+		}
+	}
+
+	return prefix(a, x) * h
+}
@@ -0,0 +1,42 @@
+package special
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIgamc(t *testing.T) {
+	for _, x := range []float64{0.01, 0.5, 1, 2.5, 10, 50} {
+		// Q(1, x) = e^-x
+		assert.InDelta(t, math.Exp(-x), Igamc(1, x), 1e-12, x)
+		// Q(1/2, x) = erfc(sqrt(x))
+		assert.InDelta(t, math.Erfc(math.Sqrt(x)), Igamc(0.5, x), 1e-12, x)
+		assert.InDelta(t, 1, Igam(2, x)+Igamc(2, x), 1e-12, x)
+	}
+
+	assert.InDelta(t, 1.0, Igamc(3, 0), 0)
+	assert.InDelta(t, 0.0, Igam(3, 0), 0)
+
+	// from the examples in NIST SP 800-22 section 2.2.8
+	assert.InDelta(t, 0.801252, Igamc(1.5, 1.0/2), 1e-6)
+}
+
+func TestChiSquareP(t *testing.T) {
+	// the median of chi-square with 255 degrees of freedom is about 254.3
+	assert.InDelta(t, 0.5, ChiSquareP(254.33, 255), 0.001)
+	// critical value for 1 degree of freedom at 5%
+	assert.InDelta(t, 0.05, ChiSquareP(3.841, 1), 0.0001)
+}
+
+func TestCritBinom(t *testing.T) {
+	// P(X <= 4) = 0.377, P(X <= 5) = 0.623 for 10 fair coin tosses
+	assert.Equal(t, 5, CritBinom(10, 0.5, 0.5))
+	// P(X <= 7) = 0.945, P(X <= 8) = 0.989
+	assert.Equal(t, 8, CritBinom(10, 0.5, 0.95))
+	// the whole distribution is needed for a target of 1
+	assert.Equal(t, 10, CritBinom(10, 0.5, 1))
+	// SP 800-90B table 2 - W=512, H=8, alpha=2^-20 has a cutoff of 13
+	assert.Equal(t, 12, CritBinom(512, 1.0/256, 1-math.Exp2(-20)))
+}
@@ -0,0 +1,124 @@
+// Package termios contains the small subset of terminal ioctls needed to talk
+// to a OneRNG (or an emulated one) over a tty.
+package termios
+
+import (
+	"os"
+	"strconv"
+	"syscall"
+	"unsafe"
+)
+
+// CRTSCTS enables RTS/CTS (hardware) flow control - missing from syscall
+const CRTSCTS = 0x80000000
+
+// ioctl runs the given ioctl request against the file, without forcing the
+// file into blocking mode the way (*os.File).Fd would.
+func ioctl(f *os.File, req uint, arg unsafe.Pointer) error {
+	rc, err := f.SyscallConn()
+	if err != nil {
+		return err
+	}
+
+	var errno syscall.Errno
+	err = rc.Control(func(fd uintptr) {
+		_, _, errno = syscall.Syscall(syscall.SYS_IOCTL, fd, uintptr(req), uintptr(arg))
+	})
+	if err != nil {
+		return err
+	}
+	if errno != 0 {
+		return &os.SyscallError{Syscall: "ioctl", Err: errno}
+	}
+
+	return nil
+}
+
+// Get returns the current terminal attributes of the file.
+func Get(f *os.File) (*syscall.Termios, error) {
+	t := &syscall.Termios{}
+	if err := ioctl(f, syscall.TCGETS, unsafe.Pointer(t)); err != nil {
+		return nil, err
+	}
+
+	return t, nil
+}
+
+// Set applies the given terminal attributes to the file immediately.
+func Set(f *os.File, t *syscall.Termios) error {
+	return ioctl(f, syscall.TCSETS, unsafe.Pointer(t))
+}
+
+// tcsetsf sets the terminal attributes after discarding pending input -
+// missing from syscall, but it always follows TCSETS and TCSETSW
+const tcsetsf = syscall.TCSETS + 2
+
+// FlushInput discards any data that's been received by the tty, but not yet
+// read.
+func FlushInput(f *os.File) error {
+	t, err := Get(f)
+	if err != nil {
+		return err
+	}
+
+	return ioctl(f, tcsetsf, unsafe.Pointer(t))
+}
+
+// MakeRaw modifies t in the same way as cfmakeraw(3): no echo, no line
+// editing, no signal characters and no translation of CR/NL in either
+// direction, with 8-bit characters.
+func MakeRaw(t *syscall.Termios) {
+	t.Iflag &^= syscall.IGNBRK | syscall.BRKINT | syscall.PARMRK | syscall.ISTRIP |
+		syscall.INLCR | syscall.IGNCR | syscall.ICRNL | syscall.IXON
+	t.Oflag &^= syscall.OPOST
+	t.Lflag &^= syscall.ECHO | syscall.ECHONL | syscall.ICANON | syscall.ISIG | syscall.IEXTEN
+	t.Cflag &^= syscall.CSIZE | syscall.PARENB
+	t.Cflag |= syscall.CS8
+	t.Cc[syscall.VMIN] = 1
+	t.Cc[syscall.VTIME] = 0
+}
+
+// IsRaw reports whether t has the attributes set by MakeRaw.
+func IsRaw(t *syscall.Termios) bool {
+	return t.Lflag&(syscall.ECHO|syscall.ICANON|syscall.ISIG) == 0 &&
+		t.Oflag&syscall.OPOST == 0 &&
+		t.Iflag&(syscall.ICRNL|syscall.INLCR|syscall.IGNCR) == 0 &&
+		t.Cflag&syscall.CSIZE == syscall.CS8
+}
+
+// OpenPTY allocates a new pseudo-terminal pair, returning the master side and
+// the path to the slave device.
+func OpenPTY() (master *os.File, slave string, err error) {
+	master, err = os.OpenFile("/dev/ptmx", os.O_RDWR|syscall.O_NOCTTY, 0)
+	if err != nil {
+		return nil, "", err
+	}
+
+	var unlock int32
+	if err = ioctl(master, syscall.TIOCSPTLCK, unsafe.Pointer(&unlock)); err != nil {
+		_ = master.Close()
+
+		return nil, "", err
+	}
+
+	var n uint32
+	if err = ioctl(master, syscall.TIOCGPTN, unsafe.Pointer(&n)); err != nil {
+		_ = master.Close()
+
+		return nil, "", err
+	}
+
+	return master, "/dev/pts/" + strconv.FormatUint(uint64(n), 10), nil
+}
+
+// SetExclusive puts the tty into (or takes it out of) exclusive mode. While
+// in exclusive mode, further open(2) calls on the tty fail with EBUSY (except
+// for root).
+func SetExclusive(f *os.File, excl bool) error {
+	req := uint(syscall.TIOCNXCL)
+	if excl {
+		req = syscall.TIOCEXCL
+	}
+
+	return ioctl(f, req, nil)
+}
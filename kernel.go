@@ -0,0 +1,312 @@
+package onerng
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// DefaultProcDir - where the kernel's random number generator sysctls
+	// are
+	DefaultProcDir = "/proc/sys/kernel/random"
+	// DefaultRandomDevice - the device used to add entropy to the kernel's
+	// pool
+	DefaultRandomDevice = "/dev/random"
+
+	// DefaultFeedBytes - the default amount of conditioned data added to the
+	// kernel's pool at a time
+	DefaultFeedBytes = 64
+	// DefaultPollInterval - the default interval between checks of the
+	// kernel's pool
+	DefaultPollInterval = time.Second
+)
+
+// ErrNoEntropyCredited - data couldn't be added to the kernel's pool because
+// none of it would be credited with entropy
+var ErrNoEntropyCredited = errors.New("no entropy credited")
+
+// EntropyPool - the kernel's entropy pool. See KernelPool.
+type EntropyPool interface {
+	// EntropyAvail returns the amount of entropy in the pool, in bits
+	EntropyAvail() (int, error)
+	// WakeupThreshold returns the level (in bits) below which the pool
+	// wants more entropy
+	WakeupThreshold() (int, error)
+	// AddEntropy mixes data into the pool, crediting it with the given
+	// number of bits of entropy
+	AddEntropy(data []byte, bits int) error
+}
+
+// KernelPool - the Linux kernel's entropy pool, read from procfs and added to
+// with the RNDADDENTROPY ioctl (which needs CAP_SYS_ADMIN)
+type KernelPool struct {
+	// ProcDir - where the random sysctls are - defaults to DefaultProcDir
+	ProcDir string
+	// Device - the random device - defaults to DefaultRandomDevice
+	Device string
+}
+
+var _ EntropyPool = (*KernelPool)(nil)
+
+// EntropyAvail returns the kernel's entropy_avail
+func (k *KernelPool) EntropyAvail() (int, error) {
+	return k.readInt("entropy_avail")
+}
+
+// WakeupThreshold returns the kernel's write_wakeup_threshold
+func (k *KernelPool) WakeupThreshold() (int, error) {
+	return k.readInt("write_wakeup_threshold")
+}
+
+// AddEntropy adds data to the kernel's pool with the RNDADDENTROPY ioctl,
+// crediting it with the given number of bits. This is only supported on
+// Linux.
+func (k *KernelPool) AddEntropy(data []byte, bits int) error {
+	dev := k.Device
+	if dev == "" {
+		dev = DefaultRandomDevice
+	}
+
+	f, err := os.OpenFile(dev, os.O_WRONLY, 0)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	err = addEntropy(f, data, bits)
+	if err != nil {
+		return fmt.Errorf("failed to add entropy with %s: %w", dev, err)
+	}
+
+	return nil
+}
+
+func (k *KernelPool) readInt(name string) (int, error) {
+	dir := k.ProcDir
+	if dir == "" {
+		dir = DefaultProcDir
+	}
+
+	b, err := os.ReadFile(filepath.Join(dir, name))
+	if err != nil {
+		return 0, err
+	}
+
+	n, err := strconv.Atoi(strings.TrimSpace(string(b)))
+	if err != nil {
+		return 0, fmt.Errorf("failed to parse %s: %w", name, err)
+	}
+
+	return n, nil
+}
+
+// FeedPolicy - configures FeedKernel
+type FeedPolicy struct {
+	// Pool - the entropy pool to feed - defaults to a KernelPool
+	Pool EntropyPool
+	// Conditioner - conditions the data before it's added to the pool
+	// (required)
+	Conditioner Conditioner
+	// Accountant - credits the data read, and decides how much entropy the
+	// conditioned data is credited with - defaults to the device's claimed
+	// min-entropy (see NewAccountant)
+	Accountant *Accountant
+	// OnFeed is called after each addition to the pool. Optional.
+	OnFeed func(Feed)
+	// Bytes - the amount of conditioned data to add at a time - defaults to
+	// DefaultFeedBytes
+	Bytes int
+	// CreditBits - the number of bits of entropy to credit for each
+	// addition. It's capped at what the Accountant credits the data with,
+	// which is also the default.
+	CreditBits int
+	// PollInterval - how often to check whether the pool needs more entropy
+	// - defaults to DefaultPollInterval
+	PollInterval time.Duration
+}
+
+// Feed - describes one addition to the kernel's entropy pool
+type Feed struct {
+	// Bytes - the number of bytes added
+	Bytes int
+	// CreditedBits - the number of bits of entropy credited
+	CreditedBits int
+	// EntropyAvail - the pool's entropy before the addition, in bits
+	EntropyAvail int
+	// WakeupThreshold - the pool's wakeup threshold, in bits
+	WakeupThreshold int
+}
+
+// FeedKernel keeps the kernel's entropy pool topped up with data from the
+// device, like rngd. Whenever the pool holds less entropy than its wakeup
+// threshold, data is read in the given mode (and health-tested), conditioned,
+// and added to the pool until it's back above the threshold.
+//
+// The data comes from one Session, open for as long as FeedKernel runs, so
+// the health tests see all of it, and other operations wait until FeedKernel
+// returns. It runs until the context is cancelled, or an error occurs.
+func (o *OneRNG) FeedKernel(ctx context.Context, mode NoiseMode, p *FeedPolicy) error {
+	if p.Conditioner == nil {
+		return errors.New("no conditioner given")
+	}
+
+	pool := p.Pool
+	if pool == nil {
+		pool = &KernelPool{}
+	}
+
+	a := p.Accountant
+	if a == nil {
+		a = o.NewAccountant()
+	}
+
+	s, err := o.Open(ctx, mode)
+	if err != nil {
+		return err
+	}
+	defer s.Close()
+
+	t := time.NewTicker(p.pollInterval())
+	defer t.Stop()
+
+	for {
+		err := o.topUp(ctx, s, pool, a, p)
+		if err != nil {
+			return err
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-t.C:
+		}
+	}
+}
+
+// topUp feeds the pool until it's no longer below its wakeup threshold
+func (o *OneRNG) topUp(ctx context.Context, s *Session, pool EntropyPool, a *Accountant, p *FeedPolicy) error {
+	for {
+		avail, err := pool.EntropyAvail()
+		if err != nil {
+			return fmt.Errorf("failed to read the pool's entropy: %w", err)
+		}
+		threshold, err := pool.WakeupThreshold()
+		if err != nil {
+			return fmt.Errorf("failed to read the pool's wakeup threshold: %w", err)
+		}
+
+		if avail >= threshold {
+			return nil
+		}
+
+		data, credit, err := s.conditioned(a, p.Conditioner, p.bytes())
+		if err != nil {
+			return err
+		}
+
+		if p.CreditBits > 0 {
+			credit = min(credit, p.CreditBits)
+		}
+		if credit <= 0 {
+			mode := s.Mode()
+
+			return fmt.Errorf("%w: %d bytes conditioned in noise mode %d (%s)", ErrNoEntropyCredited, len(data), mode, mode)
+		}
+
+		err = pool.AddEntropy(data, credit)
+		clear(data)
+		if err != nil {
+			return err
+		}
+
+		o.log().DebugContext(ctx, "fed the entropy pool", "bytes", len(data), "credited", credit, "entropy_avail", avail)
+		if p.OnFeed != nil {
+			p.OnFeed(Feed{Bytes: len(data), CreditedBits: credit, EntropyAvail: avail, WakeupThreshold: threshold})
+		}
+	}
+}
+
+// conditioned reads from the session through the conditioner until n bytes of
+// output are available, and returns them with the number of (whole) bits of
+// entropy they're credited with. Only the input read here is credited - the
+// conditioner may have read more than it needed, and output beyond n bytes is
+// thrown away, so what's left of the balance is written off before and after.
+func (s *Session) conditioned(a *Accountant, c Conditioner, n int) ([]byte, int, error) {
+	a.writeOff()
+	defer a.writeOff()
+
+	out := &bytes.Buffer{}
+	before := a.Stats().OutputCreditedBits
+	lw := &limitedBuffer{b: out, n: n}
+	ow := a.Output(lw, c.Ratio())
+	w := c.Writer(ow)
+
+	// the conditioner may produce less than its nominal ratio, so keep
+	// reading until there's enough
+	for out.Len() < n {
+		need := int64(math.Ceil(float64(n-out.Len()) / c.Ratio()))
+
+		_, err := io.CopyN(a.Writer(w, s.Mode()), s, need)
+		if err != nil && !errors.Is(err, errBufferFull) {
+			return nil, 0, err
+		}
+	}
+
+	err := w.Close()
+	if err == nil {
+		err = ow.Close()
+	}
+	if err != nil && !errors.Is(err, errBufferFull) {
+		return nil, 0, err
+	}
+
+	credit := int(math.Floor(a.Stats().OutputCreditedBits - before))
+
+	return out.Bytes(), credit, nil
+}
+
+// errBufferFull - returned by limitedBuffer when it's full
+var errBufferFull = errors.New("buffer full")
+
+// limitedBuffer writes up to n bytes into b, and then fails with
+// errBufferFull
+type limitedBuffer struct {
+	b *bytes.Buffer
+	n int
+}
+
+func (l *limitedBuffer) Write(p []byte) (int, error) {
+	room := l.n - l.b.Len()
+	if len(p) > room {
+		l.b.Write(p[:room])
+
+		return room, errBufferFull
+	}
+
+	return l.b.Write(p)
+}
+
+func (p *FeedPolicy) bytes() int {
+	if p.Bytes <= 0 {
+		return DefaultFeedBytes
+	}
+
+	return p.Bytes
+}
+
+func (p *FeedPolicy) pollInterval() time.Duration {
+	if p.PollInterval <= 0 {
+		return DefaultPollInterval
+	}
+
+	return p.PollInterval
+}
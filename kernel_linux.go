@@ -0,0 +1,38 @@
+package onerng
+
+import (
+	"encoding/binary"
+	"os"
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+)
+
+// addEntropy adds data to the kernel's pool through f (the random device),
+// crediting it with the given number of bits
+func addEntropy(f *os.File, data []byte, bits int) error {
+	// struct rand_pool_info { int entropy_count; int buf_size; __u32 buf[]; }
+	info := make([]byte, 8+len(data))
+	binary.NativeEndian.PutUint32(info[0:], uint32(bits))
+	binary.NativeEndian.PutUint32(info[4:], uint32(len(data)))
+	copy(info[8:], data)
+	defer clear(info)
+
+	rc, err := f.SyscallConn()
+	if err != nil {
+		return err
+	}
+
+	var errno unix.Errno
+	err = rc.Control(func(fd uintptr) {
+		_, _, errno = unix.Syscall(unix.SYS_IOCTL, fd, unix.RNDADDENTROPY, uintptr(unsafe.Pointer(&info[0])))
+	})
+	if err != nil {
+		return err
+	}
+	if errno != 0 {
+		return &os.SyscallError{Syscall: "ioctl", Err: errno}
+	}
+
+	return nil
+}
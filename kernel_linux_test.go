@@ -0,0 +1,38 @@
+package onerng
+
+import (
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestKernelPool(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "entropy_avail"), []byte("123\n"), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "write_wakeup_threshold"), []byte("bogus\n"), 0o644))
+
+	dev := filepath.Join(dir, "random")
+	require.NoError(t, os.WriteFile(dev, nil, 0o644))
+
+	k := &KernelPool{ProcDir: dir, Device: dev}
+	n, err := k.EntropyAvail()
+	require.NoError(t, err)
+	assert.Equal(t, 123, n)
+
+	_, err = k.WakeupThreshold()
+	require.Error(t, err)
+
+	// a regular file doesn't support the ioctl
+	err = k.AddEntropy([]byte("data"), 32)
+	require.ErrorIs(t, err, syscall.ENOTTY)
+
+	k = &KernelPool{ProcDir: filepath.Join(dir, "missing"), Device: filepath.Join(dir, "missing")}
+	_, err = k.EntropyAvail()
+	require.ErrorIs(t, err, os.ErrNotExist)
+	err = k.AddEntropy([]byte("data"), 32)
+	require.ErrorIs(t, err, os.ErrNotExist)
+}
@@ -0,0 +1,13 @@
+//go:build !linux
+
+package onerng
+
+import (
+	"errors"
+	"os"
+)
+
+// addEntropy is only supported on Linux
+func addEntropy(_ *os.File, _ []byte, _ int) error {
+	return errors.ErrUnsupported
+}
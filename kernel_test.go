@@ -0,0 +1,175 @@
+package onerng
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakePool - an entropy pool which counts what's credited to it
+type fakePool struct {
+	err       error
+	added     [][]byte
+	avail     int
+	threshold int
+}
+
+func (p *fakePool) EntropyAvail() (int, error)    { return p.avail, p.err }
+func (p *fakePool) WakeupThreshold() (int, error) { return p.threshold, nil }
+
+func (p *fakePool) AddEntropy(data []byte, bits int) error {
+	p.added = append(p.added, append([]byte{}, data...))
+	p.avail += bits
+
+	return nil
+}
+
+func TestFeedKernel(t *testing.T) {
+	o := New("/dev/null", WithDialer(randomDialer()))
+	c, err := newSHA256Compressor()
+	require.NoError(t, err)
+	pool := &fakePool{avail: 100, threshold: 256}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	feeds := []Feed{}
+	err = o.FeedKernel(ctx, Default, &FeedPolicy{
+		Pool:         pool,
+		Conditioner:  c,
+		Bytes:        32,
+		PollInterval: time.Millisecond,
+		OnFeed: func(f Feed) {
+			feeds = append(feeds, f)
+			cancel()
+		},
+	})
+	require.ErrorIs(t, err, context.Canceled)
+
+	// 64 bytes at 7 bits per byte, hashed to 32, is full entropy
+	require.Len(t, feeds, 1)
+	assert.Equal(t, Feed{Bytes: 32, CreditedBits: 256, EntropyAvail: 100, WakeupThreshold: 256}, feeds[0])
+	assert.Equal(t, 356, pool.avail)
+	require.Len(t, pool.added, 1)
+	assert.Len(t, pool.added[0], 32)
+}
+
+func TestFeedKernel_CreditBits(t *testing.T) {
+	o := New("/dev/null", WithDialer(randomDialer()))
+	pool := &fakePool{avail: 0, threshold: 256}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// the same session is used for every feed
+	dials := 0
+	d := randomDialer()
+	o.Dialer = DialerFunc(func(ctx context.Context, addr string) (Transport, error) {
+		dials++
+
+		return d.Dial(ctx, addr)
+	})
+
+	a := o.NewAccountant()
+	feeds := 0
+	err := o.FeedKernel(ctx, DisableWhitener, &FeedPolicy{
+		Pool:         pool,
+		Conditioner:  passthrough{},
+		Accountant:   a,
+		CreditBits:   100,
+		PollInterval: time.Millisecond,
+		OnFeed: func(f Feed) {
+			feeds++
+			// 64 bytes at 2 bits per byte
+			assert.Equal(t, 100, f.CreditedBits)
+			if pool.avail >= pool.threshold {
+				cancel()
+			}
+		},
+	})
+	require.ErrorIs(t, err, context.Canceled)
+
+	assert.Equal(t, 3, feeds)
+	assert.Equal(t, 1, dials)
+	assert.Equal(t, 300, pool.avail)
+	assert.Equal(t, int64(3*64), a.Stats().OutputBytes)
+}
+
+func TestFeedKernel_Errors(t *testing.T) {
+	o := New("/dev/null", WithDialer(randomDialer()))
+	ctx := context.Background()
+
+	err := o.FeedKernel(ctx, Default, &FeedPolicy{Pool: &fakePool{}})
+	require.Error(t, err)
+
+	// silent mode has no entropy to credit
+	err = o.FeedKernel(ctx, Silent, &FeedPolicy{
+		Pool:        &fakePool{threshold: 256},
+		Conditioner: passthrough{},
+	})
+	require.ErrorIs(t, err, ErrNoEntropyCredited)
+
+	poolErr := errors.New("no procfs")
+	err = o.FeedKernel(ctx, Default, &FeedPolicy{
+		Pool:        &fakePool{err: poolErr},
+		Conditioner: passthrough{},
+	})
+	require.ErrorIs(t, err, poolErr)
+
+	o = New("/dev/null", WithDialer(DialerFunc(func(context.Context, string) (Transport, error) {
+		return newFakeDev("short"), nil
+	})))
+	err = o.FeedKernel(ctx, Default, &FeedPolicy{
+		Pool:        &fakePool{threshold: 256},
+		Conditioner: passthrough{},
+	})
+	require.Error(t, err)
+}
+
+// overReader - passes data through, but claims to compress it, so twice as
+// much is read as is needed
+type overReader struct{ passthrough }
+
+func (overReader) Ratio() float64 { return 0.5 }
+
+func TestFeedKernel_OverRead(t *testing.T) {
+	o := New("/dev/null", WithDialer(randomDialer()))
+	pool := &fakePool{threshold: 1 << 20}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// the entropy behind the output that's thrown away mustn't be credited
+	// to later output - with the estimate lowered after the first feed, the
+	// second is only credited with its own input
+	a := o.NewAccountant()
+	feeds := []Feed{}
+	err := o.FeedKernel(ctx, Default, &FeedPolicy{
+		Pool:        pool,
+		Conditioner: overReader{},
+		Accountant:  a,
+		OnFeed: func(f Feed) {
+			feeds = append(feeds, f)
+			a.SetEstimate(Default, 1)
+			if len(feeds) == 2 {
+				cancel()
+			}
+		},
+	})
+	require.ErrorIs(t, err, context.Canceled)
+
+	require.Len(t, feeds, 2)
+	assert.Equal(t, 8*64, feeds[0].CreditedBits)
+	assert.Equal(t, 2*64, feeds[1].CreditedBits)
+
+	total := 0
+	for _, f := range feeds {
+		total += f.CreditedBits
+	}
+	assert.LessOrEqual(t, total, 8*len(feeds)*64)
+	assert.Zero(t, a.Stats().Balance())
+}
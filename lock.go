@@ -0,0 +1,89 @@
+package onerng
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"syscall"
+	"time"
+)
+
+// DefaultLockDir - the default directory for UUCP-style lock files
+const DefaultLockDir = "/var/lock"
+
+// lockRetryInterval - how often to retry while waiting for a lock
+const lockRetryInterval = 100 * time.Millisecond
+
+// LockError - returned when the device is locked by another process
+type LockError struct {
+	// Path to the device
+	Path string
+	// LockFile is the UUCP lock file, if one was used
+	LockFile string
+	// PID of the process holding the lock, or 0 if it couldn't be determined
+	PID int
+}
+
+func (e *LockError) Error() string {
+	if e.PID == 0 {
+		return fmt.Sprintf("device %s is locked by another process", e.Path)
+	}
+
+	return fmt.Sprintf("device %s is locked by process %d", e.Path, e.PID)
+}
+
+// Is reports whether target is ErrDeviceBusy
+func (e *LockError) Is(target error) bool {
+	return target == ErrDeviceBusy
+}
+
+// openLocked opens the device and takes an exclusive lock on it, waiting up to
+// timeout for another process to release it. See tryLock for details.
+func openLocked(ctx context.Context, path, lockDir string, timeout time.Duration) (f *os.File, clearExcl, unlock func() error, err error) {
+	if lockDir == "" {
+		lockDir = DefaultLockDir
+	}
+
+	deadline := time.Now().Add(timeout)
+	for {
+		f, clearExcl, unlock, err = tryOpenLocked(path, lockDir)
+
+		var lerr *LockError
+		if !errors.As(err, &lerr) || time.Now().After(deadline) {
+			return f, clearExcl, unlock, err
+		}
+
+		t := time.NewTimer(lockRetryInterval)
+		select {
+		case <-ctx.Done():
+			t.Stop()
+
+			return nil, nil, nil, ctx.Err()
+		case <-t.C:
+		}
+	}
+}
+
+// tryOpenLocked opens the device and locks it. When another process holds
+// the lock, the tty is in exclusive mode, so unprivileged processes can't even
+// open it - that's reported as a *LockError too, naming the holder if it can
+// be found.
+func tryOpenLocked(path, lockDir string) (f *os.File, clearExcl, unlock func() error, err error) {
+	f, err = os.OpenFile(path, os.O_RDWR, 0o600)
+	if errors.Is(err, syscall.EBUSY) {
+		return nil, nil, nil, lockHolder(path, lockDir)
+	}
+	if err != nil {
+		return nil, nil, nil, classifyOpenErr(err)
+	}
+
+	clearExcl, unlock, err = tryLock(f, path, lockDir)
+	if err != nil {
+		_ = f.Close()
+
+		return nil, nil, nil, err
+	}
+
+	return f, clearExcl, unlock, nil
+}
@@ -0,0 +1,199 @@
+package onerng
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+
+	"github.com/hairyhenderson/go-onerng/internal/termios"
+	"golang.org/x/sys/unix"
+)
+
+// tryLock attempts to take an exclusive lock on the open device. A UUCP-style
+// lock file is used when the lock directory is writable, otherwise flock(2)
+// is used on the device itself. Once the lock is held, the tty is also put
+// into exclusive mode (TIOCEXCL), so that further open(2) calls by
+// unprivileged processes fail outright with EBUSY.
+//
+// A *LockError is returned when another process holds the lock.
+//
+// Exclusive mode must be cleared with clearExcl before the device is closed,
+// and unlock must be called after.
+func tryLock(f *os.File, path, lockDir string) (clearExcl, unlock func() error, err error) {
+	unlock, err = lockFile(path, lockDir)
+	if errors.Is(err, errLockDirUnusable) {
+		unlock, err = flock(f, path)
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	err = termios.SetExclusive(f, true)
+	if isNotTTY(err) {
+		return func() error { return nil }, unlock, nil
+	}
+	if err != nil {
+		_ = unlock()
+
+		return nil, nil, fmt.Errorf("failed to set exclusive mode on %s: %w", path, err)
+	}
+
+	return func() error { return termios.SetExclusive(f, false) }, unlock, nil
+}
+
+// lockHolder returns a *LockError for a device that's locked by another
+// process, naming that process if it can be found - from the UUCP lock file
+// if there is one, or else from its flock
+func lockHolder(path, lockDir string) *LockError {
+	lockPath := filepath.Join(lockDir, lockFileName(path))
+	if pid := readLockPID(lockPath); pid > 0 && processExists(pid) {
+		return &LockError{Path: path, LockFile: lockPath, PID: pid}
+	}
+
+	return &LockError{Path: path, PID: flockHolder(path)}
+}
+
+var errLockDirUnusable = errors.New("lock directory unusable")
+
+// lockFileName returns the UUCP lock file name for the device - i.e.
+// LCK..ttyACM0 for /dev/ttyACM0, or LCK..pts_3 for /dev/pts/3
+func lockFileName(path string) string {
+	name := strings.TrimPrefix(filepath.Clean(path), "/dev/")
+
+	return "LCK.." + strings.ReplaceAll(name, "/", "_")
+}
+
+// lockFile takes a UUCP-style lock, as used by minicom, screen, etc...
+func lockFile(path, lockDir string) (unlock func() error, err error) {
+	lockPath := filepath.Join(lockDir, lockFileName(path))
+
+	// write the PID to a temp file first, then hard-link it into place, so the
+	// lock file never exists without a PID in it
+	tmp, err := os.CreateTemp(lockDir, ".onerng-lock-")
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", errLockDirUnusable, err)
+	}
+	defer os.Remove(tmp.Name())
+
+	_, err = fmt.Fprintf(tmp, "%10d\n", os.Getpid())
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", errLockDirUnusable, err)
+	}
+
+	// allow one retry, after removing a stale lock
+	for range 2 {
+		err = os.Link(tmp.Name(), lockPath)
+		if err == nil {
+			return func() error { return os.Remove(lockPath) }, nil
+		}
+		if !errors.Is(err, os.ErrExist) {
+			return nil, fmt.Errorf("%w: %w", errLockDirUnusable, err)
+		}
+
+		pid := readLockPID(lockPath)
+		if pid > 0 && processExists(pid) {
+			return nil, &LockError{Path: path, LockFile: lockPath, PID: pid}
+		}
+
+		// stale lock - the process that created it is gone
+		if err := os.Remove(lockPath); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return nil, fmt.Errorf("failed to remove stale lock file %s: %w", lockPath, err)
+		}
+	}
+
+	return nil, &LockError{Path: path, LockFile: lockPath}
+}
+
+// readLockPID reads the PID from a lock file. Both the ASCII and the old
+// binary (4-byte int) formats are understood. Returns 0 if unreadable.
+func readLockPID(lockPath string) int {
+	b, err := os.ReadFile(lockPath)
+	if err != nil {
+		return 0
+	}
+
+	if pid, err := strconv.Atoi(strings.TrimSpace(string(b))); err == nil {
+		return pid
+	}
+	if len(b) == 4 {
+		return int(b[0]) | int(b[1])<<8 | int(b[2])<<16 | int(b[3])<<24
+	}
+
+	return 0
+}
+
+func processExists(pid int) bool {
+	err := syscall.Kill(pid, 0)
+
+	return err == nil || errors.Is(err, syscall.EPERM)
+}
+
+// flock takes an advisory exclusive lock on the device itself
+func flock(f *os.File, path string) (unlock func() error, err error) {
+	rc, err := f.SyscallConn()
+	if err != nil {
+		return nil, err
+	}
+
+	var lerr error
+	err = rc.Control(func(fd uintptr) {
+		lerr = syscall.Flock(int(fd), syscall.LOCK_EX|syscall.LOCK_NB)
+	})
+	if err != nil {
+		return nil, err
+	}
+	if errors.Is(lerr, syscall.EWOULDBLOCK) {
+		return nil, &LockError{Path: path, PID: flockHolder(path)}
+	}
+	if lerr != nil {
+		return nil, fmt.Errorf("flock %s: %w", path, lerr)
+	}
+
+	// the lock is released when the file is closed
+	return func() error { return nil }, nil
+}
+
+// flockHolder finds the PID of the process holding a flock on the file, by
+// looking it up in /proc/locks. Returns 0 if it can't be found.
+func flockHolder(path string) int {
+	fi, err := os.Stat(path)
+	if err != nil {
+		return 0
+	}
+	st, ok := fi.Sys().(*syscall.Stat_t)
+	if !ok {
+		return 0
+	}
+
+	locks, err := os.Open("/proc/locks")
+	if err != nil {
+		return 0
+	}
+	defer locks.Close()
+
+	// lines look like: "1: FLOCK  ADVISORY  WRITE 1234 00:19:7 0 EOF", where
+	// the device major/minor are in hex
+	dev := uint64(st.Dev) //nolint:unconvert // Dev is a uint32 on some platforms
+	want := fmt.Sprintf("%02x:%02x:%d", unix.Major(dev), unix.Minor(dev), st.Ino)
+
+	s := bufio.NewScanner(locks)
+	for s.Scan() {
+		fields := strings.Fields(s.Text())
+		if len(fields) < 6 || fields[1] != "FLOCK" || fields[5] != want {
+			continue
+		}
+		if pid, err := strconv.Atoi(fields[4]); err == nil {
+			return pid
+		}
+	}
+
+	return 0
+}
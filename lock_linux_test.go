@@ -0,0 +1,175 @@
+package onerng
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/hairyhenderson/go-onerng/emulator"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLockFileName(t *testing.T) {
+	assert.Equal(t, "LCK..ttyACM0", lockFileName("/dev/ttyACM0"))
+	assert.Equal(t, "LCK..pts_3", lockFileName("/dev/pts/3"))
+	assert.Equal(t, "LCK..ttyACM0", lockFileName("/dev//ttyACM0"))
+}
+
+func TestReadLockPID(t *testing.T) {
+	dir := t.TempDir()
+	p := filepath.Join(dir, "LCK..ttyACM0")
+
+	assert.Equal(t, 0, readLockPID(p))
+
+	require.NoError(t, os.WriteFile(p, []byte("      1234\n"), 0o644))
+	assert.Equal(t, 1234, readLockPID(p))
+
+	require.NoError(t, os.WriteFile(p, []byte{0xd2, 0x04, 0, 0}, 0o644))
+	assert.Equal(t, 1234, readLockPID(p))
+
+	require.NoError(t, os.WriteFile(p, []byte("garbage"), 0o644))
+	assert.Equal(t, 0, readLockPID(p))
+}
+
+func startLockTestDevice(t *testing.T) string {
+	t.Helper()
+	d := &emulator.Device{}
+	require.NoError(t, d.Start())
+	t.Cleanup(func() { _ = d.Close() })
+
+	return d.Path()
+}
+
+func TestFileDialer_LockFile(t *testing.T) {
+	path := startLockTestDevice(t)
+	lockDir := t.TempDir()
+	lockPath := filepath.Join(lockDir, lockFileName(path))
+	ctx := context.Background()
+
+	tr, err := FileDialer{LockDir: lockDir}.Dial(ctx, path)
+	require.NoError(t, err)
+	assert.Equal(t, os.Getpid(), readLockPID(lockPath))
+
+	// a second open must fail, naming the process holding the lock
+	_, err = FileDialer{LockDir: lockDir}.Dial(ctx, path)
+	var lerr *LockError
+	require.ErrorAs(t, err, &lerr)
+	assert.Equal(t, os.Getpid(), lerr.PID)
+	assert.Equal(t, lockPath, lerr.LockFile)
+	assert.Contains(t, err.Error(), fmt.Sprintf("locked by process %d", os.Getpid()))
+
+	require.NoError(t, tr.Close())
+	assert.NoFileExists(t, lockPath)
+
+	tr, err = FileDialer{LockDir: lockDir}.Dial(ctx, path)
+	require.NoError(t, err)
+	require.NoError(t, tr.Close())
+}
+
+func TestFileDialer_StaleLockFile(t *testing.T) {
+	path := startLockTestDevice(t)
+	lockDir := t.TempDir()
+	lockPath := filepath.Join(lockDir, lockFileName(path))
+
+	// PIDs can't go this high, so the lock is definitely stale
+	require.NoError(t, os.WriteFile(lockPath, []byte("1999999999\n"), 0o644))
+
+	tr, err := FileDialer{LockDir: lockDir}.Dial(context.Background(), path)
+	require.NoError(t, err)
+	assert.Equal(t, os.Getpid(), readLockPID(lockPath))
+	require.NoError(t, tr.Close())
+}
+
+func TestFileDialer_Flock(t *testing.T) {
+	path := startLockTestDevice(t)
+	// a missing lock directory forces the flock fallback
+	lockDir := filepath.Join(t.TempDir(), "missing")
+	ctx := context.Background()
+
+	tr, err := FileDialer{LockDir: lockDir}.Dial(ctx, path)
+	require.NoError(t, err)
+
+	_, err = FileDialer{LockDir: lockDir}.Dial(ctx, path)
+	var lerr *LockError
+	require.ErrorAs(t, err, &lerr)
+	assert.Empty(t, lerr.LockFile)
+	assert.Equal(t, os.Getpid(), lerr.PID)
+
+	require.NoError(t, tr.Close())
+
+	tr, err = FileDialer{LockDir: lockDir}.Dial(ctx, path)
+	require.NoError(t, err)
+	require.NoError(t, tr.Close())
+}
+
+func TestFileDialer_LockTimeout(t *testing.T) {
+	path := startLockTestDevice(t)
+	lockDir := t.TempDir()
+	ctx := context.Background()
+
+	tr, err := FileDialer{LockDir: lockDir}.Dial(ctx, path)
+	require.NoError(t, err)
+
+	go func() {
+		time.Sleep(200 * time.Millisecond)
+		_ = tr.Close()
+	}()
+
+	tr2, err := FileDialer{LockDir: lockDir, LockTimeout: 5 * time.Second}.Dial(ctx, path)
+	require.NoError(t, err)
+	require.NoError(t, tr2.Close())
+
+	// with a timeout that's too short, the lock error is returned
+	tr3, err := FileDialer{LockDir: lockDir}.Dial(ctx, path)
+	require.NoError(t, err)
+	defer tr3.Close()
+
+	_, err = FileDialer{LockDir: lockDir, LockTimeout: 150 * time.Millisecond}.Dial(ctx, path)
+	var lerr *LockError
+	assert.ErrorAs(t, err, &lerr)
+
+	// and cancelling the context stops the wait
+	cctx, cancel := context.WithTimeout(ctx, 150*time.Millisecond)
+	defer cancel()
+	_, err = FileDialer{LockDir: lockDir, LockTimeout: time.Minute}.Dial(cctx, path)
+	assert.True(t, errors.Is(err, context.DeadlineExceeded), err)
+}
+
+func TestFileDialer_Exclusive(t *testing.T) {
+	if os.Geteuid() == 0 {
+		t.Skip("root can open a tty in exclusive mode, so this must run unprivileged")
+	}
+
+	path := startLockTestDevice(t)
+	lockDir := t.TempDir()
+	ctx := context.Background()
+
+	tr, err := FileDialer{LockDir: lockDir}.Dial(ctx, path)
+	require.NoError(t, err)
+
+	// the tty is in exclusive mode, so it can't even be opened...
+	_, err = os.OpenFile(path, os.O_RDWR, 0)
+	require.ErrorIs(t, err, syscall.EBUSY)
+
+	// ...but that's reported as a lock, naming the holder
+	_, err = FileDialer{LockDir: lockDir}.Dial(ctx, path)
+	var lerr *LockError
+	require.ErrorAs(t, err, &lerr)
+	assert.Equal(t, os.Getpid(), lerr.PID)
+
+	// and waited for, like any other lock
+	go func() {
+		time.Sleep(200 * time.Millisecond)
+		_ = tr.Close()
+	}()
+
+	tr, err = FileDialer{LockDir: lockDir, LockTimeout: 5 * time.Second}.Dial(ctx, path)
+	require.NoError(t, err)
+	require.NoError(t, tr.Close())
+}
@@ -0,0 +1,17 @@
+//go:build !linux
+
+package onerng
+
+import "os"
+
+// tryLock is only supported on Linux - elsewhere no lock is taken
+func tryLock(_ *os.File, _, _ string) (clearExcl, unlock func() error, err error) {
+	noop := func() error { return nil }
+
+	return noop, noop, nil
+}
+
+// lockHolder can't find the process holding the lock except on Linux
+func lockHolder(path, _ string) *LockError {
+	return &LockError{Path: path}
+}
@@ -26,379 +26,391 @@ Reading data from the OneRNG can be done with the Read function:
 	if err != nil {
 		return err
 	}
+
+For repeated reads, open a Session instead - it keeps the device running,
+and implements io.Reader:
+
+	s, err := o.Open(ctx, Default)
+	if err != nil {
+		return err
+	}
+	defer s.Close()
+	_, err = io.ReadFull(s, buf)
+
+By default the device node at Path is opened directly. To talk to a OneRNG
+some other way (for example through a TCP serial bridge), set a Dialer:
+
+	o := &OneRNG{
+		Path: "rng-host:2000",
+		Dialer: DialerFunc(func(ctx context.Context, addr string) (Transport, error) {
+			var d net.Dialer
+			return d.DialContext(ctx, "tcp", addr)
+		}),
+	}
 */
 package onerng
 
 import (
-	"bufio"
 	"bytes"
 	"context"
 	"crypto/aes"
 	"crypto/cipher"
+	"errors"
 	"fmt"
 	"io"
+	"log/slog"
 	mrand "math/rand"
 	"os"
 	"strconv"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 )
 
 // OneRNG - a OneRNG device
+//
+// All methods are safe for concurrent use - operations are serialized, since
+// the device can only do one thing at a time. A OneRNG must not be copied
+// after first use.
 type OneRNG struct {
-	device io.ReadWriteCloser
-	Path   string
+	// Dialer opens the connection to the device - if nil, a FileDialer is used
+	Dialer Dialer
+	// Reconnect enables automatic reconnection during Read, when set
+	Reconnect *ReconnectPolicy
+	// Path to the device - passed to the Dialer
+	Path string
+
+	logger *slog.Logger
+
+	// relocated is where the device was found after reconnecting, if it's
+	// not at Path any more
+	relocated string
+	pathMu    sync.Mutex
+
+	// tunables - see the corresponding options. Those where zero is valid
+	// are pointers, nil when not set.
+	readTimeout     time.Duration
+	initTimeout     time.Duration
+	warmup          *int64
+	allowedTimeouts *int
+	initAttempts    int
+	imageEndZeros   int
+	minEntropy      map[NoiseMode]float64
+	skipHealthTests bool
+	// optionErr is the first invalid option given to New
+	optionErr error
+
+	// ready is set once the device has passed the start-up tests (see Init)
+	ready atomic.Bool
+
+	// sem is held for the duration of each operation
+	sem     chan struct{}
+	semOnce sync.Once
 }
 
-const copyReadTimeout = 500 * time.Millisecond
-
-// cmd sends one or more commands to the OneRNG. The device is not closed on
-// completion, as it's usually being read from simultaneously.
-func (o *OneRNG) cmd(ctx context.Context, c ...string) error {
-	err := o.open()
-	if err != nil {
-		return err
-	}
-	for _, v := range c {
-		_, err = o.device.Write([]byte(v))
-		if err != nil {
-			return fmt.Errorf("errored on command %q: %w", v, err)
-		}
-		select {
-		case <-ctx.Done():
-			return nil
-		default:
-		}
+// acquire exclusive use of the device, waiting until any other operation is
+// complete or the context is cancelled
+func (o *OneRNG) acquire(ctx context.Context) error {
+	if o.optionErr != nil {
+		return o.optionErr
 	}
 
-	return nil
-}
+	o.semOnce.Do(func() {
+		o.sem = make(chan struct{}, 1)
+	})
 
-// open the OneRNG device for read/write, if it hasn't already been opened.
-// Access it as o.device.
-func (o *OneRNG) open() (err error) {
-	if o.device != nil {
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case o.sem <- struct{}{}:
 		return nil
 	}
-	o.device, err = os.OpenFile(o.Path, os.O_RDWR, 0o600)
+}
 
-	return err
+func (o *OneRNG) release() {
+	<-o.sem
 }
 
-// close the OneRNG device if it hasn't already been closed
-func (o *OneRNG) close() error {
-	if o.device == nil {
-		return nil
+// devicePath returns the current path to the device
+func (o *OneRNG) devicePath() string {
+	o.pathMu.Lock()
+	defer o.pathMu.Unlock()
+
+	if o.relocated != "" {
+		return o.relocated
 	}
-	err := o.device.Close()
-	o.device = nil
 
-	return err
+	return o.Path
 }
 
-// Version - query the OneRNG for its hardware version
-func (o *OneRNG) Version(ctx context.Context) (int, error) {
-	err := o.open()
-	if err != nil {
-		return 0, err
+func (o *OneRNG) setDevicePath(path string) {
+	o.pathMu.Lock()
+	defer o.pathMu.Unlock()
+
+	if path == o.Path {
+		path = ""
+	}
+	o.relocated = path
+}
+
+// open a new connection to the device
+func (o *OneRNG) open(ctx context.Context) (*conn, error) {
+	d := o.Dialer
+	if d == nil {
+		d = FileDialer{}
 	}
-	defer o.close()
 
-	err = o.cmd(ctx, cmdPause)
+	t, err := d.Dial(ctx, o.devicePath())
 	if err != nil {
-		return 0, err
+		return nil, err
 	}
 
-	ctx, cancel := context.WithCancel(ctx)
-	defer cancel()
-	buf := make(chan string)
-	errc := make(chan error, 1)
-	go o.scan(ctx, buf, errc)
+	return newConn(t, o.getReadTimeout()), nil
+}
+
+// do runs fn with exclusive use of a newly-opened connection to the device.
+// The connection is closed (and its reader goroutine stopped) before do
+// returns.
+func (o *OneRNG) do(ctx context.Context, fn func(c *conn) error) error {
+	if err := o.acquire(ctx); err != nil {
+		return err
+	}
+	defer o.release()
 
-	err = o.cmd(ctx, noiseCommand(Silent), cmdVersion, cmdRun)
+	c, err := o.open(ctx)
 	if err != nil {
-		return 0, err
+		return err
+	}
+
+	err = fn(c)
+	if cerr := c.close(); err == nil {
+		err = cerr
 	}
 
-	verString := ""
-loop:
+	return err
+}
+
+// waitLine waits for a line containing the given prefix, and returns the line
+// from the prefix onwards. Binary noise that was still in flight when the
+// command was sent can end up on the same line as the response, so the
+// prefix may not be at the start.
+func (c *conn) waitLine(ctx context.Context, lines <-chan string, prefix string) (string, error) {
 	for {
-		var b string
 		select {
 		case <-ctx.Done():
-			return 0, ctx.Err()
-		case b = <-buf:
-			if strings.HasPrefix(b, "Version ") {
-				verString = b
-				cancel()
-
-				break loop
+			return "", ctx.Err()
+		case <-c.done:
+			return "", c.err
+		case l := <-lines:
+			if i := strings.Index(l, prefix); i >= 0 {
+				return l[i:], nil
 			}
-		case err = <-errc:
-			return 0, err
 		}
 	}
+}
 
-	err = o.cmd(ctx, cmdPause)
-	if err != nil {
-		return 0, err
-	}
+// Version - query the OneRNG for its hardware version
+func (o *OneRNG) Version(ctx context.Context) (version int, err error) {
+	err = o.do(ctx, func(c *conn) error {
+		err := c.cmd(ctx, cmdPause)
+		if err != nil {
+			return err
+		}
+
+		lines, cancel := c.subscribeLines(ctx)
+		defer cancel()
+
+		err = c.cmd(ctx, noiseCommand(Silent), cmdVersion, cmdRun)
+		if err != nil {
+			return err
+		}
 
-	n := strings.Replace(verString, "Version ", "", 1)
-	version, err := strconv.Atoi(n)
+		verString, err := c.waitLine(ctx, lines, "Version ")
+		if err != nil {
+			return err
+		}
+
+		err = c.cmd(ctx, cmdPause)
+		if err != nil {
+			return err
+		}
+
+		n := strings.Replace(verString, "Version ", "", 1)
+		version, err = strconv.Atoi(n)
+		if err != nil {
+			return fmt.Errorf("%w: unexpected version response %q: %w", ErrNotOneRNG, verString, err)
+		}
+
+		return nil
+	})
 
 	return version, err
 }
 
 // Identify - query the OneRNG for its ID
-func (o *OneRNG) Identify(ctx context.Context) (string, error) {
-	err := o.open()
-	if err != nil {
-		return "", err
-	}
-	defer o.close()
+func (o *OneRNG) Identify(ctx context.Context) (id string, err error) {
+	err = o.do(ctx, func(c *conn) error {
+		lines, cancel := c.subscribeLines(ctx)
+		defer cancel()
 
-	_, cancel := context.WithCancel(ctx)
-	defer cancel()
-	buf := make(chan string)
-	errc := make(chan error, 1)
-	go o.scan(ctx, buf, errc)
-
-	err = o.cmd(ctx, noiseCommand(Silent), cmdID, cmdRun)
-	if err != nil {
-		return "", err
-	}
-
-	idString := ""
-loop:
-	for {
-		var b string
-		select {
-		case <-ctx.Done():
-			return "", ctx.Err()
-		case b = <-buf:
-			if strings.HasPrefix(b, "___") {
-				idString = b
-				cancel()
+		err := c.cmd(ctx, noiseCommand(Silent), cmdID, cmdRun)
+		if err != nil {
+			return err
+		}
 
-				break loop
-			}
-		case err = <-errc:
-			return "", err
+		id, err = c.waitLine(ctx, lines, "___")
+		if err != nil {
+			return err
 		}
-	}
 
-	err = o.cmd(ctx, cmdPause)
-	if err != nil {
-		return "", err
-	}
+		return c.cmd(ctx, cmdPause)
+	})
 
-	return idString, err
+	return id, err
 }
 
 // Flush the OneRNG's entropy pool
 func (o *OneRNG) Flush(ctx context.Context) error {
-	err := o.open()
-	if err != nil {
-		return err
-	}
-	defer o.close()
-
-	_, cancel := context.WithCancel(ctx)
-	defer cancel()
-
-	return o.cmd(ctx, cmdFlush)
+	return o.do(ctx, func(c *conn) error {
+		return c.cmd(ctx, cmdFlush)
+	})
 }
 
 // Image extracts the firmware image. This image is padded with random data to
 // either 128Kb or 256Kb (depending on hardware), and signed.
 //
 // See also the Verify function.
-//
-//nolint:gocyclo
-func (o *OneRNG) Image(ctx context.Context) ([]byte, error) {
-	err := o.open()
-	if err != nil {
-		return nil, err
-	}
-	defer o.close()
+func (o *OneRNG) Image(ctx context.Context) (image []byte, err error) {
+	err = o.do(ctx, func(c *conn) error {
+		err := c.cmd(ctx, cmdPause, noiseCommand(Silent))
+		if err != nil {
+			return err
+		}
 
-	err = o.cmd(ctx, cmdPause, noiseCommand(Silent))
-	if err != nil {
-		return nil, err
-	}
+		r := c.subscribeData(ctx)
+		defer r.Close()
+
+		err = c.cmd(ctx, noiseCommand(Silent), cmdImage, cmdRun)
+		if err != nil {
+			return err
+		}
 
-	ctx, cancel := context.WithCancel(ctx)
-	defer cancel()
-	buf := make(chan []byte)
-	errc := make(chan error, 1)
-	go o.stream(ctx, 4, buf, errc)
+		image, err = readImage(r, o.getImageEndZeros())
+		if err != nil {
+			return err
+		}
 
-	err = o.cmd(ctx, noiseCommand(Silent), cmdImage, cmdRun)
+		return c.cmd(ctx, cmdPause)
+	})
 	if err != nil {
 		return nil, err
 	}
 
+	return image, nil
+}
+
+// readImage reads until the image is done - the end is marked by more than
+// endZeros consecutive zeroes
+func readImage(r io.Reader, endZeros int) ([]byte, error) {
 	image := []byte{}
+	b := make([]byte, readBufSize)
 	zeros := 0
-	// stream data until we're done, or until we have 200+ consecutive zeroes
-	for zeros <= 200 {
-		var b []byte
-		select {
-		case <-ctx.Done():
-			return nil, ctx.Err()
-		case b = <-buf:
-			image = append(image, b...)
-			// count consecutive zeroes - if we hit non-zero, reset
-			for _, v := range b {
-				if v == 0 {
-					zeros++
-				} else {
-					zeros = 0
-				}
-			}
-		case err = <-errc:
+	for {
+		n, err := r.Read(b)
+		if err != nil {
 			return nil, err
 		}
-	}
 
-	err = o.cmd(ctx, cmdPause)
-	if err != nil {
-		return nil, err
-	}
+		// count consecutive zeroes - if we hit non-zero, reset
+		for i, v := range b[:n] {
+			if v != 0 {
+				zeros = 0
 
-	return image, err
-}
+				continue
+			}
 
-// Init - wait for the device to finish initializing and start returning data
-func (o *OneRNG) Init(ctx context.Context) error {
-	i := 0
-	for ; i < 200; i++ {
-		n, err := o.readData(ctx)
-		if err != nil {
-			return err
-		}
-		if n > 0 {
-			break
+			zeros++
+			if zeros > endZeros {
+				return append(image, b[:i+1]...), nil
+			}
 		}
+		image = append(image, b[:n]...)
 	}
-	// fmt.Fprintf(os.Stderr, "Initialized after %d loops\n", i)
+}
+
+// Warmup discards some data from the device (see WithWarmup), in the given
+// mode. This gives the noise sources time to settle. Init already does this,
+// so it's only needed when switching to a different mode afterwards.
+func (o *OneRNG) Warmup(ctx context.Context, flags NoiseMode) error {
+	n, err := o.Read(ctx, io.Discard, o.getWarmup(), flags)
+	o.log().DebugContext(ctx, "warmup done", "discarded", n)
 
-	return nil
+	return err
 }
 
 // Read n bytes of data from the OneRNG into the given Writer. Set flags to
 // configure the OneRNG's. Set n to -1 to continuously read until an error is
 // encountered, or the context is cancelled.
 //
-// The OneRNG device will be closed when the operation completes.
+// The data is continuously health-tested (see HealthTest and WithMinEntropy)
+// unless disabled with WithHealthTests. If a test fails, Read stops without
+// writing the failed data, and returns a *HealthError.
+//
+// The OneRNG device will be closed when the operation completes. To read
+// repeatedly, it's more efficient to Open a Session.
+//
+// If the device disconnects during the read, and o.Reconnect is set, Read
+// waits for it to reconnect and continues. See ReconnectPolicy.
 func (o *OneRNG) Read(ctx context.Context, out io.Writer, n int64, flags NoiseMode) (written int64, err error) {
-	err = o.open()
-	if err != nil {
-		return 0, err
+	if o.Reconnect != nil {
+		return o.readReconnecting(ctx, out, n, flags)
 	}
-	defer o.close()
 
-	err = o.cmd(ctx, noiseCommand(flags), cmdRun)
+	return o.readOnce(ctx, out, n, flags)
+}
+
+func (o *OneRNG) readOnce(ctx context.Context, out io.Writer, n int64, flags NoiseMode) (written int64, err error) {
+	s, err := o.Open(ctx, flags)
 	if err != nil {
 		return 0, err
 	}
 
-	//nolint:errcheck
-	defer o.cmd(ctx, cmdPause)
-
-	written, err = copyWithContext(ctx, out, o.device, n)
+	written, err = copyWithContext(ctx, out, s.r, n, o.getReadTimeout(), o.getAllowedTimeouts(), s.health)
+	if errors.Is(err, ErrHealthTest) {
+		o.ready.Store(false)
+	}
+	if n >= 0 && written < n && errors.Is(err, io.EOF) {
+		err = fmt.Errorf("%w: wanted %db, read %db: %w", ErrShortRead, n, written, err)
+	}
+	if cerr := s.Close(); err == nil {
+		err = cerr
+	}
 
 	return written, err
 }
 
 // readData - try to read some data from the RNG (during initialization)
-func (o *OneRNG) readData(ctx context.Context) (int, error) {
-	err := o.open()
-	if err != nil {
-		return 0, err
-	}
-	defer o.close()
-
-	const readTimeout = 50 * time.Millisecond
-	_, cancel := context.WithTimeout(ctx, readTimeout)
-	defer cancel()
+func (c *conn) readData(ctx context.Context, readTimeout time.Duration) (int, error) {
+	r := c.subscribeData(ctx)
+	defer r.Close()
 
-	buf := make(chan []byte)
-	errc := make(chan error, 1)
-	go o.stream(ctx, 1, buf, errc)
-
-	err = o.cmd(ctx, noiseCommand(Default), cmdRun)
+	err := c.cmd(ctx, noiseCommand(Default), cmdRun)
 	if err != nil {
 		return 0, err
 	}
 
 	// make sure we always end with a pause/silence/flush
 	//nolint:errcheck
-	defer o.cmd(ctx, cmdPause, noiseCommand(Silent), cmdFlush)
+	defer c.cmd(context.WithoutCancel(ctx), cmdPause, noiseCommand(Silent), cmdFlush)
 
-	// blocking read from the channel, with a timeout (from context)
-	select {
-	case <-ctx.Done():
-		return 0, ctx.Err()
-	case b := <-buf:
-		return len(b), nil
-	case err := <-errc:
-		return 0, err
+	_ = r.SetReadDeadline(time.Now().Add(readTimeout))
+	n, err := r.Read(make([]byte, 1))
+	if os.IsTimeout(err) {
+		return 0, nil
 	}
-}
 
-// stream from a file into a channel until an error is encountered, the channel
-// is closed, or the context is cancelled.
-func (o *OneRNG) stream(ctx context.Context, bs int, buf chan []byte, errc chan error) {
-	err := o.open()
-	if err != nil {
-		errc <- err
-
-		return
-	}
-
-	defer close(buf)
-	defer close(errc)
-	for {
-		b := make([]byte, bs)
-		n, err := io.ReadAtLeast(o.device, b, len(b))
-		if err != nil {
-			errc <- err
-
-			return
-		}
-		if n < len(b) {
-			errc <- fmt.Errorf("unexpected short read: wanted %db, read %db", len(b), n)
-
-			return
-		}
-
-		select {
-		case <-ctx.Done():
-			return
-		case buf <- b:
-		}
-	}
-}
-
-func (o *OneRNG) scan(ctx context.Context, buf chan string, errc chan error) {
-	err := o.open()
-	if err != nil {
-		errc <- err
-
-		return
-	}
-	defer close(buf)
-	defer close(errc)
-	scanner := bufio.NewScanner(o.device)
-	for scanner.Scan() {
-		select {
-		case <-ctx.Done():
-			return
-		case buf <- scanner.Text():
-		}
-	}
+	return n, err
 }
 
 const (
@@ -421,9 +433,21 @@ const (
 // purposes (i.e. rngd), so this can be used to further mangle that data in non-
 // predictable ways.
 //
-// This uses AES-128.
+// This uses AES-128. See also NewConditioner, for other options.
 func (o *OneRNG) AESWhitener(ctx context.Context, out io.Writer) (io.WriteCloser, error) {
-	k, err := o.key(ctx)
+	stream, err := o.aesCFBStream(ctx)
+	if err != nil {
+		return nil, err
+	}
+	s := &cipher.StreamWriter{S: stream, W: out}
+
+	return s, nil
+}
+
+// aesCFBStream - the AES-128-CFB keystream used by AESWhitener
+func (o *OneRNG) aesCFBStream(ctx context.Context) (cipher.Stream, error) {
+	// 16 bytes == AES-128
+	k, err := o.key(ctx, aes.BlockSize)
 	if err != nil {
 		return nil, err
 	}
@@ -443,30 +467,14 @@ func (o *OneRNG) AESWhitener(ctx context.Context, out io.Writer) (io.WriteCloser
 	}
 
 	//nolint:staticcheck // not ready to change the algorithm yet
-	stream := cipher.NewCFBEncrypter(block, iv)
-	s := &cipher.StreamWriter{S: stream, W: out}
-
-	return s, nil
+	return cipher.NewCFBEncrypter(block, iv), nil
 }
 
-func (o *OneRNG) key(ctx context.Context) ([]byte, error) {
-	err := o.open()
-	if err != nil {
-		return []byte{}, err
-	}
-
+// key reads n bytes of key material from the device
+func (o *OneRNG) key(ctx context.Context, n int) ([]byte, error) {
 	buf := &bytes.Buffer{}
 
-	err = o.cmd(ctx, noiseCommand(Default), cmdRun)
-	if err != nil {
-		return []byte{}, err
-	}
-
-	//nolint:errcheck
-	defer o.cmd(ctx, cmdPause)
-
-	// 16 bytes == AES-128
-	_, err = copyWithContext(ctx, buf, o.device, aes.BlockSize)
+	_, err := o.Read(ctx, buf, int64(n), Default)
 	k := buf.Bytes()
 
 	return k, err
@@ -500,15 +508,29 @@ type readerFunc func(p []byte) (n int, err error)
 
 func (rf readerFunc) Read(p []byte) (n int, err error) { return rf(p) }
 
-// io.CopyN/io.Copy with cancellation support
-func copyWithContext(ctx context.Context, dst io.Writer, src io.Reader, n int64) (int64, error) {
-	// allow 10 500ms timeouts, for a total of 5s. After this, it's probably worth just giving up
-	allowedTimeouts := 10
+type writerFunc func(p []byte) (n int, err error)
+
+func (wf writerFunc) Write(p []byte) (n int, err error) { return wf(p) }
+
+// io.CopyN/io.Copy with cancellation support. Each read waits up to
+// readTimeout, and allowedTimeouts timeouts are tolerated - by default 10
+// 500ms timeouts, for a total of 5s. After this, it's probably worth just
+// giving up.
+//
+// If health is non-nil, everything read is health-tested before being
+// written, and the copy stops at the first failure without writing the
+// failed chunk.
+//
+//nolint:gocyclo
+func copyWithContext(ctx context.Context, dst io.Writer, src io.Reader, n int64,
+	readTimeout time.Duration, allowedTimeouts int, health *HealthTest,
+) (int64, error) {
+	timeouts := allowedTimeouts
 
 	rf := func(p []byte) (int, error) {
-		if f, ok := src.(*os.File); ok {
+		if d, ok := src.(ReadDeadliner); ok {
 			// I don't want reads to block forever, but I also don't want to time out immediately
-			err := f.SetReadDeadline(time.Now().Add(copyReadTimeout))
+			err := d.SetReadDeadline(time.Now().Add(readTimeout))
 			if err != nil {
 				return 0, err
 			}
@@ -519,12 +541,19 @@ func copyWithContext(ctx context.Context, dst io.Writer, src io.Reader, n int64)
 			return 0, ctx.Err()
 		default:
 			n, err := src.Read(p)
-			if allowedTimeouts > 0 {
-				if err != nil && os.IsTimeout(err) {
-					allowedTimeouts--
+			if health != nil && n > 0 {
+				if herr := health.Check(p[:n]); herr != nil {
+					return 0, herr
+				}
+			}
+			if err != nil && os.IsTimeout(err) {
+				if timeouts > 0 {
+					timeouts--
 
 					return n, nil
 				}
+
+				return n, &timeoutError{err: err}
 			}
 
 			return n, err
@@ -0,0 +1,176 @@
+package onerng
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"fmt"
+	"io"
+	"os"
+	"runtime"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/hairyhenderson/go-onerng/emulator"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func startEmulator(t *testing.T, d *emulator.Device) *OneRNG {
+	t.Helper()
+	require.NoError(t, d.Start())
+	t.Cleanup(func() { _ = d.Close() })
+
+	return &OneRNG{Path: d.Path()}
+}
+
+func TestVersion_Emulated(t *testing.T) {
+	o := startEmulator(t, &emulator.Device{Version: 3})
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	v, err := o.Version(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 3, v)
+}
+
+func TestIdentify_Emulated(t *testing.T) {
+	o := startEmulator(t, &emulator.Device{ID: "0123456789"})
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	id, err := o.Identify(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, "___0123456789___", id)
+}
+
+func TestImage_Emulated(t *testing.T) {
+	img := make([]byte, 4096)
+	_, err := rand.Read(img)
+	require.NoError(t, err)
+	// make sure there's no run of zeroes long enough to end the image early
+	for i := range img {
+		img[i] |= 1
+	}
+
+	d := &emulator.Device{Image: img}
+	o := startEmulator(t, d)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	out, err := o.Image(ctx)
+	require.NoError(t, err)
+	require.Greater(t, len(out), len(img))
+	assert.Equal(t, img, out[:len(img)])
+	assert.Equal(t, make([]byte, len(out)-len(img)), out[len(img):])
+	assert.Contains(t, d.Commands(), "cmdX")
+}
+
+func TestRead_Emulated(t *testing.T) {
+	d := &emulator.Device{}
+	o := startEmulator(t, d)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	out := &bytes.Buffer{}
+	n, err := o.Read(ctx, out, 1000, Default)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1000), n)
+	assert.Equal(t, 1000, out.Len())
+
+	// the device is paused again once the read is done
+	assert.Eventually(t, func() bool { return !d.Running() }, time.Second, 10*time.Millisecond)
+	assert.Equal(t, []string{"cmd0", "cmdO", "cmdo"}, d.Commands())
+}
+
+func TestRead_EmulatedTimeout(t *testing.T) {
+	// a silent device never sends anything, so the read should give up after
+	// the allowed number of read timeouts
+	d := &emulator.Device{}
+	require.NoError(t, d.Start())
+	t.Cleanup(func() { _ = d.Close() })
+
+	o := New(d.Path(), WithReadTimeout(20*time.Millisecond), WithAllowedTimeouts(5))
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	start := time.Now()
+	n, err := o.Read(ctx, &bytes.Buffer{}, 10, Silent)
+	require.Error(t, err)
+	assert.True(t, os.IsTimeout(err), err)
+	assert.ErrorIs(t, err, ErrTimeout)
+	assert.Zero(t, n)
+	assert.GreaterOrEqual(t, time.Since(start), 100*time.Millisecond)
+	assert.Less(t, time.Since(start), 2*time.Second)
+}
+
+func TestConcurrentUse_Emulated(t *testing.T) {
+	d := &emulator.Device{Version: 3, ID: "concurrent"}
+	o := startEmulator(t, d)
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Second)
+	defer cancel()
+
+	// make sure the emulator's own goroutines are counted in the baseline
+	_, err := o.Version(ctx)
+	require.NoError(t, err)
+	baseline := runtime.NumGoroutine()
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 30)
+	for range 10 {
+		wg.Add(3)
+		go func() {
+			defer wg.Done()
+			v, err := o.Version(ctx)
+			if err == nil && v != 3 {
+				err = fmt.Errorf("wrong version %d", v)
+			}
+			errs <- err
+		}()
+		go func() {
+			defer wg.Done()
+			id, err := o.Identify(ctx)
+			if err == nil && id != "___concurrent___" {
+				err = fmt.Errorf("wrong id %q", id)
+			}
+			errs <- err
+		}()
+		go func() {
+			defer wg.Done()
+			n, err := o.Read(ctx, io.Discard, 512, Default)
+			if err == nil && n != 512 {
+				err = fmt.Errorf("short read %d", n)
+			}
+			errs <- err
+		}()
+	}
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		assert.NoError(t, err)
+	}
+
+	// no readers are left behind
+	assert.LessOrEqual(t, runtime.NumGoroutine(), baseline)
+}
+
+func TestRead_EmulatedHealthFailure(t *testing.T) {
+	// a stuck source fails the health tests, and nothing is written
+	d := &emulator.Device{Source: bytes.NewReader(bytes.Repeat([]byte{0x42}, 4096))}
+	o := startEmulator(t, d)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	out := &bytes.Buffer{}
+	n, err := o.Read(ctx, out, 1024, Default)
+	assert.ErrorIs(t, err, ErrHealthTest)
+	assert.Zero(t, n)
+	assert.Zero(t, out.Len())
+
+	var herr *HealthError
+	require.ErrorAs(t, err, &herr)
+	assert.Equal(t, "repetition count", herr.Test)
+	assert.Equal(t, byte(0x42), herr.Sample)
+}
@@ -3,9 +3,14 @@ package onerng
 import (
 	"bytes"
 	"context"
+	"io"
+	"os"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestNoiseCommand(t *testing.T) {
@@ -28,87 +33,195 @@ func TestNoiseCommand(t *testing.T) {
 	}
 }
 
+// fakeDev is an in-memory device. Like the real thing, nothing can be read
+// until it's been told to run (with cmdO), after which the contents of rbuf
+// are returned.
 type fakeDev struct {
-	rbuf   *bytes.Buffer
-	wbuf   *bytes.Buffer
-	closed bool
+	rbuf    *bytes.Buffer
+	wbuf    *bytes.Buffer
+	mu      sync.Mutex
+	cond    *sync.Cond
+	running bool
+	closed  bool
 }
 
-func (d *fakeDev) reset() {
-	d.closed = false
-	d.rbuf.Reset()
-	d.wbuf.Reset()
+func newFakeDev(data string) *fakeDev {
+	d := &fakeDev{rbuf: bytes.NewBufferString(data), wbuf: &bytes.Buffer{}}
+	d.cond = sync.NewCond(&d.mu)
+
+	return d
 }
 
 func (d *fakeDev) Close() error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
 	d.closed = true
+	d.cond.Broadcast()
 
 	return nil
 }
 
 func (d *fakeDev) Read(b []byte) (int, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	for !d.running && !d.closed {
+		d.cond.Wait()
+	}
+	if d.closed {
+		return 0, io.EOF
+	}
+
 	return d.rbuf.Read(b)
 }
 
 func (d *fakeDev) Write(b []byte) (int, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if bytes.Equal(b, []byte(cmdRun)) {
+		d.running = true
+		d.cond.Broadcast()
+	}
+
 	return d.wbuf.Write(b)
 }
 
+func (d *fakeDev) written() string {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	return d.wbuf.String()
+}
+
+func (d *fakeDev) isClosed() bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	return d.closed
+}
+
+func fakeDialer(d *fakeDev) Dialer {
+	return DialerFunc(func(context.Context, string) (Transport, error) {
+		return d, nil
+	})
+}
+
 func TestCmd(t *testing.T) {
-	d := &fakeDev{rbuf: &bytes.Buffer{}, wbuf: &bytes.Buffer{}}
-	o := &OneRNG{Path: "/dev/null", device: d}
+	d := newFakeDev("")
+	c := newConn(d, DefaultReadTimeout)
+	defer c.close()
+
 	ctx, cancel := context.WithCancel(context.Background())
-	err := o.cmd(ctx, "foo", "bar")
+	err := c.cmd(ctx, "foo", "bar")
 	assert.NoError(t, err)
-	assert.Equal(t, "foobar", d.wbuf.String())
+	assert.Equal(t, "foobar", d.written())
 
-	d.reset()
+	d.wbuf.Reset()
 	cancel()
-	err = o.cmd(ctx, "foo", "bar")
+	err = c.cmd(ctx, "foo", "bar")
 	assert.NoError(t, err)
-	assert.Equal(t, "foo", d.wbuf.String())
+	assert.Equal(t, "foo", d.written())
 }
 
 func TestClose(t *testing.T) {
-	d := &fakeDev{}
-	o := &OneRNG{Path: "/dev/null", device: d}
-	err := o.close()
+	d := newFakeDev("")
+	c := newConn(d, DefaultReadTimeout)
+	err := c.close()
 	assert.NoError(t, err)
-	assert.True(t, d.closed)
+	assert.True(t, d.isClosed())
 
-	o = &OneRNG{Path: "/dev/null", device: nil}
-	err = o.close()
-	assert.NoError(t, err)
-	assert.Nil(t, o.device)
+	// the reader goroutine is done
+	select {
+	case <-c.done:
+	default:
+		t.Fatal("reader still running")
+	}
 }
 
 func TestVersion(t *testing.T) {
-	d := &fakeDev{
-		wbuf: &bytes.Buffer{},
-		rbuf: bytes.NewBufferString("dfoawiuhf98h9inf2oifoi2jr\n" +
-			"dfkjawflihjwfoiuh2rliu13he487631487645t98y23rtoqu3rbno9q34htgfv\n" +
-			"\r\nVersion 3\r\nas;dlfjaw;oihf2ih2o3iuf2ofnlo2jnlfuhf2iou\n\n"),
-	}
-	o := &OneRNG{Path: "/dev/null", device: d}
+	d := newFakeDev("dfoawiuhf98h9inf2oifoi2jr\n" +
+		"dfkjawflihjwfoiuh2rliu13he487631487645t98y23rtoqu3rbno9q34htgfv\n" +
+		"\r\nVersion 3\r\nas;dlfjaw;oihf2ih2o3iuf2ofnlo2jnlfuhf2iou\n\n")
+	o := &OneRNG{Path: "/dev/null", Dialer: fakeDialer(d)}
 	ctx := context.Background()
 	v, err := o.Version(ctx)
 	assert.NoError(t, err)
-	assert.Equal(t, "cmdo\ncmd4\ncmdv\ncmdO\ncmdo\n", d.wbuf.String())
+	assert.Equal(t, "cmdo\ncmd4\ncmdv\ncmdO\ncmdo\n", d.written())
 	assert.Equal(t, 3, v)
+	assert.True(t, d.isClosed())
 }
 
 func TestIdentify(t *testing.T) {
-	d := &fakeDev{
-		wbuf: &bytes.Buffer{},
-		rbuf: bytes.NewBufferString("dfoawiuhf98h9inf2oifoi2jr\n" +
-			"dfkjawflihjwfoiuh2rliu13he487631487645t98y23rtoqu3rbno9q34htgfv\n" +
-			"\r\nVersion 3\r\nas;dlfjaw;oihf2ih2o3iuf2ofnlo2jnlfuhf2iou\n\n" +
-			"dlfkhadslfihwaflkhjw\n___lskdjfalsdkjflsd___\n\n"),
-	}
-	o := &OneRNG{Path: "/dev/null", device: d}
+	d := newFakeDev("dfoawiuhf98h9inf2oifoi2jr\n" +
+		"dfkjawflihjwfoiuh2rliu13he487631487645t98y23rtoqu3rbno9q34htgfv\n" +
+		"\r\nVersion 3\r\nas;dlfjaw;oihf2ih2o3iuf2ofnlo2jnlfuhf2iou\n\n" +
+		"dlfkhadslfihwaflkhjw\n___lskdjfalsdkjflsd___\n\n")
+	o := &OneRNG{Path: "/dev/null", Dialer: fakeDialer(d)}
 	ctx := context.Background()
 	id, err := o.Identify(ctx)
 	assert.NoError(t, err)
-	assert.Equal(t, "cmd4\ncmdI\ncmdO\ncmdo\n", d.wbuf.String())
+	assert.Equal(t, "cmd4\ncmdI\ncmdO\ncmdo\n", d.written())
 	assert.Equal(t, "___lskdjfalsdkjflsd___", id)
 }
+
+func TestVersion_EOF(t *testing.T) {
+	// the device stops before sending a version
+	d := newFakeDev("garbage\n")
+	o := &OneRNG{Path: "/dev/null", Dialer: fakeDialer(d)}
+	_, err := o.Version(context.Background())
+	assert.ErrorIs(t, err, io.EOF)
+}
+
+func TestVersion_NotOneRNG(t *testing.T) {
+	d := newFakeDev("Version three\n")
+	o := &OneRNG{Path: "/dev/null", Dialer: fakeDialer(d)}
+	_, err := o.Version(context.Background())
+	assert.ErrorIs(t, err, ErrNotOneRNG)
+}
+
+func TestReadImage(t *testing.T) {
+	img := append(bytes.Repeat([]byte{1, 0, 2}, 100), make([]byte, 300)...)
+	out, err := readImage(bytes.NewReader(img), 200)
+	assert.NoError(t, err)
+	assert.Equal(t, img[:300+201], out)
+
+	_, err = readImage(bytes.NewReader(img[:400]), 200)
+	assert.ErrorIs(t, err, io.EOF)
+}
+
+func TestDataReader(t *testing.T) {
+	d := newFakeDev("hello world")
+	c := newConn(d, DefaultReadTimeout)
+	defer c.close()
+
+	ctx := context.Background()
+	r := c.subscribeData(ctx)
+	defer r.Close()
+
+	// nothing is sent until the device is running
+	require.NoError(t, r.SetReadDeadline(time.Now().Add(10*time.Millisecond)))
+	_, err := r.Read(make([]byte, 5))
+	assert.True(t, os.IsTimeout(err), err)
+
+	require.NoError(t, c.cmd(ctx, cmdRun))
+	require.NoError(t, r.SetReadDeadline(time.Time{}))
+	b := make([]byte, 5)
+	_, err = io.ReadFull(r, b)
+	require.NoError(t, err)
+	assert.Equal(t, "hello", string(b))
+
+	rest, err := io.ReadAll(r)
+	assert.Equal(t, " world", string(rest))
+	assert.NoError(t, err)
+}
+
+func TestCopyWithContext_HealthTestedOnTimeout(t *testing.T) {
+	// data that arrives along with a (tolerated) timeout is still tested
+	src := readerFunc(func(p []byte) (int, error) {
+		return copy(p, bytes.Repeat([]byte{0x42}, 16)), os.ErrDeadlineExceeded
+	})
+
+	out := &bytes.Buffer{}
+	_, err := copyWithContext(context.Background(), out, src, -1, time.Second, 100, NewHealthTest(7))
+	require.ErrorIs(t, err, ErrHealthTest)
+	assert.Zero(t, out.Len())
+}
@@ -0,0 +1,247 @@
+package onerng
+
+import (
+	"fmt"
+	"log/slog"
+	"time"
+)
+
+// defaults for the tunable behaviour - see the corresponding options
+const (
+	DefaultReadTimeout     = 500 * time.Millisecond
+	DefaultAllowedTimeouts = 10
+	DefaultInitAttempts    = 200
+	DefaultInitTimeout     = 50 * time.Millisecond
+	DefaultImageEndZeros   = 200
+	DefaultWarmup          = 10240
+)
+
+// Option - configures a OneRNG created with New
+type Option func(*OneRNG)
+
+// New creates a OneRNG for the device at the given path, configured with the
+// given options. The zero value of OneRNG (with just a Path) is equivalent to
+// New(path) with no options.
+//
+// Options given invalid values (such as a zero read timeout) don't take
+// effect - instead, every operation on the OneRNG fails with an error
+// describing the first one.
+func New(path string, opts ...Option) *OneRNG {
+	o := &OneRNG{Path: path}
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	return o
+}
+
+// WithDialer sets the Dialer used to open the device
+func WithDialer(d Dialer) Option {
+	return func(o *OneRNG) {
+		o.Dialer = d
+	}
+}
+
+// WithReconnect enables automatic reconnection during reads
+func WithReconnect(p *ReconnectPolicy) Option {
+	return func(o *OneRNG) {
+		o.Reconnect = p
+	}
+}
+
+// WithReadTimeout sets how long to wait for data on each read from the
+// device before counting a timeout. Default is 500ms.
+func WithReadTimeout(d time.Duration) Option {
+	return func(o *OneRNG) {
+		if d <= 0 {
+			o.invalidOption("read timeout must be positive, not %s", d)
+
+			return
+		}
+		o.readTimeout = d
+	}
+}
+
+// WithAllowedTimeouts sets how many read timeouts are tolerated during a read
+// before giving up. Default is 10 - with the default read timeout, a device
+// that sends nothing for 5s is considered dead. With 0, the first timeout is
+// an error.
+func WithAllowedTimeouts(n int) Option {
+	return func(o *OneRNG) {
+		if n < 0 {
+			o.invalidOption("allowed timeouts must not be negative, not %d", n)
+
+			return
+		}
+		o.allowedTimeouts = &n
+	}
+}
+
+// WithInitAttempts sets how many times Init tries to get data from the device
+// before giving up. Default is 200.
+func WithInitAttempts(n int) Option {
+	return func(o *OneRNG) {
+		if n <= 0 {
+			o.invalidOption("init attempts must be positive, not %d", n)
+
+			return
+		}
+		o.initAttempts = n
+	}
+}
+
+// WithInitTimeout sets how long each of Init's attempts waits for data.
+// Default is 50ms.
+func WithInitTimeout(d time.Duration) Option {
+	return func(o *OneRNG) {
+		if d <= 0 {
+			o.invalidOption("init timeout must be positive, not %s", d)
+
+			return
+		}
+		o.initTimeout = d
+	}
+}
+
+// WithImageEndZeros sets the number of consecutive zero bytes that mark the
+// end of the firmware image (see Image). Default is 200.
+func WithImageEndZeros(n int) Option {
+	return func(o *OneRNG) {
+		if n <= 0 {
+			o.invalidOption("image end zeros must be positive, not %d", n)
+
+			return
+		}
+		o.imageEndZeros = n
+	}
+}
+
+// WithWarmup sets the number of bytes discarded by Init (before the start-up
+// health tests) and Warmup, while the noise sources settle. Default is 10240.
+// With 0, nothing is discarded.
+func WithWarmup(n int64) Option {
+	return func(o *OneRNG) {
+		if n < 0 {
+			o.invalidOption("warm-up must not be negative, not %d", n)
+
+			return
+		}
+		o.warmup = &n
+	}
+}
+
+// WithMinEntropy sets the claimed min-entropy, in bits per byte, of the data
+// produced in the given noise mode. This determines the health test cutoffs.
+// It must be more than 0, and at most 8. See DefaultMinEntropy for the
+// defaults.
+func WithMinEntropy(mode NoiseMode, bitsPerByte float64) Option {
+	return func(o *OneRNG) {
+		// a claim of no entropy would turn the health tests off
+		if !(bitsPerByte > 0 && bitsPerByte <= 8) {
+			o.invalidOption("min-entropy must be more than 0 and at most 8 bits per byte, not %g", bitsPerByte)
+
+			return
+		}
+		if o.minEntropy == nil {
+			o.minEntropy = map[NoiseMode]float64{}
+		}
+		o.minEntropy[mode] = bitsPerByte
+	}
+}
+
+// WithHealthTests enables or disables the continuous health tests during
+// Read. They're enabled by default.
+func WithHealthTests(enabled bool) Option {
+	return func(o *OneRNG) {
+		o.skipHealthTests = !enabled
+	}
+}
+
+// WithLogger sets a logger for diagnostic messages. By default nothing is
+// logged.
+func WithLogger(l *slog.Logger) Option {
+	return func(o *OneRNG) {
+		o.logger = l
+	}
+}
+
+// invalidOption records an invalid option, if it's the first
+func (o *OneRNG) invalidOption(format string, args ...any) {
+	if o.optionErr == nil {
+		o.optionErr = fmt.Errorf("invalid option: "+format, args...)
+	}
+}
+
+func (o *OneRNG) getReadTimeout() time.Duration {
+	if o.readTimeout == 0 {
+		return DefaultReadTimeout
+	}
+
+	return o.readTimeout
+}
+
+func (o *OneRNG) getAllowedTimeouts() int {
+	if o.allowedTimeouts == nil {
+		return DefaultAllowedTimeouts
+	}
+
+	return *o.allowedTimeouts
+}
+
+func (o *OneRNG) getInitAttempts() int {
+	if o.initAttempts == 0 {
+		return DefaultInitAttempts
+	}
+
+	return o.initAttempts
+}
+
+func (o *OneRNG) getInitTimeout() time.Duration {
+	if o.initTimeout == 0 {
+		return DefaultInitTimeout
+	}
+
+	return o.initTimeout
+}
+
+func (o *OneRNG) getImageEndZeros() int {
+	if o.imageEndZeros == 0 {
+		return DefaultImageEndZeros
+	}
+
+	return o.imageEndZeros
+}
+
+func (o *OneRNG) getWarmup() int64 {
+	if o.warmup == nil {
+		return DefaultWarmup
+	}
+
+	return *o.warmup
+}
+
+func (o *OneRNG) getMinEntropy(mode NoiseMode) float64 {
+	if h, ok := o.minEntropy[mode]; ok {
+		return h
+	}
+
+	return DefaultMinEntropy(mode)
+}
+
+// healthTest returns a new HealthTest for the given mode, or nil if health
+// tests are disabled or the mode has no entropy to test
+func (o *OneRNG) healthTest(mode NoiseMode) *HealthTest {
+	if o.skipHealthTests {
+		return nil
+	}
+
+	return NewHealthTest(o.getMinEntropy(mode))
+}
+
+func (o *OneRNG) log() *slog.Logger {
+	if o.logger == nil {
+		return slog.New(slog.DiscardHandler)
+	}
+
+	return o.logger
+}
@@ -0,0 +1,125 @@
+package onerng
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNew_Defaults(t *testing.T) {
+	o := New("/dev/ttyACM0")
+	assert.Equal(t, "/dev/ttyACM0", o.Path)
+	assert.Nil(t, o.Dialer)
+	assert.Nil(t, o.Reconnect)
+	assert.Equal(t, DefaultReadTimeout, o.getReadTimeout())
+	assert.Equal(t, DefaultAllowedTimeouts, o.getAllowedTimeouts())
+	assert.Equal(t, DefaultInitAttempts, o.getInitAttempts())
+	assert.Equal(t, DefaultInitTimeout, o.getInitTimeout())
+	assert.Equal(t, DefaultImageEndZeros, o.getImageEndZeros())
+	assert.Equal(t, int64(DefaultWarmup), o.getWarmup())
+	assert.NotNil(t, o.log())
+
+	// the zero value is the same
+	o = &OneRNG{}
+	assert.Equal(t, DefaultReadTimeout, o.getReadTimeout())
+	assert.Equal(t, int64(DefaultWarmup), o.getWarmup())
+}
+
+func TestNew_Options(t *testing.T) {
+	d := FileDialer{SkipLock: true}
+	p := &ReconnectPolicy{MaxAttempts: 3}
+	l := slog.New(slog.DiscardHandler)
+	o := New("/dev/ttyACM1",
+		WithDialer(d),
+		WithReconnect(p),
+		WithReadTimeout(time.Second),
+		WithAllowedTimeouts(3),
+		WithInitAttempts(20),
+		WithInitTimeout(time.Millisecond),
+		WithImageEndZeros(1000),
+		WithWarmup(42),
+		WithLogger(l),
+	)
+
+	assert.Equal(t, d, o.Dialer)
+	assert.Equal(t, p, o.Reconnect)
+	assert.Equal(t, time.Second, o.getReadTimeout())
+	assert.Equal(t, 3, o.getAllowedTimeouts())
+	assert.Equal(t, 20, o.getInitAttempts())
+	assert.Equal(t, time.Millisecond, o.getInitTimeout())
+	assert.Equal(t, 1000, o.getImageEndZeros())
+	assert.Equal(t, int64(42), o.getWarmup())
+	assert.Equal(t, l, o.log())
+}
+
+func TestNew_ZeroOptions(t *testing.T) {
+	o := New("/dev/ttyACM0", WithWarmup(0), WithAllowedTimeouts(0))
+	assert.Zero(t, o.getWarmup())
+	assert.Zero(t, o.getAllowedTimeouts())
+	require.NoError(t, o.acquire(context.Background()))
+	o.release()
+}
+
+func TestNew_InvalidOptions(t *testing.T) {
+	testdata := []struct {
+		opt Option
+		msg string
+	}{
+		{WithReadTimeout(0), "invalid option: read timeout must be positive, not 0s"},
+		{WithAllowedTimeouts(-1), "invalid option: allowed timeouts must not be negative, not -1"},
+		{WithInitAttempts(0), "invalid option: init attempts must be positive, not 0"},
+		{WithInitTimeout(-time.Second), "invalid option: init timeout must be positive, not -1s"},
+		{WithImageEndZeros(0), "invalid option: image end zeros must be positive, not 0"},
+		{WithWarmup(-1), "invalid option: warm-up must not be negative, not -1"},
+		{WithMinEntropy(Default, 0), "invalid option: min-entropy must be more than 0 and at most 8 bits per byte, not 0"},
+		{WithMinEntropy(Default, -1), "invalid option: min-entropy must be more than 0 and at most 8 bits per byte, not -1"},
+		{WithMinEntropy(Default, 8.5), "invalid option: min-entropy must be more than 0 and at most 8 bits per byte, not 8.5"},
+		{WithMinEntropy(Default, math.NaN()), "invalid option: min-entropy must be more than 0 and at most 8 bits per byte, not NaN"},
+	}
+
+	for _, d := range testdata {
+		t.Run(d.msg, func(t *testing.T) {
+			// only the first invalid option is reported
+			o := New("/dev/ttyACM0", d.opt, WithReadTimeout(-1))
+			err := o.acquire(context.Background())
+			require.EqualError(t, err, d.msg)
+
+			_, err = o.Version(context.Background())
+			require.EqualError(t, err, d.msg)
+			_, err = o.Open(context.Background(), Default)
+			require.EqualError(t, err, d.msg)
+		})
+	}
+}
+
+func TestInit_Logger(t *testing.T) {
+	buf := &bytes.Buffer{}
+	l := slog.New(slog.NewTextHandler(buf, &slog.HandlerOptions{Level: slog.LevelDebug}))
+
+	o := New("/dev/null", WithDialer(randomDialer()), WithLogger(l), WithWarmup(100))
+	_, err := o.Init(context.Background(), Default)
+	require.NoError(t, err)
+	assert.Contains(t, buf.String(), "msg=initialized attempts=1 discarded=100 tested=1024")
+}
+
+func TestNew_HealthOptions(t *testing.T) {
+	o := New("/dev/ttyACM0")
+	assert.InDelta(t, 7.0, o.getMinEntropy(Default), 0)
+	assert.NotNil(t, o.healthTest(Default))
+	assert.Nil(t, o.healthTest(Silent))
+
+	o = New("/dev/ttyACM0", WithMinEntropy(Default, 1), WithMinEntropy(EnableRF, 0.5))
+	assert.InDelta(t, 1.0, o.getMinEntropy(Default), 0)
+	assert.InDelta(t, 0.5, o.getMinEntropy(EnableRF), 0)
+	assert.InDelta(t, 2.0, o.getMinEntropy(DisableWhitener), 0)
+	assert.Equal(t, 41, o.healthTest(Default).RCTCutoff)
+
+	o = New("/dev/ttyACM0", WithHealthTests(false))
+	assert.Nil(t, o.healthTest(Default))
+}
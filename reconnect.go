@@ -0,0 +1,195 @@
+package onerng
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"syscall"
+	"time"
+)
+
+// ReconnectPolicy - configures automatic reconnection for streaming reads
+// (see OneRNG.Read). When the device disappears (i.e. it was unplugged, or
+// the USB hub was reset), Read waits for it to come back, initializes it
+// again, and continues writing to the same io.Writer.
+//
+// The device is matched by its USB serial number, so it can be found again
+// even if it comes back at a different path (i.e. /dev/ttyACM1 instead of
+// /dev/ttyACM0). When the serial number can't be determined, the same path is
+// used.
+type ReconnectPolicy struct {
+	// OnGap is called after each successful reconnection, describing the
+	// interruption in the stream. Optional.
+	OnGap func(Gap)
+	// Locate finds the path to the device with the given USB serial number.
+	// lastPath is the path the device was at before it disappeared, and
+	// serial may be empty if it's unknown. Optional - the default uses sysfs
+	// (see DiscoverIn).
+	Locate func(ctx context.Context, serial, lastPath string) (string, error)
+	// SysfsRoot is where sysfs is mounted - defaults to DefaultSysfsRoot
+	SysfsRoot string
+	// MaxAttempts is the number of consecutive failed attempts to allow
+	// before giving up. Zero means never give up.
+	MaxAttempts int
+	// MinBackoff is the initial delay between attempts, doubling after each
+	// failure up to MaxBackoff. Default to 100ms and 5s.
+	MinBackoff time.Duration
+	MaxBackoff time.Duration
+}
+
+// Gap - an interruption in a stream of data, caused by the device
+// disconnecting
+type Gap struct {
+	// Start is when the disconnection was noticed, and End is when the device
+	// was available again
+	Start, End time.Time
+	// Err is the error that caused the disconnection
+	Err error
+	// Path is where the device was found again
+	Path string
+	// Written is the number of bytes written before the gap
+	Written int64
+	// Attempts is the number of attempts made before reconnecting
+	Attempts int
+}
+
+// IsDisconnect reports whether the error indicates that the device has gone
+// away (i.e. it was unplugged).
+func IsDisconnect(err error) bool {
+	return errors.Is(err, syscall.EIO) ||
+		errors.Is(err, syscall.ENODEV) ||
+		errors.Is(err, syscall.ENXIO) ||
+		errors.Is(err, io.EOF) ||
+		errors.Is(err, io.ErrUnexpectedEOF)
+}
+
+// readReconnecting is Read, but with reconnection
+func (o *OneRNG) readReconnecting(ctx context.Context, out io.Writer, n int64, flags NoiseMode) (written int64, err error) {
+	p := o.Reconnect
+	serial := p.serialOf(o.devicePath())
+
+	for {
+		remaining := n
+		if n >= 0 {
+			remaining = n - written
+		}
+
+		w, err := o.readOnce(ctx, out, remaining, flags)
+		written += w
+		if err == nil || !IsDisconnect(err) || ctx.Err() != nil {
+			return written, err
+		}
+
+		o.ready.Store(false)
+
+		gap := Gap{Start: time.Now(), Err: err, Written: written}
+		gap.Path, gap.Attempts, err = o.reconnect(ctx, serial, flags)
+		if err != nil {
+			return written, fmt.Errorf("failed to reconnect after %w: %w", gap.Err, err)
+		}
+		gap.End = time.Now()
+
+		if p.OnGap != nil {
+			p.OnGap(gap)
+		}
+	}
+}
+
+// reconnect waits for the device to come back, and initializes it
+func (o *OneRNG) reconnect(ctx context.Context, serial string, mode NoiseMode) (path string, attempts int, err error) {
+	p := o.Reconnect
+	locate := p.Locate
+	if locate == nil {
+		locate = p.locate
+	}
+
+	backoff := p.MinBackoff
+	if backoff <= 0 {
+		backoff = 100 * time.Millisecond
+	}
+	maxBackoff := p.MaxBackoff
+	if maxBackoff <= 0 {
+		maxBackoff = 5 * time.Second
+	}
+
+	for {
+		attempts++
+
+		path, err = locate(ctx, serial, o.devicePath())
+		if err == nil {
+			o.setDevicePath(path)
+			_, err = o.Init(ctx, mode)
+		}
+		if err == nil {
+			return path, attempts, nil
+		}
+
+		o.log().DebugContext(ctx, "reconnection attempt failed", "attempt", attempts, "err", err)
+
+		if p.MaxAttempts > 0 && attempts >= p.MaxAttempts {
+			return "", attempts, err
+		}
+
+		t := time.NewTimer(backoff)
+		select {
+		case <-ctx.Done():
+			t.Stop()
+
+			return "", attempts, ctx.Err()
+		case <-t.C:
+		}
+		backoff = min(backoff*2, maxBackoff)
+	}
+}
+
+func (p *ReconnectPolicy) sysfsRoot() string {
+	if p.SysfsRoot == "" {
+		return DefaultSysfsRoot
+	}
+
+	return p.SysfsRoot
+}
+
+// serialOf returns the USB serial number of the device at path, or an empty
+// string if it can't be found
+func (p *ReconnectPolicy) serialOf(path string) string {
+	if resolved, err := filepath.EvalSymlinks(path); err == nil {
+		path = resolved
+	}
+
+	devs, err := DiscoverIn(p.sysfsRoot())
+	if err != nil {
+		return ""
+	}
+
+	for _, d := range devs {
+		if d.Path == path {
+			return d.Serial
+		}
+	}
+
+	return ""
+}
+
+// locate is the default Locate function - it looks up the serial number in
+// sysfs, or falls back to the last known path
+func (p *ReconnectPolicy) locate(_ context.Context, serial, lastPath string) (string, error) {
+	if serial == "" {
+		return lastPath, nil
+	}
+
+	devs, err := DiscoverIn(p.sysfsRoot())
+	if err != nil {
+		return "", err
+	}
+
+	for _, d := range devs {
+		if d.Serial == serial {
+			return d.Path, nil
+		}
+	}
+
+	return "", fmt.Errorf("%w: no OneRNG with serial %q", ErrDeviceNotFound, serial)
+}
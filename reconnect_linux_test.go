@@ -0,0 +1,125 @@
+package onerng
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/hairyhenderson/go-onerng/emulator"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// countingWriter counts bytes written, safely for concurrent inspection
+type countingWriter struct{ n atomic.Int64 }
+
+func (w *countingWriter) Write(p []byte) (int, error) {
+	w.n.Add(int64(len(p)))
+
+	return len(p), nil
+}
+
+func TestRead_Reconnect(t *testing.T) {
+	d1 := &emulator.Device{}
+	require.NoError(t, d1.Start())
+	defer d1.Close()
+
+	var current atomic.Value
+	current.Store(d1.Path())
+
+	var mu sync.Mutex
+	gaps := []Gap{}
+
+	o := &OneRNG{
+		Path: d1.Path(),
+		Reconnect: &ReconnectPolicy{
+			MinBackoff: 10 * time.Millisecond,
+			Locate: func(_ context.Context, _, _ string) (string, error) {
+				return current.Load().(string), nil
+			},
+			OnGap: func(g Gap) {
+				mu.Lock()
+				defer mu.Unlock()
+				gaps = append(gaps, g)
+			},
+		},
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	out := &countingWriter{}
+	const total = 1 << 20
+
+	type result struct {
+		err error
+		n   int64
+	}
+	done := make(chan result)
+	go func() {
+		n, err := o.Read(ctx, out, total, Default)
+		done <- result{n: n, err: err}
+	}()
+
+	require.Eventually(t, func() bool { return out.n.Load() > 1024 }, 5*time.Second, time.Millisecond)
+
+	// "unplug" the device, and plug in a new one at a different path
+	require.NoError(t, d1.Close())
+	d2 := &emulator.Device{}
+	require.NoError(t, d2.Start())
+	defer d2.Close()
+	current.Store(d2.Path())
+
+	res := <-done
+	require.NoError(t, res.err)
+	assert.Equal(t, int64(total), res.n)
+	assert.Equal(t, int64(total), out.n.Load())
+
+	mu.Lock()
+	defer mu.Unlock()
+	require.Len(t, gaps, 1)
+	assert.Equal(t, d2.Path(), gaps[0].Path)
+	assert.True(t, IsDisconnect(gaps[0].Err), gaps[0].Err)
+	assert.Positive(t, gaps[0].Written)
+	assert.False(t, gaps[0].End.Before(gaps[0].Start))
+
+	// later operations use the new path
+	assert.Equal(t, d2.Path(), o.devicePath())
+}
+
+func TestRead_ReconnectGivesUp(t *testing.T) {
+	d := &emulator.Device{}
+	require.NoError(t, d.Start())
+	defer d.Close()
+
+	o := &OneRNG{
+		Path: d.Path(),
+		Reconnect: &ReconnectPolicy{
+			MaxAttempts: 3,
+			MinBackoff:  time.Millisecond,
+			Locate: func(context.Context, string, string) (string, error) {
+				return "", ErrDeviceNotFound
+			},
+		},
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	out := &countingWriter{}
+	done := make(chan error)
+	go func() {
+		_, err := o.Read(ctx, out, -1, Default)
+		done <- err
+	}()
+
+	require.Eventually(t, func() bool { return out.n.Load() > 0 }, 5*time.Second, time.Millisecond)
+	require.NoError(t, d.Close())
+
+	err := <-done
+	assert.True(t, errors.Is(err, ErrDeviceNotFound), err)
+	assert.True(t, IsDisconnect(err), err)
+}
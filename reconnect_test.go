@@ -0,0 +1,47 @@
+package onerng
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"syscall"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIsDisconnect(t *testing.T) {
+	assert.True(t, IsDisconnect(syscall.EIO))
+	assert.True(t, IsDisconnect(&os.PathError{Op: "read", Path: "/dev/ttyACM0", Err: syscall.EIO}))
+	assert.True(t, IsDisconnect(fmt.Errorf("wrapped: %w", syscall.ENODEV)))
+	assert.True(t, IsDisconnect(io.EOF))
+	assert.False(t, IsDisconnect(nil))
+	assert.False(t, IsDisconnect(os.ErrDeadlineExceeded))
+	assert.False(t, IsDisconnect(context.Canceled))
+}
+
+func TestReconnectPolicy_Locate(t *testing.T) {
+	root := t.TempDir()
+	fakeUSBTTY(t, root, "ttyACM1", "1-1.2", "1d50", "6086", "00000001")
+	fakeUSBTTY(t, root, "ttyACM2", "2-3", "1d50", "6086", "00000002")
+
+	p := &ReconnectPolicy{SysfsRoot: root}
+	assert.Equal(t, "00000001", p.serialOf("/dev/ttyACM1"))
+	assert.Equal(t, "", p.serialOf("/dev/ttyUSB0"))
+
+	ctx := context.Background()
+	path, err := p.locate(ctx, "00000002", "/dev/ttyACM0")
+	require.NoError(t, err)
+	assert.Equal(t, "/dev/ttyACM2", path)
+
+	_, err = p.locate(ctx, "00000003", "/dev/ttyACM0")
+	assert.True(t, errors.Is(err, ErrDeviceNotFound), err)
+
+	// with no serial, the device can only be found at the same path
+	path, err = p.locate(ctx, "", "/dev/ttyACM0")
+	require.NoError(t, err)
+	assert.Equal(t, "/dev/ttyACM0", path)
+}
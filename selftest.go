@@ -0,0 +1,23 @@
+package onerng
+
+import (
+	"context"
+
+	"github.com/hairyhenderson/go-onerng/fips1402"
+)
+
+// SelfTest runs the FIPS 140-2 statistical tests (see package fips1402) on the
+// given number of 20,000-bit blocks, read from the device in the given mode.
+// If onBlock is non-nil, it's called with each block's result.
+//
+// An error is only returned if the data couldn't be read - check the
+// Summary to see whether the tests passed.
+func (o *OneRNG) SelfTest(ctx context.Context, mode NoiseMode, blocks int, onBlock func(block int, res fips1402.Result)) (*fips1402.Summary, error) {
+	s, err := o.Open(ctx, mode)
+	if err != nil {
+		return nil, err
+	}
+	defer s.Close()
+
+	return fips1402.Run(s, blocks, onBlock)
+}
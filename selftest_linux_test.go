@@ -0,0 +1,47 @@
+package onerng
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/hairyhenderson/go-onerng/emulator"
+	"github.com/hairyhenderson/go-onerng/fips1402"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSelfTest_Emulated(t *testing.T) {
+	o := startEmulator(t, &emulator.Device{})
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	blocks := 0
+	s, err := o.SelfTest(ctx, Default, 5, func(int, fips1402.Result) { blocks++ })
+	require.NoError(t, err)
+	assert.Equal(t, 5, s.Blocks)
+	assert.Equal(t, 5, blocks)
+	assert.True(t, s.Passed())
+}
+
+func TestSelfTest_EmulatedStuck(t *testing.T) {
+	data := bytes.Repeat([]byte{0x00, 0x01}, 10*fips1402.BlockSize)
+	d := &emulator.Device{Source: bytes.NewReader(data)}
+	require.NoError(t, d.Start())
+	t.Cleanup(func() { _ = d.Close() })
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	// the health tests catch this first
+	o := New(d.Path())
+	_, err := o.SelfTest(ctx, Default, 5, nil)
+	require.ErrorIs(t, err, ErrHealthTest)
+
+	o = New(d.Path(), WithHealthTests(false))
+	s, err := o.SelfTest(ctx, Default, 5, nil)
+	require.NoError(t, err)
+	assert.Equal(t, 5, s.Failures)
+	assert.Equal(t, 5, s.TestFailures[fips1402.Monobit])
+	assert.ErrorIs(t, s.Err(), fips1402.ErrFailed)
+}
@@ -0,0 +1,205 @@
+package onerng
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"sync"
+	"time"
+)
+
+// Session - a long-lived stream of random data from the OneRNG, which stays
+// open and running in one noise mode until it's closed. This is much cheaper
+// than calling Read repeatedly for small amounts of data.
+//
+// A Session has exclusive use of the device - other OneRNG methods will wait
+// until it's closed.
+//
+// The data is continuously health-tested, as with Read.
+type Session struct {
+	o *OneRNG
+	c *conn
+	r *dataReader
+	// health is nil when health tests are disabled, or the mode has none
+	health *HealthTest
+	// healthErr is the health test failure, once there's been one
+	healthErr error
+
+	// readMu serializes reads - mu is only held to update the state, so that
+	// Close doesn't have to wait for a read to time out
+	readMu sync.Mutex
+	mu     sync.Mutex
+	mode   NoiseMode
+	closed bool
+}
+
+// ErrSessionClosed - returned when using a Session after it's been closed
+var ErrSessionClosed = errors.New("session closed")
+
+// Open starts a new streaming Session in the given noise mode. The Session
+// ends when Close is called or when the context is cancelled - Close must be
+// called in either case.
+func (o *OneRNG) Open(ctx context.Context, mode NoiseMode) (*Session, error) {
+	if err := o.acquire(ctx); err != nil {
+		return nil, err
+	}
+
+	c, err := o.open(ctx)
+	if err != nil {
+		o.release()
+
+		return nil, err
+	}
+
+	s := &Session{o: o, c: c, r: c.subscribeData(ctx), mode: mode, health: o.healthTest(mode)}
+
+	err = c.cmd(ctx, noiseCommand(mode), cmdRun)
+	if err != nil {
+		_ = s.Close()
+
+		return nil, err
+	}
+
+	return s, nil
+}
+
+// Read random data from the device. Read blocks until at least some data is
+// available. If the device sends nothing for too long, a timeout error is
+// returned (check with errors.Is(err, ErrTimeout) or os.IsTimeout).
+//
+// If the data fails a health test (see HealthTest), it's not returned -
+// instead a *HealthError is, from then on.
+//
+// Closing the Session interrupts a Read that's waiting for data.
+func (s *Session) Read(p []byte) (int, error) {
+	s.readMu.Lock()
+	defer s.readMu.Unlock()
+
+	s.mu.Lock()
+	herr := s.healthErr
+	s.mu.Unlock()
+	if herr != nil {
+		return 0, herr
+	}
+
+	n, err := s.read(p)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.health != nil && n > 0 {
+		if herr := s.health.Check(p[:n]); herr != nil {
+			clear(p[:n])
+			s.healthErr = herr
+			s.o.ready.Store(false)
+
+			return 0, herr
+		}
+	}
+
+	return n, err
+}
+
+// Raw returns a reader for the session's data that skips the health tests,
+// for assessing the noise source itself (see MeasureMinEntropy). The data
+// isn't fit for use as random output.
+func (s *Session) Raw() io.Reader {
+	return readerFunc(s.readUntested)
+}
+
+// readUntested reads without health-testing the data, for warm-up and
+// diagnostics, where the data isn't used
+func (s *Session) readUntested(p []byte) (int, error) {
+	s.readMu.Lock()
+	defer s.readMu.Unlock()
+
+	return s.read(p)
+}
+
+// read reads from the device - readMu must be held
+func (s *Session) read(p []byte) (int, error) {
+	if s.isClosed() {
+		return 0, ErrSessionClosed
+	}
+
+	// as with Read, the last timeout allowed is followed by one more
+	timeout := s.o.getReadTimeout() * time.Duration(s.o.getAllowedTimeouts()+1)
+	_ = s.r.SetReadDeadline(time.Now().Add(timeout))
+
+	n, err := s.r.Read(p)
+	switch {
+	case err == nil:
+	case s.isClosed():
+		// interrupted by Close
+		err = ErrSessionClosed
+	case os.IsTimeout(err):
+		err = &timeoutError{err: err}
+	}
+
+	return n, err
+}
+
+func (s *Session) isClosed() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.closed
+}
+
+// Mode returns the current noise mode
+func (s *Session) Mode() NoiseMode {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.mode
+}
+
+// SetMode changes the noise mode without stopping the stream. Data generated
+// in the old mode may still be in transit, so a small amount may be returned
+// by Read after SetMode returns.
+func (s *Session) SetMode(ctx context.Context, mode NoiseMode) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed {
+		return ErrSessionClosed
+	}
+
+	err := s.c.cmd(ctx, noiseCommand(mode))
+	if err != nil {
+		return err
+	}
+	s.mode = mode
+	s.health = s.o.healthTest(mode)
+
+	return nil
+}
+
+// Close pauses the device, closes it, and releases it for use by other
+// operations. Close is always safe to call, even more than once.
+func (s *Session) Close() error {
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+
+		return nil
+	}
+	s.closed = true
+	s.mu.Unlock()
+
+	// cancelling the subscription interrupts any read that's waiting, and
+	// then there are no more
+	_ = s.r.Close()
+	s.readMu.Lock()
+	defer s.readMu.Unlock()
+
+	// make sure the device is paused, even if the session's context is done
+	err := s.c.cmd(context.Background(), cmdPause)
+	if cerr := s.c.close(); err == nil {
+		err = cerr
+	}
+	s.o.release()
+
+	return err
+}
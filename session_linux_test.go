@@ -0,0 +1,146 @@
+package onerng
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/hairyhenderson/go-onerng/emulator"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSession_Emulated(t *testing.T) {
+	d := &emulator.Device{Source: bytes.NewReader(bytes.Repeat([]byte("0123456789"), 1000))}
+	o := startEmulator(t, d)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	s, err := o.Open(ctx, Default)
+	require.NoError(t, err)
+
+	var _ io.ReadCloser = s
+
+	// lots of small reads, all from the one stream
+	out := []byte{}
+	for range 20 {
+		b := make([]byte, 5)
+		_, err = io.ReadFull(s, b)
+		require.NoError(t, err)
+		out = append(out, b...)
+	}
+	assert.Equal(t, bytes.Repeat([]byte("0123456789"), 10), out)
+	assert.Equal(t, []string{"cmd0", "cmdO"}, d.Commands())
+
+	require.NoError(t, s.SetMode(ctx, EnableRF|DisableWhitener))
+	assert.Equal(t, EnableRF|DisableWhitener, s.Mode())
+	assert.Eventually(t, func() bool {
+		return len(d.Commands()) == 3
+	}, time.Second, 10*time.Millisecond)
+	assert.Equal(t, "cmd3", d.Commands()[2])
+
+	require.NoError(t, s.Close())
+	assert.Eventually(t, func() bool { return !d.Running() }, time.Second, 10*time.Millisecond)
+
+	// closing again is fine, but reading isn't
+	assert.NoError(t, s.Close())
+	_, err = s.Read(make([]byte, 1))
+	assert.ErrorIs(t, err, ErrSessionClosed)
+	assert.ErrorIs(t, s.SetMode(ctx, Default), ErrSessionClosed)
+}
+
+func TestSession_Exclusive(t *testing.T) {
+	o := startEmulator(t, &emulator.Device{Version: 3})
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	s, err := o.Open(ctx, Default)
+	require.NoError(t, err)
+
+	// other operations must wait for the session to end
+	vctx, vcancel := context.WithTimeout(ctx, 100*time.Millisecond)
+	defer vcancel()
+	_, err = o.Version(vctx)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+
+	require.NoError(t, s.Close())
+
+	v, err := o.Version(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 3, v)
+}
+
+func TestSession_ContextCancelled(t *testing.T) {
+	d := &emulator.Device{}
+	o := startEmulator(t, d)
+	ctx, cancel := context.WithCancel(context.Background())
+
+	s, err := o.Open(ctx, Default)
+	require.NoError(t, err)
+
+	_, err = s.Read(make([]byte, 16))
+	require.NoError(t, err)
+
+	cancel()
+	_, err = s.Read(make([]byte, 16))
+	assert.ErrorIs(t, err, context.Canceled)
+
+	// the device is still paused on close
+	require.NoError(t, s.Close())
+	assert.Eventually(t, func() bool { return !d.Running() }, time.Second, 10*time.Millisecond)
+}
+
+func TestSession_HealthFailure(t *testing.T) {
+	// a stuck source fails the health tests, and nothing is returned
+	d := &emulator.Device{Source: bytes.NewReader(bytes.Repeat([]byte{0x42}, 4096))}
+	o := startEmulator(t, d)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	s, err := o.Open(ctx, Default)
+	require.NoError(t, err)
+	defer s.Close()
+
+	b := make([]byte, 64)
+	_, err = io.ReadFull(s, b)
+	require.ErrorIs(t, err, ErrHealthTest)
+	assert.Equal(t, make([]byte, 64), b)
+
+	// the failure sticks
+	n, err := s.Read(b)
+	assert.ErrorIs(t, err, ErrHealthTest)
+	assert.Zero(t, n)
+}
+
+func TestSession_CloseInterruptsRead(t *testing.T) {
+	// the silent mode sends nothing, so the read waits for its timeout
+	o := startEmulator(t, &emulator.Device{})
+	WithReadTimeout(time.Minute)(o)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+
+	s, err := o.Open(ctx, Silent)
+	require.NoError(t, err)
+
+	errs := make(chan error, 1)
+	go func() {
+		_, err := s.Read(make([]byte, 16))
+		errs <- err
+	}()
+
+	time.Sleep(50 * time.Millisecond)
+
+	// Close mustn't wait for the read to time out
+	start := time.Now()
+	require.NoError(t, s.Close())
+	assert.Less(t, time.Since(start), 500*time.Millisecond)
+
+	select {
+	case err := <-errs:
+		require.ErrorIs(t, err, ErrSessionClosed)
+	case <-time.After(time.Second):
+		t.Fatal("Close didn't interrupt the read")
+	}
+}
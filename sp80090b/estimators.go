@@ -0,0 +1,200 @@
+package sp80090b
+
+import "math"
+
+// mostCommonValue - the Most Common Value Estimate (800-90B 6.3.1)
+func mostCommonValue(s []byte, k int) float64 {
+	counts := make([]int, k)
+	maxCount := 0
+	for _, b := range s {
+		counts[b]++
+		maxCount = max(maxCount, counts[b])
+	}
+
+	p := float64(maxCount) / float64(len(s))
+
+	return -math.Log2(upperBound(p, len(s)))
+}
+
+// collision - the Collision Estimate (800-90B 6.3.2), for binary samples only
+func collision(s []byte, _ int) float64 {
+	// the number of samples up to and including each collision
+	times := []float64{}
+	for i := 0; i+1 < len(s); {
+		switch {
+		case s[i] == s[i+1]:
+			times = append(times, 2)
+			i += 2
+		case i+2 < len(s):
+			// with only two values, the third must collide
+			times = append(times, 3)
+			i += 3
+		default:
+			i = len(s)
+		}
+	}
+
+	v := float64(len(times))
+	mean, sd := meanStdDev(times)
+	x := mean - zAlpha*sd/math.Sqrt(v)
+
+	// the expected time to the first collision, for a binary source where
+	// the most likely value has probability p
+	expected := func(p float64) float64 {
+		q := 1 - p
+		f := q + 2*q*q + 2*q*q*q
+
+		return p/(q*q)*(1+(1/p-1/q)/2)*f - p/q*(1/p-1/q)/2
+	}
+
+	if x >= expected(0.5) {
+		return 1
+	}
+
+	p := bisect(expected, x, 0.5, 1)
+
+	return -math.Log2(p)
+}
+
+// markov - the Markov Estimate (800-90B 6.3.3), for binary samples only
+func markov(s []byte, _ int) float64 {
+	var counts [2]float64
+	var trans [2][2]float64
+	for i, b := range s {
+		counts[b]++
+		if i > 0 {
+			trans[s[i-1]][b]++
+		}
+	}
+
+	p0 := counts[0] / float64(len(s))
+	p1 := 1 - p0
+
+	var p [2][2]float64
+	for i := range trans {
+		if n := trans[i][0] + trans[i][1]; n > 0 {
+			p[i][0] = trans[i][0] / n
+			p[i][1] = trans[i][1] / n
+		}
+	}
+
+	// log2 probabilities of the most likely 128-bit sequences
+	lg := math.Log2
+	seqs := []float64{
+		lg(p0) + 127*lg(p[0][0]),
+		lg(p0) + 64*lg(p[0][1]) + 63*lg(p[1][0]),
+		lg(p0) + lg(p[0][1]) + 126*lg(p[1][1]),
+		lg(p1) + lg(p[1][0]) + 126*lg(p[0][0]),
+		lg(p1) + 64*lg(p[1][0]) + 63*lg(p[0][1]),
+		lg(p1) + 127*lg(p[1][1]),
+	}
+
+	pmax := math.Inf(-1)
+	for _, v := range seqs {
+		if !math.IsNaN(v) {
+			pmax = math.Max(pmax, v)
+		}
+	}
+
+	return math.Min(-pmax/128, 1)
+}
+
+// compression - the Compression Estimate (800-90B 6.3.4), for binary samples
+// only
+func compression(s []byte, _ int) float64 {
+	const (
+		b = 6
+		d = 1000
+	)
+
+	blocks := make([]int, len(s)/b)
+	for i := range blocks {
+		for _, bit := range s[i*b : (i+1)*b] {
+			blocks[i] = blocks[i]<<1 | int(bit)
+		}
+	}
+
+	n := len(blocks)
+	nu := n - d
+	if nu < 2 {
+		return math.NaN()
+	}
+
+	// the dictionary records the (1-based) index each value was last seen at
+	var dict [1 << b]int
+	for i := 1; i <= d; i++ {
+		dict[blocks[i-1]] = i
+	}
+
+	logD := make([]float64, nu)
+	for i := d + 1; i <= n; i++ {
+		v := blocks[i-1]
+		dist := i
+		if dict[v] != 0 {
+			dist = i - dict[v]
+		}
+		dict[v] = i
+		logD[i-d-1] = math.Log2(float64(dist))
+	}
+
+	mean, sumSq := 0.0, 0.0
+	for _, v := range logD {
+		mean += v
+		sumSq += v * v
+	}
+	mean /= float64(nu)
+	// 0.5907 is the correction factor for b=6
+	sd := 0.5907 * math.Sqrt(sumSq/float64(nu-1)-mean*mean)
+	x := mean - zAlpha*sd/math.Sqrt(float64(nu))
+
+	logs := make([]float64, n+1)
+	for u := 1; u <= n; u++ {
+		logs[u] = math.Log2(float64(u))
+	}
+
+	// g is G(z) from 800-90B, rearranged to take linear time
+	g := func(z float64) float64 {
+		sum := 0.0
+		pow := 1.0 // (1-z)^(u-1)
+		for u := 1; u <= n; u++ {
+			if u < n {
+				sum += z * z * logs[u] * pow * float64(n-max(u, d))
+			}
+			if u > d {
+				sum += z * logs[u] * pow
+			}
+			pow *= 1 - z
+		}
+
+		return sum / float64(nu)
+	}
+	expected := func(p float64) float64 {
+		q := (1 - p) / (1<<b - 1)
+
+		return g(p) + (1<<b-1)*g(q)
+	}
+
+	lo := 1.0 / (1 << b)
+	if x >= expected(lo) {
+		return 1
+	}
+
+	p := bisect(expected, x, lo, 1)
+
+	return -math.Log2(p) / b
+}
+
+// meanStdDev returns the mean and sample standard deviation
+func meanStdDev(x []float64) (mean, sd float64) {
+	n := float64(len(x))
+	for _, v := range x {
+		mean += v
+	}
+	mean /= n
+
+	for _, v := range x {
+		sd += (v - mean) * (v - mean)
+	}
+
+	return mean, math.Sqrt(sd / (n - 1))
+}
@@ -0,0 +1,54 @@
+package sp80090b
+
+import (
+	"bytes"
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMostCommonValue(t *testing.T) {
+	// 1000 samples, the most common appears 500 times
+	s := make([]byte, 1000)
+	for i := range 500 {
+		s[i] = 1
+	}
+	p := 0.5 + zAlpha*math.Sqrt(0.25/999)
+	assert.InDelta(t, -math.Log2(p), mostCommonValue(s, 2), 1e-9)
+
+	assert.InDelta(t, 0, mostCommonValue(bytes.Repeat([]byte{7}, 1000), 256), 1e-9)
+}
+
+func TestCollision(t *testing.T) {
+	assert.Greater(t, collision(biasedBits(100000, 0.5), 2), 0.85)
+
+	h := collision(biasedBits(100000, 0.9), 2)
+	assert.InDelta(t, -math.Log2(0.9), h, 0.05)
+
+	assert.InDelta(t, 0, collision(make([]byte, 1000), 2), 0.01)
+}
+
+func TestMarkov(t *testing.T) {
+	assert.Greater(t, markov(biasedBits(100000, 0.5), 2), 0.95)
+	assert.InDelta(t, -math.Log2(0.8), markov(biasedBits(100000, 0.8), 2), 0.02)
+
+	// alternating bits are perfectly predictable
+	assert.InDelta(t, 0, markov(bytes.Repeat([]byte{0, 1}, 1000), 2), 0.01)
+	assert.InDelta(t, 0, markov(make([]byte, 1000), 2), 0.01)
+}
+
+func TestCompression(t *testing.T) {
+	assert.Greater(t, compression(biasedBits(100000, 0.5), 2), 0.75)
+	assert.Less(t, compression(biasedBits(100000, 0.9), 2), -math.Log2(0.9))
+	assert.Less(t, compression(bytes.Repeat([]byte{0, 1, 1}, 10000), 2), 0.1)
+
+	// too few samples to fill the dictionary
+	assert.True(t, math.IsNaN(compression(make([]byte, 6000), 2)))
+}
+
+func TestMeanStdDev(t *testing.T) {
+	m, sd := meanStdDev([]float64{2, 4, 4, 4, 5, 5, 7, 9})
+	assert.InDelta(t, 5, m, 1e-9)
+	assert.InDelta(t, math.Sqrt(32.0/7), sd, 1e-9)
+}
@@ -0,0 +1,298 @@
+package sp80090b
+
+import "math"
+
+// The predictor estimates (800-90B 6.3.7 - 6.3.10) each try to predict the
+// next sample from the ones before it, and the min-entropy is estimated from
+// how often (and how many times in a row) they're right.
+
+// multiMCW - the Multi Most Common in Window prediction estimate (800-90B
+// 6.3.7)
+func multiMCW(s []byte, k int) float64 {
+	windows := [...]int{63, 255, 1023, 4095}
+	l := len(s)
+	if l <= windows[0] {
+		return math.NaN()
+	}
+
+	var (
+		counts [len(windows)][]int
+		modes  [len(windows)]int
+		maxes  [len(windows)]int
+		scores [len(windows)]int
+	)
+	for j := range windows {
+		counts[j] = make([]int, k)
+		modes[j] = -1
+	}
+
+	// the index each value was last seen at, to break ties in favour of the
+	// most recent value
+	last := make([]int, k)
+	for i := range last {
+		last[i] = -1
+	}
+
+	// findMode scans for the most common value in window j
+	findMode := func(j int) {
+		modes[j], maxes[j] = -1, 0
+		for v, n := range counts[j] {
+			if n > maxes[j] || (n == maxes[j] && n > 0 && last[v] > last[modes[j]]) {
+				modes[j], maxes[j] = v, n
+			}
+		}
+	}
+
+	correct := make([]bool, 0, l-windows[0])
+	winner := 0
+	for i, x := range s {
+		if i >= windows[0] {
+			correct = append(correct, modes[winner] == int(x))
+
+			for j, w := range windows {
+				if i >= w && modes[j] == int(x) {
+					scores[j]++
+					if scores[j] >= scores[winner] {
+						winner = j
+					}
+				}
+			}
+		}
+
+		// slide each window along - dropping the oldest sample (only
+		// rescanning if it was the mode), and adding this one, which becomes
+		// the mode if it's at least as common as the current mode
+		last[x] = i
+		for j, w := range windows {
+			if i >= w {
+				old := s[i-w]
+				counts[j][old]--
+				if int(old) == modes[j] {
+					findMode(j)
+				}
+			}
+			counts[j][x]++
+			if counts[j][x] >= maxes[j] {
+				modes[j], maxes[j] = int(x), counts[j][x]
+			}
+		}
+	}
+
+	return predictorEstimate(correct, k)
+}
+
+// lagPrediction - the Lag Prediction Estimate (800-90B 6.3.8)
+func lagPrediction(s []byte, k int) float64 {
+	const lags = 128
+
+	var scores [lags + 1]int
+	winner := 1
+
+	correct := make([]bool, 0, len(s)-1)
+	for i := 1; i < len(s); i++ {
+		x := s[i]
+		correct = append(correct, i >= winner && s[i-winner] == x)
+
+		for d := 1; d <= lags && d <= i; d++ {
+			if s[i-d] == x {
+				scores[d]++
+				if scores[d] >= scores[winner] {
+					winner = d
+				}
+			}
+		}
+	}
+
+	return predictorEstimate(correct, k)
+}
+
+// multiMMC - the Multi Markov Model with Counting prediction estimate
+// (800-90B 6.3.9)
+func multiMMC(s []byte, k int) float64 {
+	const (
+		depth      = 16
+		maxEntries = 100000
+	)
+
+	var (
+		models [depth + 1]map[[depth]byte]*successors
+		scores [depth + 1]int
+	)
+	for d := 1; d <= depth; d++ {
+		models[d] = map[[depth]byte]*successors{}
+	}
+	winner := 1
+
+	correct := make([]bool, 0, max(len(s)-2, 0))
+	for i := 2; i < len(s); i++ {
+		// train each model with the previous sample
+		for d := 1; d <= depth && d < i; d++ {
+			key := tupleKey(s[i-d-1 : i-1])
+			if m, ok := models[d][key]; ok {
+				m.add(s[i-1])
+			} else if len(models[d]) < maxEntries {
+				m = &successors{}
+				m.add(s[i-1])
+				models[d][key] = m
+			}
+		}
+
+		// predict the next sample with each model
+		x := s[i]
+		var predictions [depth + 1]int
+		for d := 1; d <= depth; d++ {
+			predictions[d] = -1
+			if d <= i {
+				if m, ok := models[d][tupleKey(s[i-d:i])]; ok {
+					y, _ := m.predict()
+					predictions[d] = int(y)
+				}
+			}
+		}
+
+		correct = append(correct, predictions[winner] == int(x))
+
+		for d := 1; d <= depth; d++ {
+			if predictions[d] == int(x) {
+				scores[d]++
+				if scores[d] >= scores[winner] {
+					winner = d
+				}
+			}
+		}
+	}
+
+	return predictorEstimate(correct, k)
+}
+
+// lz78y - the LZ78Y prediction estimate (800-90B 6.3.10)
+func lz78y(s []byte, k int) float64 {
+	const (
+		maxLen     = 16
+		maxEntries = 65536
+	)
+
+	type key struct {
+		t [maxLen]byte
+		n int
+	}
+	dict := map[key]*successors{}
+
+	correct := make([]bool, 0, max(len(s)-maxLen-1, 0))
+	for i := maxLen + 1; i < len(s); i++ {
+		// add the previous sample to the dictionary, following each tuple
+		// length
+		for j := maxLen; j >= 1; j-- {
+			tk := key{tupleKey(s[i-j-1 : i-1]), j}
+			m, ok := dict[tk]
+			if !ok {
+				if len(dict) >= maxEntries {
+					continue
+				}
+				m = &successors{}
+				dict[tk] = m
+			}
+			m.add(s[i-1])
+		}
+
+		// predict with the most common value after the longest possible tuple
+		prediction, maxCount := -1, uint32(0)
+		for j := maxLen; j >= 1; j-- {
+			if m, ok := dict[key{tupleKey(s[i-j : i]), j}]; ok {
+				if y, n := m.predict(); n > maxCount {
+					prediction, maxCount = int(y), n
+				}
+			}
+		}
+
+		correct = append(correct, prediction == int(s[i]))
+	}
+
+	return predictorEstimate(correct, k)
+}
+
+// predictorEstimate computes the min-entropy from a predictor's results, based
+// on the global proportion of correct predictions, and the longest run of
+// correct predictions
+func predictorEstimate(correct []bool, k int) float64 {
+	n := len(correct)
+	if n < 2 {
+		return math.NaN()
+	}
+
+	c, run, longest := 0, 0, 0
+	for _, ok := range correct {
+		if !ok {
+			run = 0
+
+			continue
+		}
+		c++
+		run++
+		longest = max(longest, run)
+	}
+
+	pGlobal := 1 - math.Pow(0.01, 1/float64(n))
+	if c > 0 {
+		pGlobal = upperBound(float64(c)/float64(n), n)
+	}
+
+	r := float64(longest + 1)
+	noRun := func(p float64) float64 {
+		q := 1 - p
+		x := 1.0
+		for range 10 {
+			x = 1 + q*math.Pow(p, r)*math.Pow(x, r+1)
+		}
+
+		return (1 - p*x) / ((r + 1 - r*x) * q) / math.Pow(x, float64(n+1))
+	}
+	pLocal := bisect(noRun, 0.99, 0, 1)
+
+	return -math.Log2(math.Max(math.Max(pGlobal, pLocal), 1/float64(k)))
+}
+
+// successors counts the values that follow a tuple, keeping track of the
+// most common
+type successors struct {
+	next []successor
+	best int
+}
+
+type successor struct {
+	n uint32
+	y byte
+}
+
+func (s *successors) add(y byte) {
+	i := 0
+	for i < len(s.next) && s.next[i].y != y {
+		i++
+	}
+	if i == len(s.next) {
+		s.next = append(s.next, successor{y: y})
+	}
+	s.next[i].n++
+
+	// ties go to the larger value
+	b := s.next[s.best]
+	if c := s.next[i]; c.n > b.n || (c.n == b.n && c.y > b.y) {
+		s.best = i
+	}
+}
+
+// predict returns the most common successor, and its count
+func (s *successors) predict() (byte, uint32) {
+	if len(s.next) == 0 {
+		return 0, 0
+	}
+	b := s.next[s.best]
+
+	return b.y, b.n
+}
+
+func tupleKey(t []byte) (k [16]byte) {
+	copy(k[:], t)
+
+	return k
+}
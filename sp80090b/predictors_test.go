@@ -0,0 +1,73 @@
+package sp80090b
+
+import (
+	"bytes"
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPredictors(t *testing.T) {
+	predictors := map[string]func([]byte, int) float64{
+		"Lag":      lagPrediction,
+		"MultiMMC": multiMMC,
+		"LZ78Y":    lz78y,
+	}
+
+	random := randomBytes(50000, 7)
+	bits := biasedBits(50000, 0.5)
+	for name, fn := range predictors {
+		t.Run(name, func(t *testing.T) {
+			assert.Greater(t, fn(random, 256), 6.5)
+			assert.Greater(t, fn(bits, 2), 0.9)
+
+			// a repeating pattern is predictable
+			assert.Less(t, fn(bytes.Repeat([]byte{1, 2, 3, 4, 5}, 5000), 256), 0.1)
+		})
+	}
+
+	assert.Greater(t, multiMCW(random, 256), 6.5)
+	assert.Greater(t, multiMCW(bits, 2), 0.9)
+	// the most common value is usually right
+	assert.Less(t, multiMCW(bytes.Repeat([]byte{7, 7, 7, 7, 7, 7, 7, 7, 7, 1}, 5000), 256), 0.2)
+
+	// the lag predictor is good at periodic data, even with a long period
+	assert.Less(t, lagPrediction(bytes.Repeat(randomBytes(100, 8), 200), 256), 0.1)
+}
+
+func TestPredictorEstimate(t *testing.T) {
+	// never right - the global estimate is a fixed upper bound
+	n := 10000
+	p := 1 - math.Pow(0.01, 1/float64(n))
+	assert.InDelta(t, -math.Log2(p), predictorEstimate(make([]bool, n), 1<<20), 1e-9)
+
+	// never better than guessing
+	assert.InDelta(t, 8, predictorEstimate(make([]bool, n), 256), 1e-9)
+
+	// always right
+	correct := make([]bool, n)
+	for i := range correct {
+		correct[i] = true
+	}
+	assert.InDelta(t, 0, predictorEstimate(correct, 256), 0.01)
+}
+
+func TestSuccessors(t *testing.T) {
+	s := &successors{}
+	y, n := s.predict()
+	assert.Equal(t, byte(0), y)
+	assert.Equal(t, uint32(0), n)
+
+	s.add(5)
+	s.add(3)
+	y, n = s.predict()
+	// ties go to the larger value
+	assert.Equal(t, byte(5), y)
+	assert.Equal(t, uint32(1), n)
+
+	s.add(3)
+	y, n = s.predict()
+	assert.Equal(t, byte(3), y)
+	assert.Equal(t, uint32(2), n)
+}
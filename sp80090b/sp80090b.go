@@ -0,0 +1,212 @@
+/*
+Package sp80090b implements the non-IID min-entropy estimators from NIST SP
+800-90B ("Recommendation for the Entropy Sources Used for Random Bit
+Generation"), section 6.3, for assessing how much entropy a noise source
+really produces.
+
+	r, err := sp80090b.Assess(samples, 8)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("%f bits of min-entropy per bit\n", r.MinEntropyPerBit)
+
+As in section 3.1.3, the estimators are run on the samples as given (except
+for those only defined for binary data), and on the samples expanded into a
+bitstring, and the lowest resulting estimate is the assessed min-entropy.
+
+800-90B expects at least 1,000,000 samples - smaller inputs give lower (more
+conservative) estimates.
+*/
+package sp80090b
+
+import (
+	"fmt"
+	"math"
+)
+
+const (
+	// MinSamples - the smallest number of samples that can be assessed
+	MinSamples = 1000
+	// maxBitstringLen - only the first million bits of the bitstring are
+	// assessed
+	maxBitstringLen = 1000000
+	// zAlpha - the z-value for the 99.5% confidence bounds (alpha = 0.005)
+	zAlpha = 2.5758293035489
+)
+
+// Estimate - the result of one estimator
+type Estimate struct {
+	// Name - the estimator's name
+	Name string `json:"name"`
+	// MinEntropy - the estimated min-entropy per sample
+	MinEntropy float64 `json:"minEntropy"`
+	// MinEntropyPerBit - the estimated min-entropy per bit of the sample
+	MinEntropyPerBit float64 `json:"minEntropyPerBit"`
+}
+
+// Report - the results of an assessment
+type Report struct {
+	// Original - the estimates for the samples as given. This is empty for
+	// 1-bit samples, which are assessed as a bitstring.
+	Original []Estimate `json:"original,omitempty"`
+	// Bitstring - the estimates for the samples expanded into a bitstring
+	Bitstring []Estimate `json:"bitstring"`
+	// Samples - the number of samples assessed
+	Samples int `json:"samples"`
+	// BitsPerSample - the size of each sample
+	BitsPerSample int `json:"bitsPerSample"`
+	// HOriginal - the lowest estimate for the samples as given, in bits per
+	// sample
+	HOriginal float64 `json:"hOriginal"`
+	// HBitstring - the lowest estimate for the bitstring, in bits per bit
+	HBitstring float64 `json:"hBitstring"`
+	// MinEntropy - the assessed min-entropy, in bits per sample - the lower
+	// of HOriginal and HBitstring * BitsPerSample
+	MinEntropy float64 `json:"minEntropy"`
+	// MinEntropyPerBit - the assessed min-entropy, in bits per bit
+	MinEntropyPerBit float64 `json:"minEntropyPerBit"`
+}
+
+// estimator - one of the estimators. Binary estimators only apply to 1-bit
+// samples. k is the number of possible sample values.
+type estimator struct {
+	fn     func(s []byte, k int) float64
+	name   string
+	binary bool
+}
+
+var estimators = []estimator{
+	{name: "Most Common Value", fn: mostCommonValue},
+	{name: "Collision", fn: collision, binary: true},
+	{name: "Markov", fn: markov, binary: true},
+	{name: "Compression", fn: compression, binary: true},
+	{name: "t-Tuple", fn: tTuple},
+	{name: "Longest Repeated Substring", fn: lrs},
+	{name: "Multi Most Common in Window", fn: multiMCW},
+	{name: "Lag Prediction", fn: lagPrediction},
+	{name: "Multi Markov Model with Counting", fn: multiMMC},
+	{name: "LZ78Y", fn: lz78y},
+}
+
+// Assess runs all the estimators on the given samples. Only the low
+// bitsPerSample bits (between 1 and 8) of each sample are used.
+func Assess(samples []byte, bitsPerSample int) (*Report, error) {
+	if bitsPerSample < 1 || bitsPerSample > 8 {
+		return nil, fmt.Errorf("bits per sample must be between 1 and 8, not %d", bitsPerSample)
+	}
+	if len(samples) < MinSamples {
+		return nil, fmt.Errorf("at least %d samples are needed, not %d", MinSamples, len(samples))
+	}
+
+	mask := byte(1<<bitsPerSample - 1)
+	s := make([]byte, len(samples))
+	for i, b := range samples {
+		s[i] = b & mask
+	}
+
+	r := &Report{
+		Samples:       len(s),
+		BitsPerSample: bitsPerSample,
+		HOriginal:     float64(bitsPerSample),
+		HBitstring:    1,
+	}
+
+	if bitsPerSample > 1 {
+		r.Original = run(s, bitsPerSample, false)
+		for _, e := range r.Original {
+			r.HOriginal = math.Min(r.HOriginal, e.MinEntropy)
+		}
+	}
+
+	r.Bitstring = run(Bitstring(s, bitsPerSample, maxBitstringLen), 1, true)
+	for _, e := range r.Bitstring {
+		r.HBitstring = math.Min(r.HBitstring, e.MinEntropy)
+	}
+
+	r.MinEntropy = math.Min(r.HOriginal, r.HBitstring*float64(bitsPerSample))
+	r.MinEntropyPerBit = r.MinEntropy / float64(bitsPerSample)
+
+	return r, nil
+}
+
+// MostCommonValue returns a quick min-entropy estimate of 8-bit samples, in
+// bits per sample, from the Most Common Value Estimate (800-90B 6.3.1) alone.
+// It's never lower than the estimate from Assess, so it's only useful for
+// spotting sources that are obviously broken.
+func MostCommonValue(samples []byte) float64 {
+	if len(samples) == 0 {
+		return 0
+	}
+
+	return mostCommonValue(samples, 256)
+}
+
+// run the applicable estimators. Estimators that can't produce an estimate
+// (returning NaN) are skipped.
+func run(s []byte, bits int, binary bool) []Estimate {
+	k := 1 << bits
+	out := make([]Estimate, 0, len(estimators))
+
+	for _, e := range estimators {
+		if e.binary && !binary {
+			continue
+		}
+
+		h := e.fn(s, k)
+		if math.IsNaN(h) {
+			continue
+		}
+		h = math.Max(0, math.Min(h, float64(bits)))
+
+		out = append(out, Estimate{
+			Name:             e.name,
+			MinEntropy:       h,
+			MinEntropyPerBit: h / float64(bits),
+		})
+	}
+
+	return out
+}
+
+// Bitstring expands samples of the given size into a slice of bits (one per
+// byte), most significant first, up to a maximum length (or unlimited, if
+// max is 0 or less).
+func Bitstring(samples []byte, bitsPerSample, maxLen int) []byte {
+	n := len(samples) * bitsPerSample
+	if maxLen > 0 {
+		n = min(n, maxLen)
+	}
+
+	bits := make([]byte, 0, n)
+	for _, b := range samples {
+		for i := bitsPerSample - 1; i >= 0; i-- {
+			if len(bits) == n {
+				return bits
+			}
+			bits = append(bits, (b>>i)&1)
+		}
+	}
+
+	return bits
+}
+
+// upperBound - the 99.5% upper confidence bound (see zAlpha) for a proportion
+// p observed over n trials
+func upperBound(p float64, n int) float64 {
+	return math.Min(1, p+zAlpha*math.Sqrt(p*(1-p)/float64(n-1)))
+}
+
+// bisect finds x in [lo, hi] where f(x) = target, for a function f that
+// decreases as x increases. NaN results are treated as too small.
+func bisect(f func(float64) float64, target, lo, hi float64) float64 {
+	for range 64 {
+		mid := (lo + hi) / 2
+		if v := f(mid); v > target {
+			lo = mid
+		} else {
+			hi = mid
+		}
+	}
+
+	return (lo + hi) / 2
+}
@@ -0,0 +1,175 @@
+package sp80090b
+
+import (
+	"bytes"
+	"math/rand/v2"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// randomBytes returns deterministic pseudo-random bytes
+func randomBytes(n int, seed byte) []byte {
+	b := make([]byte, n)
+	_, _ = rand.NewChaCha8([32]byte{seed}).Read(b)
+
+	return b
+}
+
+// biasedBits returns bits which are 1 with probability p
+func biasedBits(n int, p float64) []byte {
+	r := rand.New(rand.NewChaCha8([32]byte{42}))
+	b := make([]byte, n)
+	for i := range b {
+		if r.Float64() < p {
+			b[i] = 1
+		}
+	}
+
+	return b
+}
+
+// lcg returns n outputs of a 32-bit linear congruential generator (with the
+// Numerical Recipes constants), which is easy to reproduce in other tools
+func lcg(n int, seed uint32) []uint32 {
+	x := seed
+	out := make([]uint32, n)
+	for i := range out {
+		x = x*1664525 + 1013904223
+		out[i] = x
+	}
+
+	return out
+}
+
+func TestEstimators_KnownAnswers(t *testing.T) {
+	// 2-bit samples, where 3 is five times as likely as each other value
+	s := make([]byte, 5000)
+	for i, x := range lcg(len(s), 1) {
+		s[i] = byte(min(x>>29, 3))
+	}
+
+	// bits which repeat the one before with probability 0.7
+	bits := make([]byte, 7000)
+	prev := byte(0)
+	for i, x := range lcg(len(bits), 2) {
+		if x>>24 >= 179 {
+			prev ^= 1
+		}
+		bits[i] = prev
+	}
+
+	// the expected values were worked out independently for these inputs,
+	// following each procedure in 800-90B step by step
+	testdata := []struct {
+		fn      func([]byte, int) float64
+		name    string
+		samples float64
+		bits    float64
+		// binary estimators are only run on the bits
+		binary bool
+	}{
+		{mostCommonValue, "Most Common Value", 0.6540293502267145, 0.9387653987262045, false},
+		{collision, "Collision", 0, 0.2655245543822336, true},
+		{markov, "Markov", 0, 0.5177054066941543, true},
+		{compression, "Compression", 0, 0.24910732977173, true},
+		{tTuple, "t-Tuple", 0.6371667096888916, 0.56731138262914, false},
+		{lrs, "Longest Repeated Substring", 1.1203177632038723, 0.7652346216124971, false},
+		{multiMCW, "Multi Most Common in Window", 0.6519880585484144, 0.9074849362469793, false},
+		{lagPrediction, "Lag Prediction", 1.1515700455400084, 0.4930047775440565, false},
+		{multiMMC, "Multi Markov Model with Counting", 0.6566188946781508, 0.49308965868694166, false},
+		{lz78y, "LZ78Y", 0.6555409319838652, 0.4937921035360946, false},
+	}
+	for _, d := range testdata {
+		t.Run(d.name, func(t *testing.T) {
+			if !d.binary {
+				assert.InDelta(t, d.samples, d.fn(s, 4), 1e-12)
+			}
+			assert.InDelta(t, d.bits, d.fn(bits, 2), 1e-12)
+		})
+	}
+
+	// the worked example from 800-90B 6.3.1, where the most common of the
+	// 20 samples occurs 8 times, so pu = 0.6895
+	example := []byte{0, 1, 1, 2, 0, 1, 2, 2, 0, 1, 0, 1, 1, 0, 2, 2, 1, 0, 2, 1}
+	assert.InDelta(t, 0.5364, mostCommonValue(example, 3), 1e-4)
+}
+
+func TestAssess_Errors(t *testing.T) {
+	_, err := Assess(randomBytes(MinSamples, 1), 0)
+	assert.Error(t, err)
+	_, err = Assess(randomBytes(MinSamples, 1), 9)
+	assert.Error(t, err)
+	_, err = Assess(randomBytes(MinSamples-1, 1), 8)
+	assert.Error(t, err)
+}
+
+func TestAssess_Random(t *testing.T) {
+	r, err := Assess(randomBytes(20000, 1), 8)
+	require.NoError(t, err)
+
+	assert.Equal(t, 20000, r.Samples)
+	assert.Equal(t, 8, r.BitsPerSample)
+	assert.Len(t, r.Original, 7)
+	assert.Len(t, r.Bitstring, 10)
+	for _, e := range append(r.Original, r.Bitstring...) {
+		assert.Greater(t, e.MinEntropyPerBit, 0.75, e.Name)
+		assert.LessOrEqual(t, e.MinEntropyPerBit, 1.0, e.Name)
+	}
+
+	assert.Greater(t, r.MinEntropyPerBit, 0.75)
+	assert.InDelta(t, r.MinEntropy/8, r.MinEntropyPerBit, 1e-9)
+	assert.LessOrEqual(t, r.MinEntropy, r.HOriginal)
+	assert.LessOrEqual(t, r.MinEntropy, r.HBitstring*8)
+}
+
+func TestAssess_Stuck(t *testing.T) {
+	r, err := Assess(bytes.Repeat([]byte{0x5a}, 10000), 8)
+	require.NoError(t, err)
+	assert.Less(t, r.MinEntropyPerBit, 0.01)
+}
+
+func TestAssess_Biased(t *testing.T) {
+	// 1-bit samples are only assessed as a bitstring
+	r, err := Assess(biasedBits(100000, 0.75), 1)
+	require.NoError(t, err)
+	assert.Empty(t, r.Original)
+	assert.Len(t, r.Bitstring, 10)
+
+	// -log2(0.75) = 0.415
+	assert.InDelta(t, 0.4, r.MinEntropyPerBit, 0.2)
+	assert.Less(t, r.MinEntropyPerBit, 0.415)
+}
+
+func TestAssess_Masked(t *testing.T) {
+	// only the low 4 bits are used, and they're random
+	s := randomBytes(20000, 2)
+	for i := range s {
+		s[i] |= 0xf0
+	}
+	r, err := Assess(s, 4)
+	require.NoError(t, err)
+	assert.Greater(t, r.MinEntropyPerBit, 0.7)
+	assert.LessOrEqual(t, r.MinEntropy, 4.0)
+}
+
+func TestMostCommonValueExported(t *testing.T) {
+	assert.Zero(t, MostCommonValue(nil))
+	assert.Less(t, MostCommonValue(bytes.Repeat([]byte{0x5a}, 10000)), 0.01)
+
+	h := MostCommonValue(randomBytes(100000, 1))
+	assert.Greater(t, h, 7.0)
+	assert.LessOrEqual(t, h, 8.0)
+}
+
+func TestBitstring(t *testing.T) {
+	assert.Equal(t, []byte{1, 0, 1, 0, 0, 0, 1, 1}, Bitstring([]byte{0xa3}, 8, 0))
+	assert.Equal(t, []byte{0, 1, 1, 1, 1, 0}, Bitstring([]byte{0x03, 0x06}, 3, 0))
+	assert.Equal(t, []byte{1, 0, 1}, Bitstring([]byte{0xa3, 0xff}, 8, 3))
+}
+
+func TestBisect(t *testing.T) {
+	x := bisect(func(x float64) float64 { return 1 - x*x }, 0.75, 0, 1)
+	assert.InDelta(t, 0.5, x, 1e-9)
+}
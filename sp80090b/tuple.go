@@ -0,0 +1,234 @@
+package sp80090b
+
+import "math"
+
+// The t-Tuple and LRS estimates both need to count repeated substrings of
+// various lengths, which is done with a suffix array.
+
+// tupleCounts - counts of repeated tuples (substrings) of each length
+type tupleCounts struct {
+	// most[t] - the number of occurrences of the most common t-tuple
+	most []int
+	// pairs[w] - the number of pairs of identical w-tuples, i.e. the sum
+	// of C(n, 2) over the number of occurrences n of each w-tuple
+	pairs []float64
+	// longest - the length of the longest repeated substring
+	longest int
+}
+
+func countTuples(s []byte) *tupleCounts {
+	n := len(s)
+	sa := suffixArray(s)
+	lcp := lcpArray(s, sa)
+
+	tc := &tupleCounts{}
+	for _, l := range lcp {
+		tc.longest = max(tc.longest, l)
+	}
+
+	// pairs is built as a difference array, and most as the largest group
+	// with exactly that common prefix length
+	diff := make([]float64, tc.longest+2)
+	most := make([]int, tc.longest+2)
+
+	// walk the lcp-intervals bottom-up - each is a group of suffixes sharing
+	// a prefix of length lcp, which is a group of identical w-tuples for
+	// each w between the parent interval's lcp (exclusive) and its own
+	type interval struct{ lcp, lb int }
+	stack := []interval{{0, 0}}
+	for i := 1; i <= n; i++ {
+		cur := 0
+		if i < n {
+			cur = lcp[i]
+		}
+
+		lb := i - 1
+		for cur < stack[len(stack)-1].lcp {
+			top := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+
+			parent := max(cur, stack[len(stack)-1].lcp)
+			size := i - top.lb
+			c := float64(size) * float64(size-1) / 2
+			diff[parent+1] += c
+			diff[top.lcp+1] -= c
+			most[top.lcp] = max(most[top.lcp], size)
+
+			lb = top.lb
+		}
+		if cur > stack[len(stack)-1].lcp {
+			stack = append(stack, interval{cur, lb})
+		}
+	}
+
+	tc.pairs = make([]float64, tc.longest+1)
+	sum := 0.0
+	for w := 1; w <= tc.longest; w++ {
+		sum += diff[w]
+		tc.pairs[w] = sum
+	}
+
+	// a group with a longer common prefix is also part of a (possibly
+	// larger) group for each shorter tuple length
+	tc.most = make([]int, tc.longest+2)
+	largest := 1
+	for t := tc.longest + 1; t >= 1; t-- {
+		largest = max(largest, most[t])
+		tc.most[t] = largest
+	}
+
+	return tc
+}
+
+// tTuple - the t-Tuple Estimate (800-90B 6.3.5)
+func tTuple(s []byte, _ int) float64 {
+	tc := countTuples(s)
+	l := len(s)
+
+	pmax := 0.0
+	for i := 1; i < len(tc.most) && tc.most[i] >= 35; i++ {
+		p := float64(tc.most[i]) / float64(l-i+1)
+		pmax = math.Max(pmax, math.Pow(p, 1/float64(i)))
+	}
+	if pmax == 0 {
+		return math.NaN()
+	}
+
+	return -math.Log2(upperBound(pmax, l))
+}
+
+// lrs - the Longest Repeated Substring Estimate (800-90B 6.3.6)
+func lrs(s []byte, _ int) float64 {
+	tc := countTuples(s)
+	l := len(s)
+
+	// u is the smallest tuple length where no tuple occurs 35 times
+	u := 1
+	for u < len(tc.most) && tc.most[u] >= 35 {
+		u++
+	}
+	if u > tc.longest {
+		return math.NaN()
+	}
+
+	pmax := 0.0
+	for w := u; w <= tc.longest; w++ {
+		n := float64(l - w + 1)
+		p := tc.pairs[w] / (n * (n - 1) / 2)
+		pmax = math.Max(pmax, math.Pow(p, 1/float64(w)))
+	}
+
+	return -math.Log2(upperBound(pmax, l))
+}
+
+// suffixArray builds the suffix array of s by prefix doubling, with radix
+// sorting
+func suffixArray(s []byte) []int {
+	n := len(s)
+	sa := make([]int, n)
+	rank := make([]int, n)
+	tmp := make([]int, n)
+	if n == 0 {
+		return sa
+	}
+
+	// sort by the first byte
+	counts := make([]int, max(n, 256)+1)
+	for _, b := range s {
+		counts[int(b)+1]++
+	}
+	for i := 1; i <= 256; i++ {
+		counts[i] += counts[i-1]
+	}
+	for i, b := range s {
+		sa[counts[b]] = i
+		counts[b]++
+	}
+	classes := 0
+	for i := range sa {
+		if i > 0 && s[sa[i]] != s[sa[i-1]] {
+			classes++
+		}
+		rank[sa[i]] = classes
+	}
+
+	for k := 1; classes < n-1; k <<= 1 {
+		// order by the second half - suffixes with no second half first
+		j := 0
+		for i := n - k; i < n; i++ {
+			tmp[j] = i
+			j++
+		}
+		for _, p := range sa {
+			if p >= k {
+				tmp[j] = p - k
+				j++
+			}
+		}
+
+		// then stable sort by the first half
+		clear(counts)
+		for _, p := range tmp {
+			counts[rank[p]+1]++
+		}
+		for i := 1; i <= classes+1; i++ {
+			counts[i] += counts[i-1]
+		}
+		for _, p := range tmp {
+			sa[counts[rank[p]]] = p
+			counts[rank[p]]++
+		}
+
+		// re-rank
+		second := func(p int) int {
+			if p+k < n {
+				return rank[p+k]
+			}
+
+			return -1
+		}
+		tmp[sa[0]] = 0
+		classes = 0
+		for i := 1; i < n; i++ {
+			a, b := sa[i-1], sa[i]
+			if rank[a] != rank[b] || second(a) != second(b) {
+				classes++
+			}
+			tmp[b] = classes
+		}
+		rank, tmp = tmp, rank
+	}
+
+	return sa
+}
+
+// lcpArray computes the longest common prefix of each suffix in the suffix
+// array with the one before it (Kasai's algorithm). lcp[0] is 0.
+func lcpArray(s []byte, sa []int) []int {
+	n := len(s)
+	rank := make([]int, n)
+	for i, p := range sa {
+		rank[p] = i
+	}
+
+	lcp := make([]int, n)
+	h := 0
+	for i := range n {
+		if rank[i] == 0 {
+			h = 0
+
+			continue
+		}
+
+		j := sa[rank[i]-1]
+		for i+h < n && j+h < n && s[i+h] == s[j+h] {
+			h++
+		}
+		lcp[rank[i]] = h
+		if h > 0 {
+			h--
+		}
+	}
+
+	return lcp
+}
@@ -0,0 +1,76 @@
+package sp80090b
+
+import (
+	"bytes"
+	"sort"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func naiveSuffixArray(s []byte) []int {
+	sa := make([]int, len(s))
+	for i := range sa {
+		sa[i] = i
+	}
+	sort.Slice(sa, func(a, b int) bool { return bytes.Compare(s[sa[a]:], s[sa[b]:]) < 0 })
+
+	return sa
+}
+
+func TestSuffixArray(t *testing.T) {
+	assert.Equal(t, []int{5, 3, 1, 0, 4, 2}, suffixArray([]byte("banana")))
+	assert.Empty(t, suffixArray(nil))
+	assert.Equal(t, []int{0}, suffixArray([]byte{1}))
+	assert.Equal(t, []int{3, 2, 1, 0}, suffixArray([]byte{0, 0, 0, 0}))
+
+	for _, s := range [][]byte{
+		randomBytes(5000, 3),
+		Bitstring(randomBytes(1000, 4), 8, 0),
+		bytes.Repeat([]byte{1, 2, 3}, 500),
+	} {
+		assert.Equal(t, naiveSuffixArray(s), suffixArray(s))
+	}
+}
+
+func TestLCPArray(t *testing.T) {
+	s := []byte("banana")
+	assert.Equal(t, []int{0, 1, 3, 0, 0, 2}, lcpArray(s, suffixArray(s)))
+}
+
+func TestCountTuples(t *testing.T) {
+	s := Bitstring(randomBytes(300, 5), 8, 0)
+	tc := countTuples(s)
+
+	// compare with counting every tuple
+	for w := 1; w <= tc.longest+1; w++ {
+		counts := map[string]int{}
+		for i := 0; i+w <= len(s); i++ {
+			counts[string(s[i:i+w])]++
+		}
+
+		most, pairs := 0, 0.0
+		for _, n := range counts {
+			most = max(most, n)
+			pairs += float64(n*(n-1)) / 2
+		}
+
+		assert.Equal(t, most, tc.most[w], w)
+		if w <= tc.longest {
+			assert.InDelta(t, pairs, tc.pairs[w], 0, w)
+			assert.Greater(t, most, 1)
+		} else {
+			assert.Equal(t, 1, most)
+		}
+	}
+}
+
+func TestTTupleAndLRS(t *testing.T) {
+	s := randomBytes(100000, 6)
+	assert.Greater(t, tTuple(s, 256), 6.0)
+	assert.Greater(t, lrs(s, 256), 6.0)
+
+	s = bytes.Repeat([]byte("abcd"), 1000)
+	assert.Less(t, tTuple(s, 256), 0.1)
+	assert.Less(t, lrs(s, 256), 0.1)
+}
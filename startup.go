@@ -0,0 +1,142 @@
+package onerng
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+)
+
+// StartupSamples - the number of consecutive samples health-tested by Init,
+// as required for start-up testing by SP 800-90B (4.3)
+const StartupSamples = 1024
+
+// ErrNotReady - the device failed its start-up tests
+var ErrNotReady = errors.New("OneRNG failed start-up tests")
+
+// InitReport - what happened during Init, and why the device was accepted or
+// rejected
+type InitReport struct {
+	// Reason - why the device was accepted or rejected
+	Reason string `json:"reason"`
+	// Duration - how long Init took
+	Duration time.Duration `json:"duration"`
+	// Discarded - the number of warm-up bytes discarded (see WithWarmup)
+	Discarded int64 `json:"discarded"`
+	// Mode - the noise mode the tests ran in
+	Mode NoiseMode `json:"mode"`
+	// Attempts - the number of attempts it took for the device to start
+	// sending data
+	Attempts int `json:"attempts"`
+	// Tested - the number of samples health-tested
+	Tested int `json:"tested"`
+	// RCTCutoff - the repetition count test's cutoff, or 0 if the health
+	// tests didn't run
+	RCTCutoff int `json:"rct_cutoff"`
+	// APTCutoff - the adaptive proportion test's cutoff, or 0 if the health
+	// tests didn't run
+	APTCutoff int `json:"apt_cutoff"`
+	// Accepted - whether the device passed, and is ready
+	Accepted bool `json:"accepted"`
+}
+
+// Init runs the start-up procedure from SP 800-90B (4.3) in the given mode:
+// it waits for the device to start sending data, discards a warm-up (see
+// WithWarmup), then runs the health tests (see HealthTest) on StartupSamples
+// consecutive samples. The device is only marked ready (see Ready) if they
+// pass.
+//
+// The report is always returned, even when the device is rejected. The error
+// wraps ErrNotReady, and the cause (e.g. a *HealthError, or ErrTimeout).
+func (o *OneRNG) Init(ctx context.Context, mode NoiseMode) (*InitReport, error) {
+	o.ready.Store(false)
+
+	start := time.Now()
+	r := &InitReport{Mode: mode}
+	reject := func(err error, reason string, args ...any) (*InitReport, error) {
+		r.Duration = time.Since(start)
+		r.Reason = fmt.Sprintf(reason, args...)
+		o.log().WarnContext(ctx, "device rejected", "reason", r.Reason)
+
+		return r, fmt.Errorf("%w: %s: %w", ErrNotReady, r.Reason, err)
+	}
+
+	err := o.waitForData(ctx, r)
+	if err != nil {
+		return reject(err, "failed waiting for data")
+	}
+	if r.Attempts == 0 {
+		return reject(&timeoutError{err: errors.New("no data")},
+			"no data received after %d attempts", o.getInitAttempts())
+	}
+
+	s, err := o.Open(ctx, mode)
+	if err != nil {
+		return reject(err, "failed to start the noise source")
+	}
+	defer s.Close()
+
+	// the start-up tests are run separately on the samples, so the session's
+	// own health tests are bypassed
+	r.Discarded, err = io.CopyN(io.Discard, readerFunc(s.readUntested), o.getWarmup())
+	if err != nil {
+		return reject(err, "failed after discarding %d of %d warm-up bytes", r.Discarded, o.getWarmup())
+	}
+
+	samples := make([]byte, StartupSamples)
+	r.Tested, err = io.ReadFull(readerFunc(s.readUntested), samples)
+	if err != nil {
+		return reject(err, "failed after reading %d of %d start-up samples", r.Tested, StartupSamples)
+	}
+
+	health := o.healthTest(mode)
+	if health == nil {
+		r.Reason = "data received (no health tests for this mode)"
+	} else {
+		r.RCTCutoff, r.APTCutoff = health.RCTCutoff, health.APTCutoff
+		if err := health.Check(samples); err != nil {
+			return reject(err, "start-up health tests failed")
+		}
+		r.Reason = "start-up health tests passed"
+	}
+
+	r.Accepted = true
+	r.Duration = time.Since(start)
+	o.ready.Store(true)
+	o.log().DebugContext(ctx, "initialized", "attempts", r.Attempts, "discarded", r.Discarded, "tested", r.Tested)
+
+	return r, nil
+}
+
+// waitForData waits for the device to start sending data, recording the
+// number of attempts in the report (0 if no data was received)
+func (o *OneRNG) waitForData(ctx context.Context, r *InitReport) error {
+	return o.do(ctx, func(c *conn) error {
+		attempts := o.getInitAttempts()
+		for i := 1; i <= attempts; i++ {
+			n, err := c.readData(ctx, o.getInitTimeout())
+			if err != nil {
+				return err
+			}
+			if n > 0 {
+				r.Attempts = i
+
+				return nil
+			}
+		}
+
+		return nil
+	})
+}
+
+// Ready returns true once the device has passed the start-up tests (see
+// Init). It's reset when the device disconnects, or when a continuous health
+// test fails.
+//
+// Ready is advisory - Read, Open, and the rest don't check it, so callers
+// that must only use data from a device that's passed its start-up tests (as
+// SP 800-90B requires) need to call Init first, and check its error or Ready.
+func (o *OneRNG) Ready() bool {
+	return o.ready.Load()
+}
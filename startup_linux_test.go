@@ -0,0 +1,79 @@
+package onerng
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/hairyhenderson/go-onerng/emulator"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInit_Emulated(t *testing.T) {
+	o := startEmulator(t, &emulator.Device{})
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	r, err := o.Init(ctx, EnableRF)
+	require.NoError(t, err)
+	assert.True(t, r.Accepted)
+	assert.True(t, o.Ready())
+	assert.Equal(t, int64(DefaultWarmup), r.Discarded)
+	assert.Equal(t, StartupSamples, r.Tested)
+}
+
+func TestInit_EmulatedNoData(t *testing.T) {
+	o := startEmulator(t, &emulator.Device{Source: bytes.NewReader(nil)})
+	o.initAttempts = 2
+	o.initTimeout = 10 * time.Millisecond
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	r, err := o.Init(ctx, Default)
+	require.ErrorIs(t, err, ErrNotReady)
+	require.ErrorIs(t, err, ErrTimeout)
+	assert.False(t, o.Ready())
+	assert.Zero(t, r.Attempts)
+	assert.Equal(t, "no data received after 2 attempts", r.Reason)
+}
+
+func TestInit_EmulatedSilent(t *testing.T) {
+	// the silent modes send nothing, so the warm-up can't complete - it's
+	// larger than whatever the emulator sends before it switches modes
+	o := startEmulator(t, &emulator.Device{})
+	for _, opt := range []Option{WithWarmup(1 << 20), WithReadTimeout(10 * time.Millisecond), WithAllowedTimeouts(2)} {
+		opt(o)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	r, err := o.Init(ctx, Silent)
+	require.ErrorIs(t, err, ErrNotReady)
+	require.ErrorIs(t, err, ErrTimeout)
+	assert.Equal(t, 1, r.Attempts)
+	assert.Less(t, r.Discarded, o.getWarmup())
+	assert.False(t, r.Accepted)
+}
+
+func TestInit_EmulatedHealthFailureAfterInit(t *testing.T) {
+	// good data for long enough to pass the start-up tests, then stuck
+	good := make([]byte, 1<<20)
+	_, err := rand.Read(good)
+	require.NoError(t, err)
+	src := io.MultiReader(bytes.NewReader(good), bytes.NewReader(make([]byte, 1<<20)))
+	o := startEmulator(t, &emulator.Device{Source: src})
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	_, err = o.Init(ctx, Default)
+	require.NoError(t, err)
+	require.True(t, o.Ready())
+
+	_, err = o.Read(ctx, io.Discard, -1, Default)
+	require.ErrorIs(t, err, ErrHealthTest)
+	assert.False(t, o.Ready())
+}
@@ -0,0 +1,76 @@
+package onerng
+
+import (
+	"bytes"
+	"context"
+	"math/rand/v2"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// randomDialer opens a new fake device with plenty of pseudo-random data each
+// time
+func randomDialer() Dialer {
+	return DialerFunc(func(context.Context, string) (Transport, error) {
+		data := make([]byte, 16384)
+		_, _ = rand.NewChaCha8([32]byte{}).Read(data)
+
+		return newFakeDev(string(data)), nil
+	})
+}
+
+func TestInit(t *testing.T) {
+	o := New("/dev/null", WithDialer(randomDialer()), WithWarmup(1000))
+	assert.False(t, o.Ready())
+
+	r, err := o.Init(context.Background(), DisableWhitener)
+	require.NoError(t, err)
+	assert.True(t, o.Ready())
+	assert.Equal(t, &InitReport{
+		Mode:      DisableWhitener,
+		Attempts:  1,
+		Discarded: 1000,
+		Tested:    StartupSamples,
+		RCTCutoff: 21,
+		APTCutoff: 202,
+		Accepted:  true,
+		Reason:    "start-up health tests passed",
+		Duration:  r.Duration,
+	}, r)
+
+	// no health tests
+	o = New("/dev/null", WithDialer(randomDialer()), WithHealthTests(false))
+	r, err = o.Init(context.Background(), Default)
+	require.NoError(t, err)
+	assert.True(t, r.Accepted)
+	assert.Equal(t, "data received (no health tests for this mode)", r.Reason)
+	assert.Zero(t, r.RCTCutoff)
+}
+
+func TestInit_Rejected(t *testing.T) {
+	// stuck after the warm-up
+	data := append(bytes.Repeat([]byte{1, 2, 3, 4}, 25), bytes.Repeat([]byte{0xff}, 2000)...)
+	o := New("/dev/null", WithWarmup(100), WithDialer(DialerFunc(func(context.Context, string) (Transport, error) {
+		return newFakeDev(string(data)), nil
+	})))
+
+	r, err := o.Init(context.Background(), Default)
+	require.ErrorIs(t, err, ErrNotReady)
+	require.ErrorIs(t, err, ErrHealthTest)
+	assert.False(t, o.Ready())
+	assert.False(t, r.Accepted)
+	assert.Equal(t, "start-up health tests failed", r.Reason)
+	assert.Equal(t, int64(100), r.Discarded)
+
+	// too little data
+	o = New("/dev/null", WithWarmup(100), WithDialer(DialerFunc(func(context.Context, string) (Transport, error) {
+		return newFakeDev("not much"), nil
+	})))
+	r, err = o.Init(context.Background(), Default)
+	require.ErrorIs(t, err, ErrNotReady)
+	assert.False(t, r.Accepted)
+	assert.Contains(t, r.Reason, "warm-up bytes")
+
+}
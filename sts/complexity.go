@@ -0,0 +1,111 @@
+package sts
+
+import (
+	"math"
+
+	"github.com/hairyhenderson/go-onerng/internal/special"
+)
+
+// complexityPi - the probabilities of each class of the linear complexity
+// deviation T (the exact values, which 800-22 rounds to 6 digits)
+var complexityPi = []float64{1.0 / 96, 1.0 / 32, 1.0 / 8, 1.0 / 2, 1.0 / 4, 1.0 / 16, 1.0 / 48}
+
+// LinearComplexity - the Linear Complexity Test (800-22 2.10), with blocks of
+// m bits
+func LinearComplexity(seq []byte, m int) (float64, error) {
+	blocks := len(seq) / m
+	if m < 1 || blocks < 1 {
+		return 0, notApplicable("sequence shorter than a block")
+	}
+
+	mf := float64(m)
+	sign := 1.0
+	if m%2 == 1 {
+		sign = -1
+	}
+	mu := mf/2 + (9-sign)/36 - (mf/3+2.0/9)/math.Exp2(mf)
+
+	v := make([]float64, len(complexityPi))
+	for i := range blocks {
+		l := float64(berlekampMassey(seq[i*m : (i+1)*m]))
+		t := sign*(l-mu) + 2.0/9
+
+		// the classes are T <= -2.5, (-2.5, -1.5], ..., T > 2.5
+		class := len(complexityPi) - 1
+		for c := range class {
+			if t <= float64(c)-2.5 {
+				class = c
+
+				break
+			}
+		}
+		v[class]++
+	}
+
+	chi := 0.0
+	for i, p := range complexityPi {
+		e := float64(blocks) * p
+		chi += (v[i] - e) * (v[i] - e) / e
+	}
+
+	return special.Igamc(float64(len(complexityPi)-1)/2, chi/2), nil
+}
+
+// berlekampMassey returns the linear complexity of the sequence, i.e. the
+// length of the shortest LFSR that generates it. The polynomials are kept as
+// bitsets, where bit i is the coefficient of x^i.
+func berlekampMassey(seq []byte) int {
+	words := len(seq)/64 + 1
+	c := make([]uint64, words)
+	b := make([]uint64, words)
+	t := make([]uint64, words)
+	// r holds the sequence so far, reversed - bit i is seq[n-i]
+	r := make([]uint64, words)
+	c[0], b[0] = 1, 1
+
+	l, last := 0, -1
+	for n, s := range seq {
+		shiftLeft(r, 1)
+		r[0] |= uint64(s)
+
+		// the discrepancy
+		d := uint64(0)
+		for i := range c {
+			d ^= c[i] & r[i]
+		}
+		if parity(d) == 0 {
+			continue
+		}
+
+		copy(t, c)
+		xorShifted(c, b, n-last)
+		if l <= n/2 {
+			l = n + 1 - l
+			last = n
+			copy(b, t)
+		}
+	}
+
+	return l
+}
+
+// shiftLeft shifts the bitset left by k bits (k < 64), discarding any bits
+// shifted out of the end
+func shiftLeft(x []uint64, k int) {
+	for i := len(x) - 1; i > 0; i-- {
+		x[i] = x[i]<<k | x[i-1]>>(64-k)
+	}
+	x[0] <<= k
+}
+
+// xorShifted sets dst ^= src << k, discarding any bits shifted out of the end
+func xorShifted(dst, src []uint64, k int) {
+	words, bits := k/64, k%64
+	for i := len(dst) - 1; i >= words; i-- {
+		v := src[i-words] << bits
+		if bits > 0 && i-words > 0 {
+			v |= src[i-words-1] >> (64 - bits)
+		}
+		dst[i] ^= v
+	}
+}
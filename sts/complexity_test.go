@@ -0,0 +1,35 @@
+package sts
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBerlekampMassey(t *testing.T) {
+	assert.Equal(t, 4, berlekampMassey(parseBits("1101011110001")))
+	assert.Equal(t, 0, berlekampMassey(make([]byte, 100)))
+
+	// a single one at the end needs an LFSR as long as the sequence
+	seq := make([]byte, 200)
+	seq[199] = 1
+	assert.Equal(t, 200, berlekampMassey(seq))
+
+	// an LFSR sequence (x^7 + x + 1) has the LFSR's length
+	seq = make([]byte, 300)
+	seq[0] = 1
+	for i := 7; i < len(seq); i++ {
+		seq[i] = seq[i-6] ^ seq[i-7]
+	}
+	assert.Equal(t, 7, berlekampMassey(seq))
+}
+
+func TestLinearComplexity(t *testing.T) {
+	p, err := LinearComplexity(randomBits(1, 500000), 500)
+	require.NoError(t, err)
+	assert.Greater(t, p, 0.001)
+
+	_, err = LinearComplexity(randomBits(1, 499), 500)
+	require.ErrorIs(t, err, ErrNotApplicable)
+}
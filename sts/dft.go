@@ -0,0 +1,118 @@
+package sts
+
+import (
+	"math"
+	"math/bits"
+	"math/cmplx"
+)
+
+// DFT - the Discrete Fourier Transform (Spectral) Test (800-22 2.6)
+func DFT(seq []byte) (float64, error) {
+	n := len(seq)
+	if n < 2 {
+		return 0, notApplicable("sequence too short")
+	}
+
+	x := make([]complex128, n)
+	for i, b := range seq {
+		x[i] = complex(2*float64(b)-1, 0)
+	}
+	s := dft(x)
+
+	// the 95% peak height threshold
+	t := math.Sqrt(math.Log(1/0.05) * float64(n))
+	n0 := 0.95 * float64(n) / 2
+	n1 := 0
+	for _, v := range s[:n/2] {
+		if cmplx.Abs(v) < t {
+			n1++
+		}
+	}
+
+	d := (float64(n1) - n0) / math.Sqrt(float64(n)*0.95*0.05/4)
+
+	return math.Erfc(math.Abs(d) / math.Sqrt2), nil
+}
+
+// dft computes the discrete Fourier transform of x, of any length (using
+// Bluestein's algorithm when the length isn't a power of 2)
+func dft(x []complex128) []complex128 {
+	n := len(x)
+	if n&(n-1) == 0 {
+		out := make([]complex128, n)
+		copy(out, x)
+		fft(out, false)
+
+		return out
+	}
+
+	// express the DFT as a convolution, which can be computed with
+	// power-of-2 FFTs
+	m := 1 << bits.Len(uint(2*n-1))
+	w := make([]complex128, n)
+	for k := range w {
+		// k^2 mod 2n, to keep the angle accurate for large k
+		kk := (uint64(k) * uint64(k)) % uint64(2*n)
+		w[k] = cmplx.Exp(complex(0, -math.Pi*float64(kk)/float64(n)))
+	}
+
+	a := make([]complex128, m)
+	for k, v := range x {
+		a[k] = v * w[k]
+	}
+	b := make([]complex128, m)
+	b[0] = cmplx.Conj(w[0])
+	for k := 1; k < n; k++ {
+		b[k] = cmplx.Conj(w[k])
+		b[m-k] = b[k]
+	}
+
+	fft(a, false)
+	fft(b, false)
+	for i := range a {
+		a[i] *= b[i]
+	}
+	fft(a, true)
+
+	out := make([]complex128, n)
+	for k := range out {
+		out[k] = a[k] * w[k] / complex(float64(m), 0)
+	}
+
+	return out
+}
+
+// fft - an in-place radix-2 FFT. The length must be a power of 2. The inverse
+// transform isn't scaled.
+func fft(a []complex128, inverse bool) {
+	n := len(a)
+	if n < 2 {
+		return
+	}
+
+	shift := 64 - bits.Len(uint(n-1))
+	for i := range a {
+		j := int(bits.Reverse64(uint64(i)) >> shift)
+		if i < j {
+			a[i], a[j] = a[j], a[i]
+		}
+	}
+
+	sign := -1.0
+	if inverse {
+		sign = 1
+	}
+	for size := 2; size <= n; size <<= 1 {
+		step := cmplx.Exp(complex(0, sign*2*math.Pi/float64(size)))
+		for start := 0; start < n; start += size {
+			w := complex(1, 0)
+			for k := range size / 2 {
+				u := a[start+k]
+				v := a[start+k+size/2] * w
+				a[start+k] = u + v
+				a[start+k+size/2] = u - v
+				w *= step
+			}
+		}
+	}
+}
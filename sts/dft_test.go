@@ -0,0 +1,57 @@
+package sts
+
+import (
+	"math"
+	"math/cmplx"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func naiveDFT(x []complex128) []complex128 {
+	n := len(x)
+	out := make([]complex128, n)
+	for k := range out {
+		for j, v := range x {
+			out[k] += v * cmplx.Exp(complex(0, -2*math.Pi*float64(j*k)/float64(n)))
+		}
+	}
+
+	return out
+}
+
+func TestDFT_Transform(t *testing.T) {
+	for _, n := range []int{1, 2, 8, 12, 100, 257} {
+		x := make([]complex128, n)
+		for i, b := range randomBits(uint64(n), n) {
+			x[i] = complex(2*float64(b)-1, 0)
+		}
+
+		expected := naiveDFT(x)
+		actual := dft(x)
+		require.Len(t, actual, n)
+		for i := range expected {
+			assert.InDelta(t, real(expected[i]), real(actual[i]), 1e-9, "n=%d, i=%d", n, i)
+			assert.InDelta(t, imag(expected[i]), imag(actual[i]), 1e-9, "n=%d, i=%d", n, i)
+		}
+	}
+}
+
+func TestDFT(t *testing.T) {
+	p, err := DFT(randomBits(1, 100000))
+	require.NoError(t, err)
+	assert.Greater(t, p, 0.001)
+
+	// a periodic sequence has a few very large peaks
+	seq := make([]byte, 100000)
+	for i := range seq {
+		seq[i] = byte(i / 3 % 2)
+	}
+	p, err = DFT(seq)
+	require.NoError(t, err)
+	assert.Less(t, p, 1e-6)
+
+	_, err = DFT(nil)
+	require.ErrorIs(t, err, ErrNotApplicable)
+}
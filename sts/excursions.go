@@ -0,0 +1,123 @@
+package sts
+
+import (
+	"math"
+
+	"github.com/hairyhenderson/go-onerng/internal/special"
+)
+
+// excursionsPi - the probability that a state x is visited k times in a cycle,
+// indexed by |x| and k (the last is k >= 5)
+var excursionsPi = [5][6]float64{
+	{},
+	{0.5000000000, 0.25000000000, 0.12500000000, 0.06250000000, 0.03125000000, 0.0312500000},
+	{0.7500000000, 0.06250000000, 0.04687500000, 0.03515625000, 0.02636718750, 0.0791015625},
+	{0.8333333333, 0.02777777778, 0.02314814815, 0.01929012346, 0.01607510288, 0.0803755143},
+	{0.8750000000, 0.01562500000, 0.01367187500, 0.01196289063, 0.01046752930, 0.0732727051},
+}
+
+// excursionStates - the states for the Random Excursions Test, in the order
+// of the p-values
+var excursionStates = []int{-4, -3, -2, -1, 1, 2, 3, 4}
+
+// RandomExcursions - the Random Excursions Test (800-22 2.14). There's one
+// p-value per state (-4 to -1, then 1 to 4).
+func RandomExcursions(seq []byte) ([]float64, error) {
+	// nu[x][k] - the number of cycles in which state x was visited k times
+	var nu [9][6]float64
+
+	var visits [9]int
+	cycles := walkCycles(seq, func(s int) {
+		if s != 0 && abs(s) <= 4 {
+			visits[s+4]++
+		}
+	}, func() {
+		for i, v := range visits {
+			nu[i][min(v, 5)]++
+		}
+		visits = [9]int{}
+	})
+	if err := checkCycles(len(seq), cycles); err != nil {
+		return nil, err
+	}
+
+	j := float64(cycles)
+	p := make([]float64, len(excursionStates))
+	for i, x := range excursionStates {
+		pi := excursionsPi[abs(x)]
+
+		chi := 0.0
+		for k := range pi {
+			e := j * pi[k]
+			chi += (nu[x+4][k] - e) * (nu[x+4][k] - e) / e
+		}
+		p[i] = special.Igamc(2.5, chi/2)
+	}
+
+	return p, nil
+}
+
+// RandomExcursionsVariant - the Random Excursions Variant Test (800-22 2.15).
+// There's one p-value per state (-9 to -1, then 1 to 9).
+func RandomExcursionsVariant(seq []byte) ([]float64, error) {
+	var visits [19]int
+	cycles := walkCycles(seq, func(s int) {
+		if abs(s) <= 9 {
+			visits[s+9]++
+		}
+	}, nil)
+	if err := checkCycles(len(seq), cycles); err != nil {
+		return nil, err
+	}
+
+	j := float64(cycles)
+	p := make([]float64, 0, 18)
+	for x := -9; x <= 9; x++ {
+		if x == 0 {
+			continue
+		}
+
+		d := math.Abs(float64(visits[x+9]) - j)
+		p = append(p, math.Erfc(d/math.Sqrt(2*j*float64(4*abs(x)-2))))
+	}
+
+	return p, nil
+}
+
+// walkCycles follows the random walk of the sequence's partial sums, calling
+// visit for each state, and endCycle at the end of each cycle (i.e. a return
+// to zero, or the end of the sequence). It returns the number of cycles.
+func walkCycles(seq []byte, visit func(s int), endCycle func()) int {
+	cycles := 0
+	s := 0
+	for _, b := range seq {
+		s += 2*int(b) - 1
+		visit(s)
+
+		if s == 0 {
+			cycles++
+			if endCycle != nil {
+				endCycle()
+			}
+		}
+	}
+	if s != 0 {
+		cycles++
+		if endCycle != nil {
+			endCycle()
+		}
+	}
+
+	return cycles
+}
+
+// checkCycles returns an error when there are too few cycles for the random
+// excursions tests to be applied
+func checkCycles(n, cycles int) error {
+	constraint := math.Max(0.005*math.Sqrt(float64(n)), 500)
+	if float64(cycles) < constraint {
+		return notApplicable("%d cycles, but at least %.0f are needed", cycles, constraint)
+	}
+
+	return nil
+}
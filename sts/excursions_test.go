@@ -0,0 +1,48 @@
+package sts
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWalkCycles(t *testing.T) {
+	// the example from 800-22 2.14.4 - the walk is
+	// -1 0 1 0 1 2 1 2 1 2, so there are 3 cycles
+	var states []int
+	cycles := walkCycles(parseBits("0110110101"), func(s int) {
+		states = append(states, s)
+	}, nil)
+	assert.Equal(t, 3, cycles)
+	assert.Equal(t, []int{-1, 0, 1, 0, 1, 2, 1, 2, 1, 2}, states)
+}
+
+func TestRandomExcursions(t *testing.T) {
+	_, err := RandomExcursions(randomBits(1, 1000))
+	require.ErrorIs(t, err, ErrNotApplicable)
+
+	// the number of cycles is random, and often too low (this seed has
+	// only 56)
+	_, err = RandomExcursions(randomBits(2, 1000000))
+	require.ErrorIs(t, err, ErrNotApplicable)
+
+	p, err := RandomExcursions(randomBits(1, 1000000))
+	require.NoError(t, err)
+	require.Len(t, p, 8)
+	for _, v := range p {
+		assert.Greater(t, v, 0.0001)
+	}
+}
+
+func TestRandomExcursionsVariant(t *testing.T) {
+	_, err := RandomExcursionsVariant(randomBits(1, 1000))
+	require.ErrorIs(t, err, ErrNotApplicable)
+
+	p, err := RandomExcursionsVariant(randomBits(1, 1000000))
+	require.NoError(t, err)
+	require.Len(t, p, 18)
+	for _, v := range p {
+		assert.Greater(t, v, 0.0001)
+	}
+}
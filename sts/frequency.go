@@ -0,0 +1,176 @@
+package sts
+
+import (
+	"math"
+
+	"github.com/hairyhenderson/go-onerng/internal/special"
+)
+
+// Frequency - the Frequency (Monobit) Test (800-22 2.1)
+func Frequency(seq []byte) (float64, error) {
+	n := len(seq)
+	if n == 0 {
+		return 0, notApplicable("empty sequence")
+	}
+
+	s := 0
+	for _, b := range seq {
+		s += 2*int(b) - 1
+	}
+
+	obs := math.Abs(float64(s)) / math.Sqrt(float64(n))
+
+	return math.Erfc(obs / math.Sqrt2), nil
+}
+
+// BlockFrequency - the Frequency Test within a Block (800-22 2.2), with
+// blocks of m bits
+func BlockFrequency(seq []byte, m int) (float64, error) {
+	blocks := len(seq) / m
+	if m < 1 || blocks < 1 {
+		return 0, notApplicable("sequence shorter than a block")
+	}
+
+	chi := 0.0
+	for i := range blocks {
+		ones := 0
+		for _, b := range seq[i*m : (i+1)*m] {
+			ones += int(b)
+		}
+		v := float64(ones)/float64(m) - 0.5
+		chi += v * v
+	}
+	chi *= 4 * float64(m)
+
+	return special.Igamc(float64(blocks)/2, chi/2), nil
+}
+
+// Runs - the Runs Test (800-22 2.3). As in the reference implementation, the
+// p-value is 0 when the sequence fails the frequency prerequisite.
+func Runs(seq []byte) (float64, error) {
+	n := float64(len(seq))
+	if n == 0 {
+		return 0, notApplicable("empty sequence")
+	}
+
+	ones := 0
+	for _, b := range seq {
+		ones += int(b)
+	}
+	pi := float64(ones) / n
+	if math.Abs(pi-0.5) >= 2/math.Sqrt(n) {
+		return 0, nil
+	}
+
+	v := 1
+	for i := 1; i < len(seq); i++ {
+		if seq[i] != seq[i-1] {
+			v++
+		}
+	}
+
+	num := math.Abs(float64(v) - 2*n*pi*(1-pi))
+	den := 2 * math.Sqrt(2*n) * pi * (1 - pi)
+
+	return math.Erfc(num / den), nil
+}
+
+// LongestRun - the Test for the Longest Run of Ones in a Block (800-22 2.4).
+// The block size depends on the length of the sequence.
+func LongestRun(seq []byte) (float64, error) {
+	n := len(seq)
+
+	var (
+		m, lo int
+		pi    []float64
+	)
+	switch {
+	case n < 128:
+		return 0, notApplicable("sequence shorter than 128 bits")
+	case n < 6272:
+		m, lo = 8, 1
+		pi = []float64{0.21484375, 0.3671875, 0.23046875, 0.1875}
+	case n < 750000:
+		m, lo = 128, 4
+		pi = []float64{0.1174035788, 0.242955959, 0.249363483, 0.17517706, 0.102701071, 0.112398847}
+	default:
+		m, lo = 10000, 10
+		pi = []float64{0.0882, 0.2092, 0.2483, 0.1933, 0.1208, 0.0675, 0.0727}
+	}
+	k := len(pi) - 1
+
+	blocks := n / m
+	v := make([]float64, len(pi))
+	for i := range blocks {
+		longest, run := 0, 0
+		for _, b := range seq[i*m : (i+1)*m] {
+			if b == 1 {
+				run++
+				longest = max(longest, run)
+			} else {
+				run = 0
+			}
+		}
+
+		v[min(max(longest-lo, 0), k)]++
+	}
+
+	chi := 0.0
+	for i, p := range pi {
+		e := float64(blocks) * p
+		chi += (v[i] - e) * (v[i] - e) / e
+	}
+
+	return special.Igamc(float64(k)/2, chi/2), nil
+}
+
+// CumulativeSums - the Cumulative Sums (Cusum) Test (800-22 2.13), in the
+// forward and backward modes
+func CumulativeSums(seq []byte) (forward, backward float64, err error) {
+	n := len(seq)
+	if n == 0 {
+		return 0, 0, notApplicable("empty sequence")
+	}
+
+	// the maximum excursion from zero, going forwards and backwards
+	s, zf := 0, 0
+	for _, b := range seq {
+		s += 2*int(b) - 1
+		zf = max(zf, abs(s))
+	}
+	s, zb := 0, 0
+	for i := n - 1; i >= 0; i-- {
+		s += 2*int(seq[i]) - 1
+		zb = max(zb, abs(s))
+	}
+
+	return cusumP(n, zf), cusumP(n, zb), nil
+}
+
+func cusumP(n, z int) float64 {
+	if z == 0 {
+		return 1
+	}
+
+	sqrtN := math.Sqrt(float64(n))
+	zf := float64(z)
+
+	sum1 := 0.0
+	for k := (-n/z + 1) / 4; k <= (n/z-1)/4; k++ {
+		sum1 += normalCDF(float64(4*k+1)*zf/sqrtN) - normalCDF(float64(4*k-1)*zf/sqrtN)
+	}
+	sum2 := 0.0
+	for k := (-n/z - 3) / 4; k <= (n/z-1)/4; k++ {
+		sum2 += normalCDF(float64(4*k+3)*zf/sqrtN) - normalCDF(float64(4*k+1)*zf/sqrtN)
+	}
+
+	return 1 - sum1 + sum2
+}
+
+func abs(x int) int {
+	if x < 0 {
+		return -x
+	}
+
+	return x
+}
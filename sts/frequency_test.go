@@ -0,0 +1,74 @@
+package sts
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// parseBits converts a string of '0's and '1's to a sequence
+func parseBits(s string) []byte {
+	s = strings.Join(strings.Fields(s), "")
+	seq := make([]byte, len(s))
+	for i, c := range s {
+		seq[i] = byte(c - '0')
+	}
+
+	return seq
+}
+
+// the examples are from the test descriptions in 800-22
+
+func TestFrequency(t *testing.T) {
+	p, err := Frequency(parseBits("1011010101"))
+	require.NoError(t, err)
+	assert.InDelta(t, 0.527089, p, 1e-6)
+
+	_, err = Frequency(nil)
+	require.ErrorIs(t, err, ErrNotApplicable)
+}
+
+func TestBlockFrequency(t *testing.T) {
+	p, err := BlockFrequency(parseBits("0110011010"), 3)
+	require.NoError(t, err)
+	assert.InDelta(t, 0.801252, p, 1e-6)
+
+	_, err = BlockFrequency(parseBits("01"), 3)
+	require.ErrorIs(t, err, ErrNotApplicable)
+}
+
+func TestRuns(t *testing.T) {
+	p, err := Runs(parseBits("1001101011"))
+	require.NoError(t, err)
+	assert.InDelta(t, 0.147232, p, 1e-6)
+
+	// fails the frequency prerequisite
+	p, err = Runs(parseBits(strings.Repeat("1110", 100)))
+	require.NoError(t, err)
+	assert.Zero(t, p)
+}
+
+func TestLongestRun(t *testing.T) {
+	p, err := LongestRun(parseBits(`
+		11001100000101010110110001001100111000000000001001001101010100010001
+		001111010110100000001101011111001100111001101101100010110010`))
+	require.NoError(t, err)
+	assert.InDelta(t, 0.180609, p, 1e-6)
+
+	_, err = LongestRun(parseBits("0101"))
+	require.ErrorIs(t, err, ErrNotApplicable)
+}
+
+func TestCumulativeSums(t *testing.T) {
+	fwd, _, err := CumulativeSums(parseBits("1011010111"))
+	require.NoError(t, err)
+	assert.InDelta(t, 0.4116588, fwd, 1e-6)
+
+	fwd, bwd, err := CumulativeSums(parseBits(`
+		1100100100001111110110101010001000100001011010001100001000110100110001001100011001100010100010111000`))
+	require.NoError(t, err)
+	assert.InDelta(t, 0.219194, fwd, 1e-6)
+	assert.InDelta(t, 0.114866, bwd, 1e-6)
+}
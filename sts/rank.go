@@ -0,0 +1,98 @@
+package sts
+
+import (
+	"math"
+	"math/bits"
+)
+
+// rankSize - the matrices are 32x32
+const rankSize = 32
+
+// Rank - the Binary Matrix Rank Test (800-22 2.5), with 32x32 matrices
+func Rank(seq []byte) (float64, error) {
+	n := len(seq) / (rankSize * rankSize)
+	if n < 38 {
+		return 0, notApplicable("at least 38 matrices (38,912 bits) are needed")
+	}
+
+	// the probabilities of full rank, rank-1, and lower
+	p32 := rankProbability(rankSize)
+	p31 := rankProbability(rankSize - 1)
+	p30 := 1 - p32 - p31
+
+	var f32, f31 float64
+	for i := range n {
+		var m [rankSize]uint32
+		block := seq[i*rankSize*rankSize:]
+		for r := range m {
+			for c := range rankSize {
+				m[r] = m[r]<<1 | uint32(block[r*rankSize+c])
+			}
+		}
+
+		switch rankGF2(m) {
+		case rankSize:
+			f32++
+		case rankSize - 1:
+			f31++
+		}
+	}
+	f30 := float64(n) - f32 - f31
+
+	nf := float64(n)
+	chi := (f32-p32*nf)*(f32-p32*nf)/(p32*nf) +
+		(f31-p31*nf)*(f31-p31*nf)/(p31*nf) +
+		(f30-p30*nf)*(f30-p30*nf)/(p30*nf)
+
+	return math.Exp(-chi / 2), nil
+}
+
+// rankProbability - the probability that a random 32x32 binary matrix has
+// rank r
+func rankProbability(r int) float64 {
+	m, q := float64(rankSize), float64(rankSize)
+	rf := float64(r)
+
+	p := math.Exp2(rf*(q+m-rf) - m*q)
+	for i := range r {
+		fi := float64(i)
+		p *= (1 - math.Exp2(fi-q)) * (1 - math.Exp2(fi-m)) / (1 - math.Exp2(fi-rf))
+	}
+
+	return p
+}
+
+// rankGF2 computes the rank of the matrix over GF(2), by Gaussian elimination
+func rankGF2(m [rankSize]uint32) int {
+	rank := 0
+	for col := rankSize - 1; col >= 0; col-- {
+		bit := uint32(1) << col
+
+		pivot := -1
+		for r := rank; r < rankSize; r++ {
+			if m[r]&bit != 0 {
+				pivot = r
+
+				break
+			}
+		}
+		if pivot < 0 {
+			continue
+		}
+
+		m[rank], m[pivot] = m[pivot], m[rank]
+		for r := range rankSize {
+			if r != rank && m[r]&bit != 0 {
+				m[r] ^= m[rank]
+			}
+		}
+		rank++
+	}
+
+	return rank
+}
+
+// parity - the parity of the set bits
+func parity(x uint64) uint64 {
+	return uint64(bits.OnesCount64(x) & 1)
+}
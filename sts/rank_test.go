@@ -0,0 +1,45 @@
+package sts
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRankGF2(t *testing.T) {
+	var m [rankSize]uint32
+	assert.Equal(t, 0, rankGF2(m))
+
+	for i := range m {
+		m[i] = 1 << i
+	}
+	assert.Equal(t, rankSize, rankGF2(m))
+
+	// a repeated row
+	m[5] = m[7]
+	assert.Equal(t, rankSize-1, rankGF2(m))
+
+	// a row that's the sum of two others
+	m[5] = m[1] ^ m[2] ^ m[3]
+	assert.Equal(t, rankSize-1, rankGF2(m))
+}
+
+func TestRankProbability(t *testing.T) {
+	assert.InDelta(t, 0.2888, rankProbability(rankSize), 1e-4)
+	assert.InDelta(t, 0.5776, rankProbability(rankSize-1), 1e-4)
+}
+
+func TestRank(t *testing.T) {
+	_, err := Rank(randomBits(1, 1024*37))
+	require.ErrorIs(t, err, ErrNotApplicable)
+
+	p, err := Rank(randomBits(1, 1024*100))
+	require.NoError(t, err)
+	assert.Greater(t, p, 0.001)
+
+	// all-zero matrices are all rank 0
+	p, err = Rank(make([]byte, 1024*100))
+	require.NoError(t, err)
+	assert.Less(t, p, 1e-6)
+}
@@ -0,0 +1,259 @@
+package sts
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"slices"
+	"strings"
+
+	"github.com/hairyhenderson/go-onerng/internal/special"
+)
+
+// minUniformitySequences - the uniformity of the p-values isn't computed for
+// fewer sequences than this, as recommended by 800-22 (4.2.2)
+const minUniformitySequences = 55
+
+// minUniformityP - the uniformity P-value below which the p-values are
+// considered not uniformly distributed
+const minUniformityP = 0.0001
+
+// Report - summarizes the results of running the tests on many sequences,
+// like the reference implementation's finalAnalysisReport.txt. There's one
+// line for each p-value a test produces, showing how the p-values are
+// distributed, whether they're uniformly distributed, and the proportion of
+// sequences that passed.
+type Report struct {
+	lines map[lineKey]*line
+	// Source - a description of where the sequences came from, for the
+	// report's header
+	Source string
+	// Alpha - the significance level
+	Alpha     float64
+	sequences int
+}
+
+type lineKey struct {
+	test  string
+	index int
+}
+
+type line struct {
+	bins   [10]int
+	passed int
+	total  int
+}
+
+// NewReport creates an empty Report with the given significance level
+func NewReport(alpha float64) *Report {
+	return &Report{Alpha: alpha, lines: map[lineKey]*line{}}
+}
+
+// Add adds the results from one sequence. Tests which couldn't be applied to
+// the sequence are left out.
+func (r *Report) Add(results []Result) {
+	r.sequences++
+
+	for _, res := range results {
+		if res.Err != nil {
+			continue
+		}
+
+		for i, p := range res.PValues {
+			k := lineKey{res.Test, i}
+			l, ok := r.lines[k]
+			if !ok {
+				l = &line{}
+				r.lines[k] = l
+			}
+
+			l.bins[min(int(p*10), 9)]++
+			l.total++
+			if p >= r.Alpha {
+				l.passed++
+			}
+		}
+	}
+}
+
+// Sequences - the number of sequences added
+func (r *Report) Sequences() int {
+	return r.sequences
+}
+
+// Passed returns true when every line has an acceptable proportion of passing
+// sequences, and (when there are enough sequences to tell) uniformly
+// distributed p-values
+func (r *Report) Passed() bool {
+	return r.failures() == 0
+}
+
+// Err returns an error wrapping ErrFailed if the report didn't pass, or nil
+func (r *Report) Err() error {
+	n := r.failures()
+	if n == 0 {
+		return nil
+	}
+
+	return fmt.Errorf("%w: %d of %d p-value lines failed", ErrFailed, n, len(r.lines))
+}
+
+func (r *Report) failures() int {
+	n := 0
+	for _, l := range r.lines {
+		u, ok := uniformity(l)
+		if !r.proportionOK(l.passed, l.total) || (ok && u < minUniformityP) {
+			n++
+		}
+	}
+
+	return n
+}
+
+// WriteTo writes the report, in the same format as the reference
+// implementation
+func (r *Report) WriteTo(w io.Writer) (int64, error) {
+	sb := &strings.Builder{}
+
+	rule := strings.Repeat("-", 78)
+	fmt.Fprintln(sb, rule)
+	fmt.Fprintln(sb, "RESULTS FOR THE UNIFORMITY OF P-VALUES AND THE PROPORTION OF PASSING SEQUENCES")
+	fmt.Fprintln(sb, rule)
+	if r.Source != "" {
+		fmt.Fprintf(sb, "   generator is <%s>\n", r.Source)
+		fmt.Fprintln(sb, rule)
+	}
+	fmt.Fprintln(sb, " C1  C2  C3  C4  C5  C6  C7  C8  C9 C10  P-VALUE  PROPORTION  STATISTICAL TEST")
+	fmt.Fprintln(sb, rule)
+
+	for _, k := range r.sortedKeys() {
+		l := r.lines[k]
+		for _, c := range l.bins {
+			fmt.Fprintf(sb, "%3d ", c)
+		}
+
+		u, ok := uniformity(l)
+		switch {
+		case !ok:
+			sb.WriteString("    ----    ")
+		case u < minUniformityP:
+			fmt.Fprintf(sb, " %8.6f * ", u)
+		default:
+			fmt.Fprintf(sb, " %8.6f   ", u)
+		}
+
+		flag := " "
+		if !r.proportionOK(l.passed, l.total) {
+			flag = "*"
+		}
+		fmt.Fprintf(sb, "%4d/%-4d %s  %s\n", l.passed, l.total, flag, k.test)
+	}
+
+	sep := strings.TrimSpace(strings.Repeat("- ", 40))
+	fmt.Fprintln(sb, sep)
+	fmt.Fprintf(sb, "The minimum pass rate for each statistical test with the exception of the\n"+
+		"random excursion (variant) test is approximately = %d for a\n"+
+		"sample size = %d binary sequences.\n\n",
+		r.minPassed(r.sequences), r.sequences)
+
+	if n := r.excursionSequences(); n > 0 {
+		fmt.Fprintf(sb, "The minimum pass rate for the random excursion (variant) test\n"+
+			"is approximately = %d for a sample size = %d binary sequences.\n\n",
+			r.minPassed(n), n)
+	} else {
+		fmt.Fprint(sb, "The minimum pass rate for the random excursion (variant) test is undefined.\n\n")
+	}
+
+	fmt.Fprintln(sb, "For further guidelines construct a probability table using the MAPLE program\n"+
+		"provided in the addendum section of the documentation.")
+	fmt.Fprintln(sb, sep)
+
+	n, err := io.WriteString(w, sb.String())
+
+	return int64(n), err
+}
+
+// sortedKeys returns the lines' keys in the order of the tests, then the
+// p-values
+func (r *Report) sortedKeys() []lineKey {
+	order := func(name string) int {
+		i := slices.IndexFunc(tests, func(t test) bool { return t.name == name })
+		if i < 0 {
+			return len(tests)
+		}
+
+		return i
+	}
+
+	keys := make([]lineKey, 0, len(r.lines))
+	for k := range r.lines {
+		keys = append(keys, k)
+	}
+	slices.SortFunc(keys, func(a, b lineKey) int {
+		if c := order(a.test) - order(b.test); c != 0 {
+			return c
+		}
+		if c := strings.Compare(a.test, b.test); c != 0 {
+			return c
+		}
+
+		return a.index - b.index
+	})
+
+	return keys
+}
+
+// proportionRange returns the confidence interval for the number of passing
+// sequences out of n
+func (r *Report) proportionRange(n int) (lo, hi float64) {
+	p := 1 - r.Alpha
+	d := 3 * math.Sqrt(p*r.Alpha/float64(n))
+
+	return (p - d) * float64(n), (p + d) * float64(n)
+}
+
+func (r *Report) proportionOK(passed, n int) bool {
+	if n == 0 {
+		return true
+	}
+
+	lo, hi := r.proportionRange(n)
+
+	return float64(passed) >= lo && float64(passed) <= hi
+}
+
+func (r *Report) minPassed(n int) int {
+	if n == 0 {
+		return 0
+	}
+
+	lo, _ := r.proportionRange(n)
+
+	return max(int(lo), 0)
+}
+
+// excursionSequences - the number of sequences the random excursion tests
+// could be applied to
+func (r *Report) excursionSequences() int {
+	if l, ok := r.lines[lineKey{"RandomExcursions", 0}]; ok {
+		return l.total
+	}
+
+	return 0
+}
+
+// uniformity returns the P-value of a chi-square test that the p-values are
+// uniformly distributed, or false if there are too few to tell
+func uniformity(l *line) (float64, bool) {
+	if l.total < minUniformitySequences {
+		return 0, false
+	}
+
+	e := float64(l.total) / 10
+	chi := 0.0
+	for _, c := range l.bins {
+		chi += (float64(c) - e) * (float64(c) - e) / e
+	}
+
+	return special.Igamc(9.0/2, chi/2), true
+}
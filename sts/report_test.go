@@ -0,0 +1,80 @@
+package sts
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReport(t *testing.T) {
+	r := NewReport(DefaultAlpha)
+	r.Source = "test"
+
+	// p-values spread evenly, with 1 in 100 failing
+	for i := range 100 {
+		p := (float64(i) + 0.5) / 100
+		r.Add([]Result{
+			{Test: "Frequency", PValues: []float64{p}},
+			{Test: "Serial", PValues: []float64{p, 1 - p}},
+			{Test: "RandomExcursions", Err: ErrNotApplicable},
+		})
+	}
+	assert.Equal(t, 100, r.Sequences())
+	assert.True(t, r.Passed())
+	require.NoError(t, r.Err())
+
+	out := &strings.Builder{}
+	_, err := r.WriteTo(out)
+	require.NoError(t, err)
+
+	lines := strings.Split(out.String(), "\n")
+	assert.Equal(t, "   generator is <test>", lines[3])
+	assert.Equal(t, " 10  10  10  10  10  10  10  10  10  10  1.000000     99/100     Frequency", lines[7])
+	assert.Equal(t, " 10  10  10  10  10  10  10  10  10  10  1.000000     99/100     Serial", lines[8])
+	assert.Equal(t, " 10  10  10  10  10  10  10  10  10  10  1.000000     99/100     Serial", lines[9])
+	assert.Contains(t, out.String(), "approximately = 96 for a\nsample size = 100 binary sequences.")
+	assert.Contains(t, out.String(), "random excursion (variant) test is undefined.")
+}
+
+func TestReport_Failures(t *testing.T) {
+	r := NewReport(DefaultAlpha)
+	for range 100 {
+		r.Add([]Result{
+			{Test: "Runs", PValues: []float64{0.001}},
+			{Test: "Frequency", PValues: []float64{0.55}},
+		})
+	}
+	assert.False(t, r.Passed())
+	require.ErrorIs(t, r.Err(), ErrFailed)
+	assert.EqualError(t, r.Err(), "SP 800-22 tests failed: 2 of 2 p-value lines failed")
+
+	out := &strings.Builder{}
+	_, err := r.WriteTo(out)
+	require.NoError(t, err)
+
+	lines := strings.Split(out.String(), "\n")
+	// in the order of the tests, not as added
+	assert.Equal(t, "  0   0   0   0   0 100   0   0   0   0  0.000000 *  100/100     Frequency", lines[5])
+	assert.Equal(t, "100   0   0   0   0   0   0   0   0   0  0.000000 *    0/100  *  Runs", lines[6])
+}
+
+func TestReport_FewSequences(t *testing.T) {
+	r := NewReport(DefaultAlpha)
+	r.Add([]Result{
+		{Test: "Frequency", PValues: []float64{0.9}},
+		{Test: "RandomExcursions", PValues: []float64{0.3, 0.5}},
+		{Test: "Rank", Err: errors.New("nope")},
+	})
+	assert.True(t, r.Passed())
+
+	out := &strings.Builder{}
+	_, err := r.WriteTo(out)
+	require.NoError(t, err)
+
+	assert.Contains(t, out.String(), "  0   0   0   0   0   0   0   0   0   1     ----       1/1       Frequency\n")
+	assert.NotContains(t, out.String(), "Rank")
+	assert.Contains(t, out.String(), "is approximately = 0 for a sample size = 1 binary sequences.")
+}
@@ -0,0 +1,81 @@
+package sts
+
+import (
+	"math"
+
+	"github.com/hairyhenderson/go-onerng/internal/special"
+)
+
+// ApproximateEntropy - the Approximate Entropy Test (800-22 2.12), with
+// overlapping blocks of m bits
+func ApproximateEntropy(seq []byte, m int) (float64, error) {
+	n := len(seq)
+	if m < 1 || m > 24 || n < m+1 {
+		return 0, notApplicable("block length %d out of range", m)
+	}
+
+	phi := func(m int) float64 {
+		sum := 0.0
+		for _, c := range patternCounts(seq, m) {
+			if c > 0 {
+				sum += float64(c) * math.Log(float64(c)/float64(n))
+			}
+		}
+
+		return sum / float64(n)
+	}
+
+	apEn := phi(m) - phi(m+1)
+	chi := 2 * float64(n) * (math.Ln2 - apEn)
+
+	return special.Igamc(math.Exp2(float64(m-1)), chi/2), nil
+}
+
+// Serial - the Serial Test (800-22 2.11), with overlapping patterns of m bits
+func Serial(seq []byte, m int) (p1, p2 float64, err error) {
+	n := len(seq)
+	if m < 2 || m > 24 || n < m {
+		return 0, 0, notApplicable("pattern length %d out of range", m)
+	}
+
+	psi := func(m int) float64 {
+		if m <= 0 {
+			return 0
+		}
+
+		sum := 0.0
+		for _, c := range patternCounts(seq, m) {
+			sum += float64(c) * float64(c)
+		}
+
+		return sum*math.Exp2(float64(m))/float64(n) - float64(n)
+	}
+
+	psi0, psi1, psi2 := psi(m), psi(m-1), psi(m-2)
+	del1 := psi0 - psi1
+	del2 := psi0 - 2*psi1 + psi2
+
+	p1 = special.Igamc(math.Exp2(float64(m-2)), del1/2)
+	p2 = special.Igamc(math.Exp2(float64(m-3)), del2/2)
+
+	return p1, p2, nil
+}
+
+// patternCounts counts the occurrences of each overlapping m-bit pattern,
+// wrapping around at the end of the sequence
+func patternCounts(seq []byte, m int) []int {
+	n := len(seq)
+	counts := make([]int, 1<<m)
+	mask := 1<<m - 1
+
+	w := 0
+	for i := range m - 1 {
+		w = w<<1 | int(seq[i%n])
+	}
+	for i := range n {
+		w = (w<<1 | int(seq[(i+m-1)%n])) & mask
+		counts[w]++
+	}
+
+	return counts
+}
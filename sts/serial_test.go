@@ -0,0 +1,33 @@
+package sts
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPatternCounts(t *testing.T) {
+	// 0011011101 wraps around to 0011011101 00...
+	assert.Equal(t, []int{0, 1, 1, 2, 1, 2, 2, 1}, patternCounts(parseBits("0011011101"), 3))
+	assert.Equal(t, []int{4, 6}, patternCounts(parseBits("0011011101"), 1))
+}
+
+func TestSerial(t *testing.T) {
+	p1, p2, err := Serial(parseBits("0011011101"), 3)
+	require.NoError(t, err)
+	assert.InDelta(t, 0.808792, p1, 1e-6)
+	assert.InDelta(t, 0.670320, p2, 1e-6)
+
+	_, _, err = Serial(parseBits("0011011101"), 1)
+	require.ErrorIs(t, err, ErrNotApplicable)
+}
+
+func TestApproximateEntropy(t *testing.T) {
+	p, err := ApproximateEntropy(parseBits("0100110101"), 3)
+	require.NoError(t, err)
+	assert.InDelta(t, 0.261961, p, 1e-6)
+
+	_, err = ApproximateEntropy(parseBits("01"), 3)
+	require.ErrorIs(t, err, ErrNotApplicable)
+}
@@ -0,0 +1,149 @@
+/*
+Package sts implements the 15 statistical tests from NIST SP 800-22 rev. 1a
+("A Statistical Test Suite for Random and Pseudorandom Number Generators for
+Cryptographic Applications"), with the default parameters of the NIST
+reference implementation (sts-2.1.2).
+
+Each test takes a sequence of bits (one per byte, as produced by Bits) and
+returns one or more p-values. A sequence passes a test when a p-value is at
+least the significance level (usually 0.01). Run runs all tests on a sequence,
+and a Report summarizes the results over many sequences in the same way as the
+reference implementation's finalAnalysisReport.txt:
+
+	rep := sts.NewReport(sts.DefaultAlpha)
+	for _, seq := range sequences {
+		rep.Add(sts.Run(sts.Bits(seq)))
+	}
+	rep.WriteTo(os.Stdout)
+
+800-22 recommends sequences of at least 1,000,000 bits, and some tests can't
+be applied to shorter ones.
+*/
+package sts
+
+import (
+	"errors"
+	"fmt"
+	"math"
+)
+
+// DefaultAlpha - the default significance level
+const DefaultAlpha = 0.01
+
+// default test parameters, from the reference implementation
+const (
+	DefaultBlockFrequencyBlockLen    = 128
+	DefaultNonOverlappingTemplateLen = 9
+	DefaultOverlappingTemplateLen    = 9
+	DefaultApproximateEntropyLen     = 10
+	DefaultSerialLen                 = 16
+	DefaultLinearComplexityBlockLen  = 500
+)
+
+// ErrNotApplicable - the test can't be applied to the sequence (usually
+// because it's too short)
+var ErrNotApplicable = errors.New("test not applicable")
+
+// ErrFailed - the sequences failed the tests (see Report)
+var ErrFailed = errors.New("SP 800-22 tests failed")
+
+func notApplicable(format string, args ...any) error {
+	return fmt.Errorf("%w: %s", ErrNotApplicable, fmt.Sprintf(format, args...))
+}
+
+// Result - the p-values from running one test on a sequence
+type Result struct {
+	// Err - set if the test couldn't be run
+	Err error
+	// Test - the test's name, as in the reference implementation's reports
+	Test string
+	// PValues - the p-values - most tests produce one, but some produce
+	// many (e.g. one per template, or per state)
+	PValues []float64
+}
+
+// test - a test, with the default parameters
+type test struct {
+	fn   func(seq []byte) ([]float64, error)
+	name string
+}
+
+func single(fn func([]byte) (float64, error)) func([]byte) ([]float64, error) {
+	return func(seq []byte) ([]float64, error) {
+		p, err := fn(seq)
+		if err != nil {
+			return nil, err
+		}
+
+		return []float64{p}, nil
+	}
+}
+
+// tests - in the same order as the reference implementation's reports
+var tests = []test{
+	{name: "Frequency", fn: single(Frequency)},
+	{name: "BlockFrequency", fn: single(func(b []byte) (float64, error) {
+		return BlockFrequency(b, DefaultBlockFrequencyBlockLen)
+	})},
+	{name: "CumulativeSums", fn: func(b []byte) ([]float64, error) {
+		fwd, bwd, err := CumulativeSums(b)
+
+		return []float64{fwd, bwd}, err
+	}},
+	{name: "Runs", fn: single(Runs)},
+	{name: "LongestRun", fn: single(LongestRun)},
+	{name: "Rank", fn: single(Rank)},
+	{name: "FFT", fn: single(DFT)},
+	{name: "NonOverlappingTemplate", fn: func(b []byte) ([]float64, error) {
+		return NonOverlappingTemplate(b, DefaultNonOverlappingTemplateLen)
+	}},
+	{name: "OverlappingTemplate", fn: single(func(b []byte) (float64, error) {
+		return OverlappingTemplate(b, DefaultOverlappingTemplateLen)
+	})},
+	{name: "Universal", fn: single(Universal)},
+	{name: "ApproximateEntropy", fn: single(func(b []byte) (float64, error) {
+		return ApproximateEntropy(b, DefaultApproximateEntropyLen)
+	})},
+	{name: "RandomExcursions", fn: RandomExcursions},
+	{name: "RandomExcursionsVariant", fn: RandomExcursionsVariant},
+	{name: "Serial", fn: func(b []byte) ([]float64, error) {
+		p1, p2, err := Serial(b, DefaultSerialLen)
+
+		return []float64{p1, p2}, err
+	}},
+	{name: "LinearComplexity", fn: single(func(b []byte) (float64, error) {
+		return LinearComplexity(b, DefaultLinearComplexityBlockLen)
+	})},
+}
+
+// Run runs all the tests on the sequence, with the default parameters
+func Run(seq []byte) []Result {
+	results := make([]Result, len(tests))
+	for i, t := range tests {
+		p, err := t.fn(seq)
+		if err != nil {
+			p = nil
+		}
+		results[i] = Result{Test: t.name, PValues: p, Err: err}
+	}
+
+	return results
+}
+
+// Bits expands data into a sequence of bits (one per byte), most significant
+// first
+func Bits(data []byte) []byte {
+	bits := make([]byte, 0, len(data)*8)
+	for _, b := range data {
+		for i := 7; i >= 0; i-- {
+			bits = append(bits, (b>>i)&1)
+		}
+	}
+
+	return bits
+}
+
+// normalCDF - the standard normal cumulative distribution function
+func normalCDF(x float64) float64 {
+	return 0.5 * math.Erfc(-x/math.Sqrt2)
+}
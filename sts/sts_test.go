@@ -0,0 +1,146 @@
+package sts
+
+import (
+	"math"
+	"math/big"
+	"math/rand/v2"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// eBits returns the first n bits of the binary expansion of e (including the
+// integer part), which is the sequence used for the examples in 800-22
+// Appendix B
+func eBits(n int) []byte {
+	// sum 1/k! by binary splitting, until k! is much larger than 2^n
+	k := 2
+	for lg := 0.0; lg < float64(n)+64; k++ {
+		lg += math.Log2(float64(k))
+	}
+
+	var split func(a, b int) (t, q *big.Int)
+	split = func(a, b int) (t, q *big.Int) {
+		if b-a == 1 {
+			return big.NewInt(1), big.NewInt(int64(b))
+		}
+
+		mid := (a + b) / 2
+		t1, q1 := split(a, mid)
+		t2, q2 := split(mid, b)
+
+		return t1.Mul(t1, q2).Add(t1, t2), q1.Mul(q1, q2)
+	}
+	t, q := split(0, k)
+
+	// e = 1 + t/q, and the integer part is 2 bits long
+	x := new(big.Int).Add(t, q)
+	x.Lsh(x, uint(n-2)).Quo(x, q)
+
+	seq := make([]byte, n)
+	for i := range seq {
+		seq[i] = byte(x.Bit(n - 1 - i))
+	}
+
+	return seq
+}
+
+// randomBits returns n pseudo-random bits from a seeded generator
+func randomBits(seed uint64, n int) []byte {
+	r := rand.New(rand.NewChaCha8([32]byte{byte(seed)}))
+	seq := make([]byte, n)
+	for i := range seq {
+		seq[i] = byte(r.Uint32() & 1)
+	}
+
+	return seq
+}
+
+func TestBits(t *testing.T) {
+	assert.Equal(t, []byte{1, 0, 0, 0, 0, 0, 0, 1, 0, 1, 1, 1, 1, 1, 1, 1}, Bits([]byte{0x81, 0x7f}))
+	assert.Empty(t, Bits(nil))
+}
+
+func TestAppendixB(t *testing.T) {
+	if testing.Short() {
+		t.Skip("slow")
+	}
+
+	// the examples in 800-22 use the first 1,000,000 bits of e
+	seq := eBits(1000000)
+	require.Equal(t, []byte{1, 0, 1, 0, 1, 1, 0, 1, 1, 1, 1, 1, 1, 0, 0, 0}, seq[:16])
+
+	one := func(p float64, err error) float64 {
+		require.NoError(t, err)
+
+		return p
+	}
+	nth := func(i int) func([]float64, error) float64 {
+		return func(p []float64, err error) float64 {
+			require.NoError(t, err)
+
+			return p[i]
+		}
+	}
+
+	fwd, bwd, err := CumulativeSums(seq)
+	require.NoError(t, err)
+	p1, p2, err := Serial(seq, 16)
+	require.NoError(t, err)
+
+	testdata := []struct {
+		name     string
+		expected float64
+		actual   float64
+	}{
+		{"Frequency", 0.953749, one(Frequency(seq))},
+		{"BlockFrequency", 0.619340, one(BlockFrequency(seq, 100))},
+		{"CumulativeSums (forward)", 0.669886, fwd},
+		{"CumulativeSums (backward)", 0.724265, bwd},
+		{"Runs", 0.561917, one(Runs(seq))},
+		{"LongestRun", 0.718945, one(LongestRun(seq))},
+		{"Rank", 0.306156, one(Rank(seq))},
+		{"NonOverlappingTemplate (000000001)", 0.078790, nth(0)(NonOverlappingTemplate(seq, 9))},
+		{"OverlappingTemplate", 0.110434, one(OverlappingTemplate(seq, 9))},
+		{"Universal", 0.282568, one(Universal(seq))},
+		{"RandomExcursionsVariant (x=-1)", 0.826009, nth(8)(RandomExcursionsVariant(seq))},
+		{"Serial (P1)", 0.766182, p1},
+		{"Serial (P2)", 0.462921, p2},
+		{"LinearComplexity", 0.826202, one(LinearComplexity(seq, 500))},
+	}
+
+	for _, d := range testdata {
+		assert.InDelta(t, d.expected, d.actual, 2e-6, d.name)
+	}
+}
+
+func TestRun(t *testing.T) {
+	results := Run(randomBits(1, 1000000))
+	require.Len(t, results, 15)
+
+	counts := map[string]int{
+		"CumulativeSums":          2,
+		"NonOverlappingTemplate":  148,
+		"RandomExcursions":        8,
+		"RandomExcursionsVariant": 18,
+		"Serial":                  2,
+	}
+	for _, r := range results {
+		require.NoError(t, r.Err, r.Test)
+
+		n, ok := counts[r.Test]
+		if !ok {
+			n = 1
+		}
+		assert.Len(t, r.PValues, n, r.Test)
+	}
+
+	// too short for most tests
+	results = Run(randomBits(1, 1000))
+	assert.Equal(t, "Frequency", results[0].Test)
+	require.NoError(t, results[0].Err)
+	assert.Equal(t, "Rank", results[5].Test)
+	require.ErrorIs(t, results[5].Err, ErrNotApplicable)
+	assert.Nil(t, results[5].PValues)
+}
@@ -0,0 +1,158 @@
+package sts
+
+import (
+	"math"
+
+	"github.com/hairyhenderson/go-onerng/internal/special"
+)
+
+// nonOverlappingBlocks - the number of blocks the sequence is split into for
+// the Non-overlapping Template Matching Test
+const nonOverlappingBlocks = 8
+
+// NonOverlappingTemplate - the Non-overlapping Template Matching Test (800-22
+// 2.7), with every aperiodic template of m bits (as in the reference
+// implementation's template files). There's one p-value per template, in
+// ascending order of template.
+func NonOverlappingTemplate(seq []byte, m int) ([]float64, error) {
+	if m < 2 || m > 21 {
+		return nil, notApplicable("template length %d out of range", m)
+	}
+
+	blockLen := len(seq) / nonOverlappingBlocks
+	if blockLen < m {
+		return nil, notApplicable("sequence too short")
+	}
+
+	templates := aperiodicTemplates(m)
+
+	// an aperiodic template can't overlap itself, so counting every
+	// occurrence is the same as counting non-overlapping ones
+	counts := make([][]int, nonOverlappingBlocks)
+	mask := uint32(1)<<m - 1
+	for i := range counts {
+		counts[i] = make([]int, 1<<m)
+		block := seq[i*blockLen : (i+1)*blockLen]
+
+		w := uint32(0)
+		for j, b := range block {
+			w = (w<<1 | uint32(b)) & mask
+			if j >= m-1 {
+				counts[i][w]++
+			}
+		}
+	}
+
+	pow := math.Exp2(float64(m))
+	mu := float64(blockLen-m+1) / pow
+	variance := float64(blockLen) * (1/pow - float64(2*m-1)/(pow*pow))
+
+	p := make([]float64, len(templates))
+	for j, t := range templates {
+		chi := 0.0
+		for i := range counts {
+			d := float64(counts[i][t]) - mu
+			chi += d * d / variance
+		}
+		p[j] = special.Igamc(nonOverlappingBlocks/2.0, chi/2)
+	}
+
+	return p, nil
+}
+
+// aperiodicTemplates returns the m-bit templates which can't overlap
+// themselves (i.e. no proper prefix is also a suffix), in ascending order
+func aperiodicTemplates(m int) []uint32 {
+	var templates []uint32
+	for t := uint32(0); t < 1<<m; t++ {
+		aperiodic := true
+		for k := 1; k < m; k++ {
+			// compare the first k bits with the last k bits
+			if t>>(m-k) == t&(1<<k-1) {
+				aperiodic = false
+
+				break
+			}
+		}
+		if aperiodic {
+			templates = append(templates, t)
+		}
+	}
+
+	return templates
+}
+
+// overlappingBlockLen - the block length for the Overlapping Template
+// Matching Test
+const overlappingBlockLen = 1032
+
+// OverlappingTemplate - the Overlapping Template Matching Test (800-22 2.8),
+// with a template of m ones
+func OverlappingTemplate(seq []byte, m int) (float64, error) {
+	const k = 5
+
+	blocks := len(seq) / overlappingBlockLen
+	if m < 1 || m > overlappingBlockLen || blocks < 1 {
+		return 0, notApplicable("sequence too short")
+	}
+
+	v := make([]float64, k+1)
+	for i := range blocks {
+		block := seq[i*overlappingBlockLen : (i+1)*overlappingBlockLen]
+
+		w, run := 0, 0
+		for _, b := range block {
+			if b == 1 {
+				run++
+				if run >= m {
+					w++
+				}
+			} else {
+				run = 0
+			}
+		}
+		v[min(w, k)]++
+	}
+
+	lambda := float64(overlappingBlockLen-m+1) / math.Exp2(float64(m))
+	eta := lambda / 2
+
+	pi := make([]float64, k+1)
+	sum := 0.0
+	for u := range k {
+		pi[u] = overlappingProbability(u, eta)
+		sum += pi[u]
+	}
+	pi[k] = 1 - sum
+
+	chi := 0.0
+	for i := range v {
+		e := float64(blocks) * pi[i]
+		chi += (v[i] - e) * (v[i] - e) / e
+	}
+
+	return special.Igamc(k/2.0, chi/2), nil
+}
+
+// overlappingProbability - the probability that the template occurs u times
+// in a block (the Pr function in the reference implementation)
+func overlappingProbability(u int, eta float64) float64 {
+	if u == 0 {
+		return math.Exp(-eta)
+	}
+
+	lg := func(x float64) float64 {
+		v, _ := math.Lgamma(x)
+
+		return v
+	}
+
+	uf := float64(u)
+	sum := 0.0
+	for l := 1; l <= u; l++ {
+		lf := float64(l)
+		sum += math.Exp(-eta - uf*math.Ln2 + lf*math.Log(eta) - lg(lf+1) + lg(uf) - lg(lf) - lg(uf-lf+1))
+	}
+
+	return sum
+}
@@ -0,0 +1,51 @@
+package sts
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAperiodicTemplates(t *testing.T) {
+	assert.Equal(t, []uint32{0b01, 0b10}, aperiodicTemplates(2))
+	assert.Equal(t, []uint32{0b001, 0b011, 0b100, 0b110}, aperiodicTemplates(3))
+
+	// the sizes of the reference implementation's template files
+	assert.Len(t, aperiodicTemplates(9), 148)
+	assert.Len(t, aperiodicTemplates(10), 284)
+}
+
+func TestNonOverlappingTemplate(t *testing.T) {
+	p, err := NonOverlappingTemplate(randomBits(1, 100000), 9)
+	require.NoError(t, err)
+	require.Len(t, p, 148)
+
+	passed := 0
+	for _, v := range p {
+		if v >= DefaultAlpha {
+			passed++
+		}
+	}
+	assert.GreaterOrEqual(t, passed, 140)
+
+	_, err = NonOverlappingTemplate(randomBits(1, 40), 9)
+	require.ErrorIs(t, err, ErrNotApplicable)
+}
+
+func TestOverlappingTemplate(t *testing.T) {
+	p, err := OverlappingTemplate(randomBits(1, 1000000), 9)
+	require.NoError(t, err)
+	assert.Greater(t, p, 0.001)
+
+	// the probabilities sum to 1
+	sum := 0.0
+	for u := range 5 {
+		sum += overlappingProbability(u, 1)
+	}
+	assert.Less(t, sum, 1.0)
+	assert.InDelta(t, 0.367879, overlappingProbability(0, 1), 1e-6)
+
+	_, err = OverlappingTemplate(randomBits(1, 1000), 9)
+	require.ErrorIs(t, err, ErrNotApplicable)
+}
@@ -0,0 +1,77 @@
+package sts
+
+import (
+	"math"
+)
+
+// universalParams - the expected value and variance of the test statistic for
+// each block length L, starting at L=6
+var universalParams = []struct{ expected, variance float64 }{
+	{5.2177052, 2.954},
+	{6.1962507, 3.125},
+	{7.1836656, 3.238},
+	{8.1764248, 3.311},
+	{9.1723243, 3.356},
+	{10.170032, 3.384},
+	{11.168765, 3.401},
+	{12.168070, 3.410},
+	{13.167693, 3.416},
+	{14.167488, 3.419},
+	{15.167379, 3.421},
+}
+
+// universalMinLen - the minimum sequence length for each block length L,
+// starting at L=6
+var universalMinLen = []int{
+	387840, 904960, 2068480, 4654080, 10342400, 22753280,
+	49643520, 107560960, 231669760, 496435200, 1059061760,
+}
+
+// Universal - Maurer's "Universal Statistical" Test (800-22 2.9). The block
+// length depends on the length of the sequence.
+func Universal(seq []byte) (float64, error) {
+	n := len(seq)
+
+	l := 0
+	for i, minLen := range universalMinLen {
+		if n >= minLen {
+			l = i + 6
+		}
+	}
+	if l == 0 {
+		return 0, notApplicable("at least %d bits are needed", universalMinLen[0])
+	}
+
+	q := 10 << l
+	k := n/l - q
+
+	// the position (1-based) where each L-bit value was last seen
+	last := make([]int, 1<<l)
+	value := func(i int) int {
+		v := 0
+		for _, b := range seq[i*l : (i+1)*l] {
+			v = v<<1 | int(b)
+		}
+
+		return v
+	}
+
+	for i := range q {
+		last[value(i)] = i + 1
+	}
+
+	sum := 0.0
+	for i := q; i < q+k; i++ {
+		v := value(i)
+		sum += math.Log2(float64(i + 1 - last[v]))
+		last[v] = i + 1
+	}
+	phi := sum / float64(k)
+
+	lf, kf := float64(l), float64(k)
+	params := universalParams[l-6]
+	c := 0.7 - 0.8/lf + (4+32/lf)*math.Pow(kf, -3/lf)/15
+	sigma := c * math.Sqrt(params.variance/kf)
+
+	return math.Erfc(math.Abs(phi-params.expected) / (math.Sqrt2 * sigma)), nil
+}
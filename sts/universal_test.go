@@ -0,0 +1,26 @@
+package sts
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUniversal(t *testing.T) {
+	_, err := Universal(randomBits(1, 387839))
+	require.ErrorIs(t, err, ErrNotApplicable)
+
+	p, err := Universal(randomBits(1, 387840))
+	require.NoError(t, err)
+	assert.Greater(t, p, 0.001)
+
+	// a repeating pattern is very compressible
+	seq := make([]byte, 400000)
+	for i := range seq {
+		seq[i] = byte(i / 7 % 2)
+	}
+	p, err = Universal(seq)
+	require.NoError(t, err)
+	assert.Less(t, p, 1e-6)
+}
@@ -0,0 +1,161 @@
+package onerng
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"time"
+)
+
+// Transport - a connection to a OneRNG. This is usually the device node
+// itself, but could be anything that carries the OneRNG's serial stream (a
+// PTY, a TCP connection to a serial bridge such as ser2net, a recorded
+// session, etc...).
+//
+// A single goroutine reads from the Transport while it's open. Close must
+// unblock any Read that's in progress, unless the Transport implements
+// ReadDeadliner, in which case the reader will notice within a short time.
+type Transport interface {
+	io.ReadWriteCloser
+}
+
+// ReadDeadliner is implemented by Transports that support read deadlines.
+// When available, reads are done with a deadline so that they don't block
+// forever when the device stops sending data or the connection is closed.
+// *os.File and net.Conn both implement this.
+type ReadDeadliner interface {
+	SetReadDeadline(t time.Time) error
+}
+
+// Dialer - opens a Transport to a OneRNG
+type Dialer interface {
+	// Dial opens a connection to the OneRNG at the given path. The meaning of
+	// the path is specific to the Dialer.
+	Dial(ctx context.Context, path string) (Transport, error)
+}
+
+// DialerFunc - an adapter to allow ordinary functions to be used as Dialers
+type DialerFunc func(ctx context.Context, path string) (Transport, error)
+
+// Dial calls f(ctx, path)
+func (f DialerFunc) Dial(ctx context.Context, path string) (Transport, error) {
+	return f(ctx, path)
+}
+
+// FileDialer - the default Dialer, which opens the device node at the given
+// path for reading and writing.
+//
+// When the device is a tty, it's put into raw mode (as with
+// `stty raw -echo clocal`) so that commands aren't echoed back and the random
+// stream isn't mangled by the line discipline. The original terminal settings
+// are restored when the Transport is closed. Any stale data waiting in the
+// tty's input queue (such as the tail end of a previous session's output) is
+// discarded.
+//
+// The device is also locked for exclusive access, so that other processes
+// can't interleave commands or steal data. A UUCP-style lock file (i.e.
+// /var/lock/LCK..ttyACM0) is used, falling back to flock(2) when the lock
+// directory isn't writable. The tty is then put into exclusive mode, so that
+// unprivileged processes can't open it at all. If the device is already
+// locked (or in exclusive mode), a *LockError is returned.
+type FileDialer struct {
+	// LockDir is the directory for lock files - defaults to DefaultLockDir
+	LockDir string
+	// LockTimeout is how long to wait for another process to release the
+	// device. By default, fail immediately if the device is locked.
+	LockTimeout time.Duration
+	// SkipLock disables locking
+	SkipLock bool
+	// SkipRawMode leaves the terminal settings alone
+	SkipRawMode bool
+}
+
+// Dial opens the device node at path
+func (d FileDialer) Dial(ctx context.Context, path string) (Transport, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	df, err := d.open(ctx, path)
+	if err != nil {
+		return nil, err
+	}
+	f := df.File
+
+	if !d.SkipRawMode {
+		restore, err := makeRaw(f)
+		if err != nil {
+			_ = df.Close()
+
+			return nil, fmt.Errorf("failed to set raw mode on %s: %w", path, err)
+		}
+		if restore != nil {
+			df.release = append(df.release, restore)
+		}
+	}
+
+	if err := flushInput(f); err != nil {
+		_ = df.Close()
+
+		return nil, fmt.Errorf("failed to flush %s: %w", path, err)
+	}
+
+	if len(df.release) == 0 && df.unlock == nil {
+		return f, nil
+	}
+
+	return df, nil
+}
+
+// open opens the device node, locking it unless SkipLock is set
+func (d FileDialer) open(ctx context.Context, path string) (*deviceFile, error) {
+	if d.SkipLock {
+		f, err := os.OpenFile(path, os.O_RDWR, 0o600)
+		if err != nil {
+			return nil, classifyOpenErr(err)
+		}
+
+		return &deviceFile{File: f}, nil
+	}
+
+	f, clearExcl, unlock, err := openLocked(ctx, path, d.LockDir, d.LockTimeout)
+	if err != nil {
+		return nil, err
+	}
+
+	return &deviceFile{File: f, release: []func() error{clearExcl}, unlock: unlock}, nil
+}
+
+// deviceFile is an open device node, along with any resources (locks,
+// terminal settings) that need to be released when it's closed
+type deviceFile struct {
+	*os.File
+	// release is called in reverse order before the file is closed
+	release []func() error
+	// unlock is called after the file is closed
+	unlock func() error
+}
+
+// Close releases resources, closes the file, and finally unlocks the device
+func (f *deviceFile) Close() (err error) {
+	for i := len(f.release) - 1; i >= 0; i-- {
+		if rerr := f.release[i](); err == nil {
+			err = rerr
+		}
+	}
+	f.release = nil
+
+	if cerr := f.File.Close(); cerr != nil {
+		err = cerr
+	}
+
+	if f.unlock != nil {
+		if uerr := f.unlock(); err == nil {
+			err = uerr
+		}
+		f.unlock = nil
+	}
+
+	return err
+}
@@ -0,0 +1,74 @@
+package onerng
+
+import (
+	"bytes"
+	"context"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOpen_Dialer(t *testing.T) {
+	d := newFakeDev("")
+	dialed := ""
+	o := &OneRNG{
+		Path: "tcp://localhost:2000",
+		Dialer: DialerFunc(func(_ context.Context, path string) (Transport, error) {
+			dialed = path
+
+			return d, nil
+		}),
+	}
+
+	c, err := o.open(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, "tcp://localhost:2000", dialed)
+	assert.Equal(t, d, c.t)
+
+	require.NoError(t, c.close())
+	assert.True(t, d.isClosed())
+}
+
+func TestFileDialer(t *testing.T) {
+	ctx := context.Background()
+	_, err := FileDialer{SkipLock: true}.Dial(ctx, filepath.Join(t.TempDir(), "missing"))
+	assert.ErrorIs(t, err, os.ErrNotExist)
+	assert.ErrorIs(t, err, ErrDeviceNotFound)
+
+	p := filepath.Join(t.TempDir(), "dev")
+	require.NoError(t, os.WriteFile(p, nil, 0o600))
+	tr, err := FileDialer{SkipLock: true}.Dial(ctx, p)
+	require.NoError(t, err)
+	assert.Implements(t, (*ReadDeadliner)(nil), tr)
+	assert.NoError(t, tr.Close())
+
+	cctx, cancel := context.WithCancel(ctx)
+	cancel()
+	_, err = FileDialer{SkipLock: true}.Dial(cctx, p)
+	assert.ErrorIs(t, err, context.Canceled)
+}
+
+func TestCopyWithContext_Deadline(t *testing.T) {
+	// a net.Conn supports read deadlines, so a silent peer should cause the
+	// copy to give up rather than block forever
+	client, server := net.Pipe()
+	defer client.Close()
+	defer server.Close()
+
+	go func() {
+		_, _ = server.Write([]byte("hello"))
+	}()
+
+	out := &bytes.Buffer{}
+	n, err := copyWithContext(context.Background(), out, client, 10, 10*time.Millisecond, 2, nil)
+	require.Error(t, err)
+	assert.True(t, os.IsTimeout(err), err)
+	assert.ErrorIs(t, err, ErrTimeout)
+	assert.Equal(t, int64(5), n)
+	assert.Equal(t, "hello", out.String())
+}
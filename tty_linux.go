@@ -0,0 +1,51 @@
+package onerng
+
+import (
+	"errors"
+	"os"
+	"syscall"
+
+	"github.com/hairyhenderson/go-onerng/internal/termios"
+)
+
+// makeRaw puts the tty in raw mode, with no echo, no flow control, and modem
+// control lines ignored - the equivalent of `stty raw -echo clocal`. The
+// returned function restores the original settings. If f is not a tty,
+// nothing is done and restore is nil.
+func makeRaw(f *os.File) (restore func() error, err error) {
+	saved, err := termios.Get(f)
+	if isNotTTY(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	t := *saved
+	termios.MakeRaw(&t)
+	t.Cflag |= syscall.CLOCAL | syscall.CREAD
+	t.Cflag &^= termios.CRTSCTS
+	t.Iflag &^= syscall.IXOFF | syscall.IXANY
+
+	if err := termios.Set(f, &t); err != nil {
+		return nil, err
+	}
+
+	return func() error { return termios.Set(f, saved) }, nil
+}
+
+func isNotTTY(err error) bool {
+	return errors.Is(err, syscall.ENOTTY) || errors.Is(err, syscall.EINVAL)
+}
+
+// flushInput discards any stale data the tty received before it was opened
+// (e.g. the tail end of a previous session's output). Nothing is done if f is
+// not a tty.
+func flushInput(f *os.File) error {
+	err := termios.FlushInput(f)
+	if isNotTTY(err) {
+		return nil
+	}
+
+	return err
+}
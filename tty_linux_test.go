@@ -0,0 +1,113 @@
+package onerng
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/hairyhenderson/go-onerng/emulator"
+	"github.com/hairyhenderson/go-onerng/internal/termios"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFileDialer_RawMode(t *testing.T) {
+	d := &emulator.Device{Cooked: true}
+	require.NoError(t, d.Start())
+	defer d.Close()
+
+	// hold the device open so the attributes survive, then make sure the
+	// original (cooked) settings are restored on close - it's opened first,
+	// because the dialer puts the tty into exclusive mode
+	f, err := os.OpenFile(d.Path(), os.O_RDWR, 0)
+	require.NoError(t, err)
+	defer f.Close()
+
+	ctx := context.Background()
+	tr, err := FileDialer{LockDir: t.TempDir()}.Dial(ctx, d.Path())
+	require.NoError(t, err)
+
+	tf, ok := tr.(*deviceFile)
+	require.True(t, ok)
+	attrs, err := termios.Get(tf.File)
+	require.NoError(t, err)
+	assert.True(t, termios.IsRaw(attrs))
+
+	require.NoError(t, tr.Close())
+	attrs, err = termios.Get(f)
+	require.NoError(t, err)
+	assert.False(t, termios.IsRaw(attrs))
+}
+
+func TestFileDialer_SkipRawMode(t *testing.T) {
+	d := &emulator.Device{Cooked: true}
+	require.NoError(t, d.Start())
+	defer d.Close()
+
+	tr, err := FileDialer{SkipRawMode: true, SkipLock: true}.Dial(context.Background(), d.Path())
+	require.NoError(t, err)
+	defer tr.Close()
+
+	f, ok := tr.(*os.File)
+	require.True(t, ok)
+	attrs, err := termios.Get(f)
+	require.NoError(t, err)
+	assert.False(t, termios.IsRaw(attrs))
+}
+
+func TestFileDialer_FlushInput(t *testing.T) {
+	d := &emulator.Device{}
+	require.NoError(t, d.Start())
+	defer d.Close()
+
+	// leave the version string unread in the tty's input queue - silent mode
+	// means nothing follows it
+	f, err := os.OpenFile(d.Path(), os.O_RDWR, 0)
+	require.NoError(t, err)
+	defer f.Close()
+	_, err = f.WriteString("cmd4\ncmdv\ncmdO\n")
+	require.NoError(t, err)
+	time.Sleep(100 * time.Millisecond)
+
+	tr, err := FileDialer{}.Dial(context.Background(), d.Path())
+	require.NoError(t, err)
+	defer tr.Close()
+
+	rd, ok := tr.(ReadDeadliner)
+	require.True(t, ok)
+	require.NoError(t, rd.SetReadDeadline(time.Now().Add(100*time.Millisecond)))
+
+	n, err := tr.Read(make([]byte, 64))
+	assert.ErrorIs(t, err, os.ErrDeadlineExceeded)
+	assert.Zero(t, n)
+}
+
+func TestFileDialer_NotATTY(t *testing.T) {
+	tr, err := FileDialer{SkipLock: true}.Dial(context.Background(), os.DevNull)
+	require.NoError(t, err)
+	defer tr.Close()
+
+	_, ok := tr.(*os.File)
+	assert.True(t, ok)
+}
+
+func TestRead_CookedDevice(t *testing.T) {
+	// control characters would be translated, swallowed, or echoed by a tty
+	// that isn't in raw mode
+	data := bytes.Repeat([]byte("\r\n\x03\x04\x11\x13\x7f\x0a\x0d"), 512)
+	d := &emulator.Device{Cooked: true, Source: bytes.NewReader(data)}
+	require.NoError(t, d.Start())
+	defer d.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	// the data is very repetitive, so it would fail the health tests
+	o := New(d.Path(), WithHealthTests(false))
+	out := &bytes.Buffer{}
+	_, err := o.Read(ctx, out, 1024, Default)
+	require.NoError(t, err)
+	assert.Equal(t, data[:1024], out.Bytes())
+}
@@ -0,0 +1,15 @@
+//go:build !linux
+
+package onerng
+
+import "os"
+
+// makeRaw is only supported on Linux - elsewhere nothing is done
+func makeRaw(_ *os.File) (restore func() error, err error) {
+	return nil, nil
+}
+
+// flushInput is only supported on Linux - elsewhere nothing is done
+func flushInput(_ *os.File) error {
+	return nil
+}
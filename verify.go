@@ -3,6 +3,7 @@ package onerng
 import (
 	"bytes"
 	"context"
+	"errors"
 	"fmt"
 	"io"
 	"os"
@@ -20,24 +21,24 @@ import (
 // distributed alongside the OneRNG package.
 func Verify(_ context.Context, image io.Reader, pubkey string) error {
 	if err := readMagic(image); err != nil {
-		return fmt.Errorf("failed to find magic number: %w", err)
+		return fmt.Errorf("%w: failed to find magic number: %w", ErrBadImage, err)
 	}
 	length, version, err := readHeader(image)
 	if err != nil {
-		return fmt.Errorf("failed to read header: %w", err)
+		return fmt.Errorf("%w: failed to read header: %w", ErrBadImage, err)
 	}
 
 	return readAndVerify(image, version, length, pubkey)
 }
 
 func read(r io.Reader, p []byte) error {
-	n, err := r.Read(p)
+	n, err := io.ReadFull(r, p)
+	if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+		return fmt.Errorf("%w: read %db, expected %db", ErrShortRead, n, len(p))
+	}
 	if err != nil {
 		return fmt.Errorf("read failed: %w", err)
 	}
-	if n == 0 {
-		return fmt.Errorf("short read")
-	}
 
 	return nil
 }
@@ -120,7 +121,7 @@ func verifyImage(signed, sig []byte, pubkey string) (signer *openpgp.Entity, err
 	// read public key
 	keyring, err := openpgp.ReadArmoredKeyRing(bytes.NewBufferString(pubkey))
 	if err != nil {
-		return nil, err
+		return nil, fmt.Errorf("failed to read public key: %w", err)
 	}
 
 	// verify
@@ -130,7 +131,7 @@ func verifyImage(signed, sig []byte, pubkey string) (signer *openpgp.Entity, err
 		bytes.NewBuffer(sig),
 	)
 	if err != nil {
-		return nil, fmt.Errorf("failed to verify firmware signature: %w", err)
+		return nil, fmt.Errorf("%w: %w", ErrSignatureInvalid, err)
 	}
 
 	return signer, nil
@@ -138,13 +139,13 @@ func verifyImage(signed, sig []byte, pubkey string) (signer *openpgp.Entity, err
 
 func parseImage(image io.Reader, version, length int) (signed, sig []byte, err error) {
 	c := make([]byte, length)
-	n, err := io.ReadAtLeast(image, c, length)
+	n, err := io.ReadFull(image, c)
+	if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+		return nil, nil, fmt.Errorf("%w: %w: wrong length: was %d, expected %d", ErrBadImage, ErrShortRead, n, length)
+	}
 	if err != nil {
 		return nil, nil, err
 	}
-	if n != length {
-		return nil, nil, fmt.Errorf("bad image: wrong length: was %d, expected %d", n, length)
-	}
 
 	// determine end offset
 	endOff := 0
@@ -154,9 +155,16 @@ func parseImage(image io.Reader, version, length int) (signed, sig []byte, err e
 		endOff = 600
 	}
 
+	if length < endOff+2 {
+		return nil, nil, fmt.Errorf("%w: too short: %db", ErrBadImage, length)
+	}
+
 	// signature length - 2 bytes between image and signature
 	slen := int(c[length-endOff])
 	slen |= int(c[length-endOff+1]) << 8
+	if length-endOff+2+slen > length {
+		return nil, nil, fmt.Errorf("%w: signature length %d overruns image", ErrBadImage, slen)
+	}
 
 	// split last part into image (signed part) & signature
 	signed = c[0 : length-endOff]
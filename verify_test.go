@@ -2,9 +2,16 @@ package onerng
 
 import (
 	"bytes"
+	"context"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	//nolint:staticcheck
+	"golang.org/x/crypto/openpgp"
+	//nolint:staticcheck
+	"golang.org/x/crypto/openpgp/armor"
 )
 
 func TestReadMagic(t *testing.T) {
@@ -50,3 +57,60 @@ func TestReadHeader(t *testing.T) {
 	assert.Equal(t, 0x01f00f, l)
 	assert.Equal(t, 0x7007, v)
 }
+
+func TestRead_Short(t *testing.T) {
+	err := read(bytes.NewBuffer([]byte{0x01}), make([]byte, 2))
+	assert.ErrorIs(t, err, ErrShortRead)
+
+	err = read(&bytes.Buffer{}, make([]byte, 2))
+	assert.ErrorIs(t, err, ErrShortRead)
+
+	assert.NoError(t, read(bytes.NewBuffer([]byte{0x01, 0x02}), make([]byte, 2)))
+}
+
+func TestParseImage(t *testing.T) {
+	_, _, err := parseImage(bytes.NewBuffer(make([]byte, 10)), 3, 20)
+	assert.ErrorIs(t, err, ErrBadImage)
+	assert.ErrorIs(t, err, ErrShortRead)
+
+	// too short to contain a signature
+	_, _, err = parseImage(bytes.NewBuffer(make([]byte, 100)), 3, 100)
+	assert.ErrorIs(t, err, ErrBadImage)
+
+	// signature length runs past the end of the image
+	img := make([]byte, 1000)
+	img[1000-680] = 0xff
+	img[1000-680+1] = 0xff
+	_, _, err = parseImage(bytes.NewBuffer(img), 3, 1000)
+	assert.ErrorIs(t, err, ErrBadImage)
+
+	img = make([]byte, 1000)
+	img[1000-680] = 4
+	copy(img[1000-680+2:], "sig!")
+	signed, sig, err := parseImage(bytes.NewBuffer(img), 3, 1000)
+	assert.NoError(t, err)
+	assert.Len(t, signed, 1000-680)
+	assert.Equal(t, "sig!", string(sig))
+}
+
+func TestVerify_Errors(t *testing.T) {
+	e, err := openpgp.NewEntity("test", "", "test@example.com", nil)
+	require.NoError(t, err)
+	buf := &bytes.Buffer{}
+	w, err := armor.Encode(buf, openpgp.PublicKeyType, nil)
+	require.NoError(t, err)
+	require.NoError(t, e.Serialize(w))
+	require.NoError(t, w.Close())
+	publicKeyForTest := buf.String()
+
+	ctx := context.Background()
+	err = Verify(ctx, bytes.NewBufferString("not an image"), publicKeyForTest)
+	assert.ErrorIs(t, err, ErrBadImage)
+
+	img := []byte{0xfe, 0xed, 0xbe, 0xef, 0x20, 0x14, 0xe8, 0x03, 0x00, 0x03, 0x00, 0x00, 0x00}
+	body := make([]byte, 1000)
+	body[1000-680] = 4
+	copy(body[1000-680+2:], "sig!")
+	err = Verify(ctx, bytes.NewBuffer(append(img, body...)), publicKeyForTest)
+	assert.ErrorIs(t, err, ErrSignatureInvalid)
+}